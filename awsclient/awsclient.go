@@ -0,0 +1,254 @@
+// Package awsclient loads the AWS config shared by all stores.
+package awsclient
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsMiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/guardian/devx-config/log"
+)
+
+type Options struct {
+	Profile string
+
+	// If Region is empty, it is taken from AWS_REGION, the profile, instance
+	// metadata, then DefaultRegion (falling back to the package's).
+	Region        string
+	DefaultRegion string
+
+	Account string // if set, credentials must be for this account
+
+	// If RoleARN is set, credentials are used to assume this role (e.g. from
+	// CI, or cross-account). Any roles in RoleChain are assumed first, in
+	// turn, e.g. to hop from a central account to a member one. The external
+	// ID is only used for RoleARN.
+	RoleChain   []string
+	RoleARN     string
+	ExternalID  string
+	SessionName string        // defaults to 'devx-config'
+	Timeout     time.Duration // per HTTP request; 0 for the SDK default
+
+	// An HTTP(S) proxy for all AWS requests, overriding HTTPS_PROXY etc., and
+	// a PEM file of extra CAs to trust (e.g. that of a TLS-inspecting proxy
+	// such as Zscaler).
+	Proxy    string
+	CABundle string
+
+	// Retries after a failed (e.g. throttled) request, and how they're made:
+	// 'standard' or 'adaptive' (which also rate limits requests once
+	// throttled). Zero values leave the SDK defaults.
+	MaxRetries int
+	RetryMode  string
+
+	// Limits requests (including retries) to this many per second; 0 for no
+	// limit.
+	MaxRPS float64
+
+	// Custom endpoint URLs (e.g. for LocalStack or VPC endpoints), keyed by
+	// service ID (e.g. 'SSM' or 'Secrets Manager'). The "" key applies to
+	// all other services.
+	Endpoints map[string]string
+
+	// Log request IDs, retries and latencies for every AWS call, along with
+	// (body-less, so secrets are never included) HTTP requests and responses.
+	Trace bool
+
+	// Returns the MFA code for profiles that require one (i.e. with an
+	// 'mfa_serial'). Credentials are then cached until they expire.
+	MFAToken func() (string, error)
+
+	// Temporary credentials (and MFA sessions) are cached on disk between
+	// commands unless this is set. MFA sessions are still reused within the
+	// process.
+	NoCredentialCache bool
+}
+
+func LoadConfig(ctx context.Context, logger log.Logger, opts Options) (aws.Config, error) {
+	loadOpts := []func(*awsConfig.LoadOptions) error{
+		awsConfig.WithSharedConfigProfile(opts.Profile),
+		awsConfig.WithRegion(opts.Region),
+	}
+
+	usedMFA := false
+	if opts.MFAToken != nil {
+		loadOpts = append(loadOpts, awsConfig.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = func() (string, error) {
+				usedMFA = true
+				return opts.MFAToken()
+			}
+		}))
+	}
+
+	if len(opts.Endpoints) > 0 {
+		loadOpts = append(loadOpts, awsConfig.WithEndpointResolverWithOptions(endpointResolver(opts.Endpoints)))
+	}
+
+	if opts.MaxRetries > 0 {
+		loadOpts = append(loadOpts, awsConfig.WithRetryMaxAttempts(opts.MaxRetries+1))
+	}
+
+	if opts.RetryMode != "" {
+		mode, err := aws.ParseRetryMode(opts.RetryMode)
+		if err != nil {
+			return aws.Config{}, err
+		}
+		loadOpts = append(loadOpts, awsConfig.WithRetryMode(mode))
+	}
+
+	if opts.MaxRPS > 0 {
+		loadOpts = append(loadOpts, awsConfig.WithAPIOptions([]func(*middleware.Stack) error{rateLimitMiddleware(sharedRateLimiter(opts.MaxRPS))}))
+	}
+
+	client, err := httpClient(opts)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if client != nil {
+		loadOpts = append(loadOpts, awsConfig.WithHTTPClient(client))
+	}
+
+	if opts.Trace {
+		traceLogger := logger.With("trace", "aws")
+		loadOpts = append(loadOpts,
+			awsConfig.WithClientLogMode(aws.LogRetries|aws.LogRequest|aws.LogResponse),
+			awsConfig.WithLogger(logging.LoggerFunc(func(c logging.Classification, format string, v ...interface{}) {
+				msg := redactHeaders(fmt.Sprintf(format, v...))
+				if c == logging.Warn {
+					traceLogger.Warnf("%s", msg)
+				} else {
+					traceLogger.Infof("%s", msg)
+				}
+			})),
+			awsConfig.WithAPIOptions([]func(*middleware.Stack) error{traceMiddleware(traceLogger)}),
+		)
+	}
+
+	cfg, err := awsConfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return cfg, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	region, source := resolveRegion(ctx, opts, cfg.Region)
+	logger.Debugf("using region %s (%s)", region, source)
+	cfg.Region = region
+
+	// Otherwise, assuming a role in another partition (e.g. aws-cn) fails
+	// with an unhelpful STS error.
+	for _, role := range roles(opts) {
+		if err := checkPartition(role, cfg.Region); err != nil {
+			return cfg, err
+		}
+	}
+
+	if opts.MFAToken != nil && cfg.Credentials != nil {
+		cfg.Credentials = newMFACache(opts.Profile, cfg.Credentials, &usedMFA, opts.NoCredentialCache)
+	}
+
+	// Each role is assumed using the credentials of the one before.
+	chain := roles(opts)
+	for i, role := range chain {
+		externalID := ""
+		if i == len(chain)-1 && opts.RoleARN != "" {
+			externalID = opts.ExternalID
+		}
+
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), role, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = opts.SessionName
+			if o.RoleSessionName == "" {
+				o.RoleSessionName = "devx-config"
+			}
+
+			if externalID != "" {
+				o.ExternalID = aws.String(externalID)
+			}
+		}))
+	}
+
+	if cfg.Credentials != nil && !opts.NoCredentialCache {
+		cfg.Credentials = aws.NewCredentialsCache(newCredentialsCache(opts, cfg.Credentials))
+	}
+
+	// Credentials are otherwise only loaded on first use, but checking them
+	// now means problems (e.g. an expired SSO session) can be handled here.
+	if cfg.Credentials != nil {
+		if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+			return cfg, fmt.Errorf("unable to get AWS credentials: %w", err)
+		}
+	}
+
+	if opts.Account != "" {
+		identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return cfg, fmt.Errorf("unable to check AWS account: %w", err)
+		}
+
+		if aws.ToString(identity.Account) != opts.Account {
+			return cfg, fmt.Errorf("credentials are for AWS account %s, but the service is in %s (check --profile)", aws.ToString(identity.Account), opts.Account)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Services without a custom endpoint use the default.
+func endpointResolver(endpoints map[string]string) aws.EndpointResolverWithOptions {
+	return aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		url := endpoints[service]
+		if url == "" {
+			url = endpoints[""]
+		}
+
+		if url == "" {
+			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+		}
+
+		return aws.Endpoint{URL: url, HostnameImmutable: true, SigningRegion: region, Source: aws.EndpointSourceCustom}, nil
+	})
+}
+
+var secretHeaders = regexp.MustCompile(`(?im)^(authorization|x-amz-security-token):[^\r\n]*`)
+
+// Redacts the values of headers holding credentials (the request signature and
+// session token) from a dumped request, so traces can be shared.
+func redactHeaders(dump string) string {
+	return secretHeaders.ReplaceAllString(dump, "$1: REDACTED")
+}
+
+// Logs a summary line for each operation, after any retries.
+func traceMiddleware(logger log.Logger) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("DevxTrace", func(
+			ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+		) (middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			requestID, _ := awsMiddleware.GetRequestIDMetadata(metadata)
+			attempts := 1
+			if results, ok := retry.GetAttemptResults(metadata); ok {
+				attempts = len(results.Results)
+			}
+
+			status := "ok"
+			if err != nil {
+				status = "error: " + err.Error()
+			}
+
+			logger.Infof("%s.%s request_id=%s attempts=%d latency=%s %s",
+				awsMiddleware.GetServiceID(ctx), awsMiddleware.GetOperationName(ctx), requestID, attempts, time.Since(start).Round(time.Millisecond), status)
+
+			return out, metadata, err
+		}), middleware.After)
+	}
+}
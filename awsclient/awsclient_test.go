@@ -0,0 +1,20 @@
+package awsclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	dump := "Request\r\nPOST / HTTP/1.1\r\nHost: ssm.eu-west-1.amazonaws.com\r\n" +
+		"Authorization: AWS4-HMAC-SHA256 Credential=AKID/20240101/eu-west-1/ssm/aws4_request, Signature=abc\r\n" +
+		"X-Amz-Security-Token: token\r\nx-amz-target: AmazonSSM.GetParameter\r\n\r\n"
+
+	got := redactHeaders(dump)
+	if strings.Contains(got, "AKID") || strings.Contains(got, "Signature=abc") || strings.Contains(got, "token\r\n") {
+		t.Errorf("got %q; want credentials redacted", got)
+	}
+	if !strings.Contains(got, "Authorization: REDACTED\r\n") || !strings.Contains(got, "X-Amz-Security-Token: REDACTED\r\n") || !strings.Contains(got, "Host: ssm.eu-west-1.amazonaws.com") {
+		t.Errorf("got %q; want other headers kept", got)
+	}
+}
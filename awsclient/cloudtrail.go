@@ -0,0 +1,81 @@
+package awsclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+// MaxTrailPages bounds CloudTrail lookups, as LookupEvents is limited to two
+// requests a second and an account's busy periods can span many pages.
+var MaxTrailPages = 20
+
+// TrailEvent is a management event recorded by CloudTrail.
+type TrailEvent struct {
+	Time      time.Time
+	Principal string // ARN of the caller, e.g. an assumed-role session
+	SourceIP  string
+
+	RequestParameters map[string]any
+	ResponseElements  map[string]any
+}
+
+// The parts of the event record we use. See
+// https://docs.aws.amazon.com/awscloudtrail/latest/userguide/cloudtrail-event-reference-record-contents.html.
+type trailRecord struct {
+	EventTime    time.Time `json:"eventTime"`
+	SourceIP     string    `json:"sourceIPAddress"`
+	UserIdentity struct {
+		ARN string `json:"arn"`
+	} `json:"userIdentity"`
+	RequestParameters map[string]any `json:"requestParameters"`
+	ResponseElements  map[string]any `json:"responseElements"`
+}
+
+// LookupEvents returns the cfg.Region management events with the given name
+// (e.g. "PutParameter") for the resource (e.g. a parameter name) between start
+// and end, newest first. CloudTrail only keeps the last 90 days of events, and
+// at most MaxTrailPages pages are read; if there were more, truncated is set.
+func LookupEvents(ctx context.Context, cfg aws.Config, resourceName, eventName string, start, end time.Time) (events []TrailEvent, truncated bool, err error) {
+	// Only one lookup attribute is allowed, and a resource has far fewer
+	// events than an event name has across the account, so the name is
+	// matched here.
+	pages := cloudtrail.NewLookupEventsPaginator(cloudtrail.NewFromConfig(cfg), &cloudtrail.LookupEventsInput{
+		LookupAttributes: []types.LookupAttribute{{AttributeKey: types.LookupAttributeKeyResourceName, AttributeValue: aws.String(resourceName)}},
+		StartTime:        aws.Time(start),
+		EndTime:          aws.Time(end),
+	})
+
+	for page := 0; page < MaxTrailPages && pages.HasMorePages(); page++ {
+		output, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to look up CloudTrail events: %w", err)
+		}
+
+		for _, e := range output.Events {
+			if aws.ToString(e.EventName) != eventName {
+				continue
+			}
+
+			var record trailRecord
+			if err := json.Unmarshal([]byte(aws.ToString(e.CloudTrailEvent)), &record); err != nil {
+				return nil, false, fmt.Errorf("unable to parse CloudTrail event: %w", err)
+			}
+
+			events = append(events, TrailEvent{
+				Time:              record.EventTime,
+				Principal:         record.UserIdentity.ARN,
+				SourceIP:          record.SourceIP,
+				RequestParameters: record.RequestParameters,
+				ResponseElements:  record.ResponseElements,
+			})
+		}
+	}
+
+	return events, pages.HasMorePages(), nil
+}
@@ -0,0 +1,81 @@
+package awsclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestLookupEvents(t *testing.T) {
+	event := `{"eventTime":"2024-03-01T12:00:00Z","sourceIPAddress":"203.0.113.7","userIdentity":{"arn":"arn:aws:sts::123456789012:assumed-role/developer/alice"},"requestParameters":{"name":"/PROD/app/stack/KEY"},"responseElements":{"version":3}}`
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("X-Amz-Target"); got != "CloudTrail_20131101.LookupEvents" {
+			t.Errorf("got target %s", got)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var input struct {
+			NextToken        string
+			LookupAttributes []struct{ AttributeKey, AttributeValue string }
+		}
+		_ = json.Unmarshal(body, &input)
+		if len(input.LookupAttributes) != 1 || input.LookupAttributes[0].AttributeKey != "ResourceName" || input.LookupAttributes[0].AttributeValue != "/PROD/app/stack/KEY" {
+			t.Errorf("got lookup attributes %+v; want the parameter's name", input.LookupAttributes)
+		}
+
+		output := map[string]any{"Events": []map[string]string{
+			{"EventName": "PutParameter", "CloudTrailEvent": event},
+			{"EventName": "GetParameter", "CloudTrailEvent": event},
+		}}
+		if input.NextToken == "" {
+			output["NextToken"] = "page-2"
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_ = json.NewEncoder(w).Encode(output)
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+
+	events, truncated, err := LookupEvents(context.Background(), cfg, "/PROD/app/stack/KEY", "PutParameter", time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 || len(events) != 2 || truncated {
+		t.Fatalf("got %d requests and %d events (truncated: %t); want 2 of each", requests, len(events), truncated)
+	}
+
+	e := events[0]
+	if e.Principal != "arn:aws:sts::123456789012:assumed-role/developer/alice" || e.SourceIP != "203.0.113.7" || e.ResponseElements["version"] != float64(3) {
+		t.Errorf("got %+v", e)
+	}
+
+	// Lookups stop after MaxTrailPages.
+	defer func(pages int) { MaxTrailPages = pages }(MaxTrailPages)
+	MaxTrailPages = 1
+	requests = 0
+	events, truncated, err = LookupEvents(context.Background(), cfg, "/PROD/app/stack/KEY", "PutParameter", time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 || len(events) != 1 || !truncated {
+		t.Errorf("got %d requests and %d events (truncated: %t); want 1 of each, truncated", requests, len(events), truncated)
+	}
+}
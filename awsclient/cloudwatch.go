@@ -0,0 +1,41 @@
+package awsclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// PutMetric publishes a single count to CloudWatch, with the given dimensions.
+func PutMetric(ctx context.Context, cfg aws.Config, namespace string, name string, dimensions map[string]string, value float64) error {
+	// Sorted, so that requests are reproducible.
+	var keys []string
+	for k := range dimensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var dims []types.Dimension
+	for _, k := range keys {
+		dims = append(dims, types.Dimension{Name: aws.String(k), Value: aws.String(dimensions[k])})
+	}
+
+	_, err := cloudwatch.NewFromConfig(cfg).PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace: aws.String(namespace),
+		MetricData: []types.MetricDatum{{
+			MetricName: aws.String(name),
+			Value:      aws.Float64(value),
+			Unit:       types.StandardUnitCount,
+			Dimensions: dims,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to publish metric: %w", err)
+	}
+
+	return nil
+}
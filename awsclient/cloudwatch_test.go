@@ -0,0 +1,46 @@
+package awsclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestPutMetric(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		want := map[string]string{
+			"Action":                         "PutMetricData",
+			"Namespace":                      "devx-config",
+			"MetricData.member.1.MetricName": "DriftedParameters",
+			"MetricData.member.1.Value":      "3",
+			"MetricData.member.1.Dimensions.member.1.Name":  "App",
+			"MetricData.member.1.Dimensions.member.1.Value": "example",
+			"MetricData.member.1.Dimensions.member.2.Name":  "Stage",
+		}
+		for k, v := range want {
+			if got := r.PostForm.Get(k); got != v {
+				t.Errorf("%s: got %q; want %q", k, got, v)
+			}
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+
+	err := PutMetric(context.Background(), cfg, "devx-config", "DriftedParameters", map[string]string{"Stage": "PROD", "App": "example"}, 3)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package awsclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Temporary credentials are cached on disk (readable only by the user), so
+// that consecutive commands don't each need an MFA code or a full SSO/STS
+// handshake. Long-lived credentials (e.g. access keys) are never cached.
+type fileCache struct {
+	path     string
+	provider aws.CredentialsProvider
+	cache    func() bool // whether to cache newly retrieved credentials
+}
+
+func newFileCache(name string, provider aws.CredentialsProvider, cache func() bool) aws.CredentialsProvider {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return provider
+	}
+
+	return fileCache{filepath.Join(dir, "devx-config", name+".json"), provider, cache}
+}
+
+// Session credentials from MFA-protected profiles, cached on disk only if a
+// code was actually asked for (and the file cache isn't disabled). They're
+// also kept for the rest of the process, keyed by the profile actually used,
+// so that each config loaded (e.g. one per store) doesn't ask for a new code.
+var mfaSessions = struct {
+	sync.Mutex
+	providers map[string]aws.CredentialsProvider
+}{providers: map[string]aws.CredentialsProvider{}}
+
+func newMFACache(profile string, provider aws.CredentialsProvider, usedMFA *bool, noFileCache bool) aws.CredentialsProvider {
+	profile = resolvedProfile(profile)
+
+	mfaSessions.Lock()
+	defer mfaSessions.Unlock()
+
+	if cached, ok := mfaSessions.providers[profile]; ok {
+		return cached
+	}
+
+	if !noFileCache {
+		provider = newFileCache("mfa-"+profile, provider, func() bool { return *usedMFA })
+	}
+	cached := aws.NewCredentialsCache(provider)
+	mfaSessions.providers[profile] = cached
+	return cached
+}
+
+// The profile the SDK loads, if none is given explicitly.
+func resolvedProfile(profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if profile = os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// The final credentials (e.g. from SSO, or an assumed role), cached by
+// everything that determines them, including the environment the SDK reads
+// the profile or credentials from.
+func newCredentialsCache(opts Options, provider aws.CredentialsProvider) aws.CredentialsProvider {
+	key := []string{
+		opts.Profile, os.Getenv("AWS_PROFILE"), os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SESSION_TOKEN"),
+		os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ENDPOINT_URL"),
+		opts.ExternalID, opts.SessionName,
+	}
+	key = append(key, roles(opts)...)
+
+	// Credentials from e.g. a LocalStack STS aren't valid elsewhere.
+	services := make([]string, 0, len(opts.Endpoints))
+	for service := range opts.Endpoints {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		key = append(key, service+"="+opts.Endpoints[service])
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+
+	return newFileCache("credentials-"+hex.EncodeToString(sum[:8]), provider, func() bool { return true })
+}
+
+func (c fileCache) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	if creds, ok := c.read(); ok {
+		return creds, nil
+	}
+
+	// Refreshes are serialised between commands (e.g. in several shells), so
+	// that only one of them asks for an MFA code or logs in. The others then
+	// use the credentials it cached.
+	if unlock := c.lock(ctx); unlock != nil {
+		defer unlock()
+
+		if creds, ok := c.read(); ok {
+			return creds, nil
+		}
+	}
+
+	creds, err := c.provider.Retrieve(ctx)
+	if err != nil || !creds.CanExpire || !c.cache() {
+		return creds, err
+	}
+
+	// Caching is best-effort. The file is replaced atomically, so that
+	// concurrent commands never read a partial one.
+	if data, err := json.Marshal(creds); err == nil && os.MkdirAll(filepath.Dir(c.path), 0700) == nil {
+		if f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*"); err == nil {
+			_, werr := f.Write(data)
+			cerr := f.Close()
+			if werr != nil || cerr != nil || os.Rename(f.Name(), c.path) != nil {
+				_ = os.Remove(f.Name())
+			}
+		}
+	}
+
+	return creds, nil
+}
+
+func (c fileCache) read() (aws.Credentials, bool) {
+	var creds aws.Credentials
+	if data, err := os.ReadFile(c.path); err == nil && json.Unmarshal(data, &creds) == nil {
+		if creds.CanExpire && time.Until(creds.Expires) > 5*time.Minute {
+			return creds, true
+		}
+	}
+	return creds, false
+}
+
+// How long to wait for another command's refresh, and after which its lock is
+// assumed to have been abandoned (e.g. the command was killed). Both allow
+// for someone typing an MFA code or logging in through their browser.
+const (
+	lockTimeout = time.Minute
+	lockStale   = 5 * time.Minute
+)
+
+// Takes the lock file next to the cache, returning nil if that isn't
+// possible in time, in which case credentials are refreshed regardless.
+func (c fileCache) lock(ctx context.Context) func() {
+	path := c.path + ".lock"
+	if os.MkdirAll(filepath.Dir(path), 0700) != nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }
+		}
+		if !os.IsExist(err) {
+			return nil
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			_ = os.Remove(path)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil
+		}
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package awsclient
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type countingProvider struct {
+	calls *int
+	creds aws.Credentials
+}
+
+func (p countingProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	*p.calls++
+	return p.creds, nil
+}
+
+func TestCredentialsCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	temporary := aws.Credentials{AccessKeyID: "ASIA", SecretAccessKey: "s", SessionToken: "t", CanExpire: true, Expires: time.Now().Add(time.Hour)}
+	opts := Options{Profile: "developer", RoleARN: "arn:aws:iam::123456789012:role/config"}
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		creds, err := newCredentialsCache(opts, countingProvider{&calls, temporary}).Retrieve(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if creds.AccessKeyID != "ASIA" {
+			t.Errorf("got %s; want ASIA", creds.AccessKeyID)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls; want 1 (then cached)", calls)
+	}
+
+	// A different role has its own cache.
+	opts.RoleARN = "arn:aws:iam::210987654321:role/config"
+	_, _ = newCredentialsCache(opts, countingProvider{&calls, temporary}).Retrieve(context.Background())
+	if calls != 2 {
+		t.Errorf("got %d calls; want 2", calls)
+	}
+
+	// As do other credentials from the environment, and other endpoints.
+	t.Setenv("AWS_SESSION_TOKEN", "other")
+	_, _ = newCredentialsCache(opts, countingProvider{&calls, temporary}).Retrieve(context.Background())
+	opts.Endpoints = map[string]string{"STS": "http://localhost:4566"}
+	_, _ = newCredentialsCache(opts, countingProvider{&calls, temporary}).Retrieve(context.Background())
+	if calls != 4 {
+		t.Errorf("got %d calls; want 4", calls)
+	}
+
+	// Long-lived credentials are never cached.
+	static := aws.Credentials{AccessKeyID: "AKIA", SecretAccessKey: "s"}
+	opts.Profile = "static"
+	for i := 0; i < 2; i++ {
+		_, _ = newCredentialsCache(opts, countingProvider{&calls, static}).Retrieve(context.Background())
+	}
+	if calls != 6 {
+		t.Errorf("got %d calls; want 6", calls)
+	}
+}
+
+func TestMFACache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AWS_PROFILE", "mfa-test")
+
+	temporary := aws.Credentials{AccessKeyID: "ASIA", SecretAccessKey: "s", SessionToken: "t", CanExpire: true, Expires: time.Now().Add(time.Hour)}
+	usedMFA := true
+
+	// Even without the file cache, the session is reused within the process,
+	// including when the profile comes from AWS_PROFILE.
+	calls := 0
+	for _, profile := range []string{"", "mfa-test"} {
+		if _, err := newMFACache(profile, countingProvider{&calls, temporary}, &usedMFA, true).Retrieve(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls; want 1 (then cached)", calls)
+	}
+
+	// Other profiles have their own session.
+	_, _ = newMFACache("other-mfa-test", countingProvider{&calls, temporary}, &usedMFA, true).Retrieve(context.Background())
+	if calls != 2 {
+		t.Errorf("got %d calls; want 2", calls)
+	}
+}
+
+func TestCredentialsCacheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	temporary := aws.Credentials{AccessKeyID: "ASIA", SecretAccessKey: "s", SessionToken: "t", CanExpire: true, Expires: time.Now().Add(time.Hour)}
+
+	// Another command is refreshing the credentials, and caches them before
+	// releasing its lock.
+	if err := os.WriteFile(path+".lock", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		data, _ := json.Marshal(temporary)
+		_ = os.WriteFile(path, data, 0600)
+		_ = os.Remove(path + ".lock")
+	}()
+
+	calls := 0
+	cache := fileCache{path, countingProvider{&calls, temporary}, func() bool { return true }}
+	creds, err := cache.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessKeyID != "ASIA" {
+		t.Errorf("got %s; want ASIA", creds.AccessKeyID)
+	}
+	if calls != 0 {
+		t.Errorf("got %d calls; want 0 (cached by the other command)", calls)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("got lock file (%v); want it removed", err)
+	}
+}
@@ -0,0 +1,83 @@
+package awsclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	awsHTTP "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// Returns the HTTP client for AWS requests, or nil to use the SDK's default
+// (which already uses any proxy set by HTTPS_PROXY, HTTP_PROXY and NO_PROXY).
+func httpClient(opts Options) (*awsHTTP.BuildableClient, error) {
+	if opts.Timeout == 0 && opts.Proxy == "" && opts.CABundle == "" {
+		return nil, nil
+	}
+
+	client := awsHTTP.NewBuildableClient()
+	if opts.Timeout > 0 {
+		client = client.WithTimeout(opts.Timeout)
+	}
+
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s' (must be e.g. 'http://proxy.example.com:8080')", opts.Proxy)
+		}
+
+		client = client.WithTransportOptions(func(tr *http.Transport) {
+			tr.Proxy = http.ProxyURL(proxy)
+		})
+	}
+
+	if opts.CABundle != "" {
+		pool, err := certPool(opts.CABundle)
+		if err != nil {
+			return nil, err
+		}
+
+		client = client.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			tr.TLSClientConfig.RootCAs = pool
+		})
+	}
+
+	return client, nil
+}
+
+// Returns the system's CAs plus those in the PEM bundle at path, so that a
+// bundle with just a proxy's CA (e.g. Zscaler's) is enough. A leading '~/' is
+// expanded to the user's home directory.
+func certPool(path string) (*x509.CertPool, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand '~': %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle '%s'", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,94 @@
+package awsclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := httpClient(Options{CABundle: bundle})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := get(client, server.URL)
+	if err != nil {
+		t.Fatalf("request with CA bundle failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Without the bundle, the server's certificate isn't trusted.
+	if resp, err := http.DefaultClient.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("request without CA bundle: expected an error")
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := httpClient(Options{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := get(client, "http://ssm.eu-west-1.amazonaws.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if want := "http://ssm.eu-west-1.amazonaws.com/"; proxied != want {
+		t.Errorf("proxied %q; want %q", proxied, want)
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	if client, err := httpClient(Options{}); client != nil || err != nil {
+		t.Errorf("no options: got %v, %v; want nil (the SDK default)", client, err)
+	}
+
+	if _, err := httpClient(Options{Proxy: "proxy.example.com"}); err == nil {
+		t.Errorf("proxy without a scheme: expected an error")
+	}
+
+	if _, err := httpClient(Options{CABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Errorf("missing CA bundle: expected an error")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := httpClient(Options{CABundle: empty}); err == nil {
+		t.Errorf("CA bundle without certificates: expected an error")
+	}
+}
+
+func get(client interface {
+	Do(*http.Request) (*http.Response, error)
+}, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
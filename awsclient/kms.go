@@ -0,0 +1,137 @@
+package awsclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// KeyForAlias returns the ARN of the KMS key with the alias (e.g.
+// 'alias/devx/deploy/example'), or false if there isn't one.
+func KeyForAlias(ctx context.Context, cfg aws.Config, alias string) (string, bool, error) {
+	out, err := kms.NewFromConfig(cfg).DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(alias)})
+
+	var notFound *types.NotFoundException
+	if errors.As(err, &notFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("unable to describe KMS key '%s': %w", alias, err)
+	}
+
+	return aws.ToString(out.KeyMetadata.Arn), true, nil
+}
+
+// CreateKey creates a symmetric customer managed key with the default key
+// policy (so access is controlled by IAM policies and grants), returning its
+// ARN.
+func CreateKey(ctx context.Context, cfg aws.Config, description string, tags map[string]string) (string, error) {
+	var keys []string
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kmsTags := []types.Tag{}
+	for _, k := range keys {
+		kmsTags = append(kmsTags, types.Tag{TagKey: aws.String(k), TagValue: aws.String(tags[k])})
+	}
+
+	out, err := kms.NewFromConfig(cfg).CreateKey(ctx, &kms.CreateKeyInput{
+		Description: aws.String(description),
+		Tags:        kmsTags,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create KMS key: %w", err)
+	}
+
+	return aws.ToString(out.KeyMetadata.Arn), nil
+}
+
+// CreateAlias points a new alias at the key.
+func CreateAlias(ctx context.Context, cfg aws.Config, alias string, keyID string) error {
+	_, err := kms.NewFromConfig(cfg).CreateAlias(ctx, &kms.CreateAliasInput{
+		AliasName:   aws.String(alias),
+		TargetKeyId: aws.String(keyID),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create KMS alias '%s': %w", alias, err)
+	}
+
+	return nil
+}
+
+// GrantDecrypt allows the principal (e.g. a service's role) to decrypt with the
+// key. Grants are named, so granting the same principal again is a no-op.
+func GrantDecrypt(ctx context.Context, cfg aws.Config, keyID string, principal string) error {
+	_, err := kms.NewFromConfig(cfg).CreateGrant(ctx, &kms.CreateGrantInput{
+		KeyId:            aws.String(keyID),
+		GranteePrincipal: aws.String(principal),
+		Operations:       []types.GrantOperation{types.GrantOperationDecrypt},
+		Name:             aws.String("devx-config-decrypt"),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to grant '%s' decrypt: %w", principal, err)
+	}
+
+	return nil
+}
+
+// Encrypt encrypts plaintext (of up to 4KB) with the key, returning the
+// ciphertext base64-encoded.
+func Encrypt(ctx context.Context, cfg aws.Config, keyID string, plaintext []byte) (string, error) {
+	out, err := kms.NewFromConfig(cfg).Encrypt(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(keyID),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to encrypt with KMS key '%s': %w", keyID, err)
+	}
+
+	return base64.StdEncoding.EncodeToString(out.CiphertextBlob), nil
+}
+
+// Decrypt decrypts base64-encoded ciphertext from Encrypt (or a data key from
+// GenerateDataKey). The key is optional, as KMS records it in the ciphertext,
+// but if given the ciphertext must be for it. The encryption context must be
+// the one it was encrypted with, if any.
+func Decrypt(ctx context.Context, cfg aws.Config, keyID string, ciphertext string, encryptionContext map[string]string) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	input := &kms.DecryptInput{CiphertextBlob: blob, EncryptionContext: encryptionContext}
+	if keyID != "" {
+		input.KeyId = aws.String(keyID)
+	}
+
+	out, err := kms.NewFromConfig(cfg).Decrypt(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt with KMS: %w", err)
+	}
+
+	return out.Plaintext, nil
+}
+
+// GenerateDataKey returns a new 256-bit data key, in plaintext and encrypted
+// with the KMS key (bound to the encryption context, if any), for envelope
+// encryption.
+func GenerateDataKey(ctx context.Context, cfg aws.Config, keyID string, encryptionContext map[string]string) ([]byte, []byte, error) {
+	out, err := kms.NewFromConfig(cfg).GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(keyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate a data key with KMS key '%s': %w", keyID, err)
+	}
+
+	return out.Plaintext, out.CiphertextBlob, nil
+}
@@ -0,0 +1,121 @@
+package awsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestKMS(t *testing.T) {
+	aliases := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.DescribeKey":
+			arn, ok := aliases[in["KeyId"].(string)]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"NotFoundException","message":"Alias is not found."}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"KeyMetadata": map[string]string{"KeyId": "key-1", "Arn": arn}})
+		case "TrentService.CreateKey":
+			_ = json.NewEncoder(w).Encode(map[string]any{"KeyMetadata": map[string]string{"KeyId": "key-1", "Arn": "arn:aws:kms:eu-west-1:123456789012:key/key-1"}})
+		case "TrentService.CreateAlias":
+			aliases[in["AliasName"].(string)] = "arn:aws:kms:eu-west-1:123456789012:key/" + in["TargetKeyId"].(string)
+		default:
+			t.Errorf("unexpected target %s", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+	ctx := context.Background()
+
+	if _, ok, err := KeyForAlias(ctx, cfg, "alias/devx/deploy/example"); ok || err != nil {
+		t.Fatalf("got %t, %v; want no key", ok, err)
+	}
+
+	arn, err := CreateKey(ctx, cfg, "test", map[string]string{"App": "example"})
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	if err := CreateAlias(ctx, cfg, "alias/devx/deploy/example", "key-1"); err != nil {
+		t.Fatalf("create alias: %v", err)
+	}
+
+	got, ok, err := KeyForAlias(ctx, cfg, "alias/devx/deploy/example")
+	if !ok || err != nil || got != arn {
+		t.Errorf("got %s, %t, %v; want %s", got, ok, err, arn)
+	}
+}
+
+func TestKMSEncryptDecrypt(t *testing.T) {
+	// Ciphertext is the plaintext with a prefix.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			KeyId                     string
+			Plaintext, CiphertextBlob []byte
+			EncryptionContext         map[string]string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			_ = json.NewEncoder(w).Encode(map[string]any{"CiphertextBlob": append([]byte(in.KeyId+":"), in.Plaintext...)})
+		case "TrentService.Decrypt":
+			_ = json.NewEncoder(w).Encode(map[string]any{"Plaintext": bytes.TrimPrefix(in.CiphertextBlob, []byte("alias/other:"))})
+		case "TrentService.GenerateDataKey":
+			if in.EncryptionContext["parameter"] == "" {
+				t.Errorf("GenerateDataKey: got no encryption context")
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"Plaintext": []byte("key"), "CiphertextBlob": []byte(in.KeyId + ":key")})
+		default:
+			t.Errorf("unexpected target %s", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+	ctx := context.Background()
+
+	ciphertext, err := Encrypt(ctx, cfg, "alias/other", []byte("hunter2"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	plaintext, err := Decrypt(ctx, cfg, "", ciphertext, nil)
+	if err != nil || string(plaintext) != "hunter2" {
+		t.Errorf("got %s, %v; want hunter2", plaintext, err)
+	}
+
+	key, encrypted, err := GenerateDataKey(ctx, cfg, "alias/other", map[string]string{"parameter": "/CODE/deploy/api/key"})
+	if err != nil || string(key) != "key" || string(encrypted) != "alias/other:key" {
+		t.Errorf("got %s, %s, %v; want a data key", key, encrypted, err)
+	}
+
+	if _, err := Decrypt(ctx, cfg, "", "not base64!", nil); err == nil {
+		t.Errorf("invalid ciphertext: expected an error")
+	}
+}
@@ -0,0 +1,66 @@
+package awsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// PutEvent sends an event (detail is its JSON) to an EventBridge bus (empty for
+// the default bus).
+func PutEvent(ctx context.Context, cfg aws.Config, bus string, source string, detailType string, detail []byte) error {
+	entry := types.PutEventsRequestEntry{
+		Source:     aws.String(source),
+		DetailType: aws.String(detailType),
+		Detail:     aws.String(string(detail)),
+	}
+	if bus != "" {
+		entry.EventBusName = aws.String(bus)
+	}
+
+	out, err := eventbridge.NewFromConfig(cfg).PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{entry},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to send event: %w", err)
+	}
+
+	if out.FailedEntryCount > 0 {
+		if len(out.Entries) > 0 {
+			return fmt.Errorf("unable to send event: %s: %s", aws.ToString(out.Entries[0].ErrorCode), aws.ToString(out.Entries[0].ErrorMessage))
+		}
+		return errors.New("unable to send event")
+	}
+
+	return nil
+}
+
+// PublishSNS publishes a message to an SNS topic, in the topic's region.
+func PublishSNS(ctx context.Context, cfg aws.Config, topicARN string, subject string, message string) error {
+	parts := strings.Split(topicARN, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" {
+		return fmt.Errorf("invalid SNS topic ARN '%s'", topicARN)
+	}
+	region := parts[3]
+
+	input := &sns.PublishInput{
+		TopicArn: aws.String(topicARN),
+		Message:  aws.String(message),
+	}
+	if subject != "" {
+		input.Subject = aws.String(subject)
+	}
+
+	_, err := sns.NewFromConfig(cfg, func(o *sns.Options) { o.Region = region }).Publish(ctx, input)
+	if err != nil {
+		return fmt.Errorf("unable to publish to SNS: %w", err)
+	}
+
+	return nil
+}
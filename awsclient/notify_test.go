@@ -0,0 +1,61 @@
+package awsclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestNotify(t *testing.T) {
+	var target, topic, message string
+	var entries []struct{ EventBusName, Detail string }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		if target == "AWSEvents.PutEvents" {
+			var in struct {
+				Entries []struct{ EventBusName, Detail string }
+			}
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			entries = in.Entries
+			_, _ = w.Write([]byte(`{"FailedEntryCount": 0}`))
+			return
+		}
+
+		_ = r.ParseForm()
+		topic, message = r.PostForm.Get("TopicArn"), r.PostForm.Get("Message")
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+	ctx := context.Background()
+
+	if err := PutEvent(ctx, cfg, "config-bus", "devx-config", "Parameter Changed", []byte(`{"name": "a"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].EventBusName != "config-bus" || entries[0].Detail != `{"name": "a"}` {
+		t.Errorf("got %+v; want one entry for config-bus", entries)
+	}
+
+	arn := "arn:aws:sns:us-east-1:123456789012:config-changes"
+	if err := PublishSNS(ctx, cfg, arn, "", "changed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if topic != arn || message != "changed" {
+		t.Errorf("got %s, %s; want the message published to the topic", topic, message)
+	}
+
+	if err := PublishSNS(ctx, cfg, "not-an-arn", "", "changed"); err == nil {
+		t.Errorf("invalid ARN: expected an error")
+	}
+}
@@ -0,0 +1,48 @@
+package awsclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// Partition returns the AWS partition of a region, e.g. 'aws-cn' for
+// 'cn-north-1', for use in ARNs.
+func Partition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b"
+	default:
+		return "aws"
+	}
+}
+
+// The roles to assume, in order: the chain, then RoleARN.
+func roles(opts Options) []string {
+	out := append([]string{}, opts.RoleChain...)
+	if opts.RoleARN != "" {
+		out = append(out, opts.RoleARN)
+	}
+
+	return out
+}
+
+func checkPartition(roleARN string, region string) error {
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return fmt.Errorf("invalid role ARN '%s': %w", roleARN, err)
+	}
+
+	if want := Partition(region); parsed.Partition != want {
+		return fmt.Errorf("role '%s' is in the '%s' partition, but region '%s' is in '%s'", roleARN, parsed.Partition, region, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package awsclient
+
+import "testing"
+
+func TestPartition(t *testing.T) {
+	tests := map[string]string{
+		"eu-west-1":     "aws",
+		"cn-north-1":    "aws-cn",
+		"us-gov-west-1": "aws-us-gov",
+	}
+
+	for region, want := range tests {
+		if got := Partition(region); got != want {
+			t.Errorf("%s: got %s; want %s", region, got, want)
+		}
+	}
+}
+
+func TestCheckPartition(t *testing.T) {
+	if err := checkPartition("arn:aws-cn:iam::123456789012:role/config", "cn-northwest-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := checkPartition("arn:aws:iam::123456789012:role/config", "cn-northwest-1"); err == nil {
+		t.Error("expected an error for a role in another partition")
+	}
+
+	if err := checkPartition("not-an-arn", "eu-west-1"); err == nil {
+		t.Error("expected an error for an invalid ARN")
+	}
+}
@@ -0,0 +1,89 @@
+package awsclient
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+)
+
+// A token bucket, holding up to a second's worth of requests, so that bulk
+// operations don't use up the account-wide API throughput (which is shared with
+// the apps reading their config).
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	burst := math.Max(1, math.Floor(rps))
+	return &rateLimiter{rate: rps, burst: burst, tokens: burst, now: time.Now}
+}
+
+// The limit applies to the whole process, so every config loaded (e.g. one
+// per store) shares a limiter.
+var rateLimiters = struct {
+	sync.Mutex
+	limiters map[float64]*rateLimiter
+}{limiters: map[float64]*rateLimiter{}}
+
+func sharedRateLimiter(rps float64) *rateLimiter {
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+
+	limiter, ok := rateLimiters.limiters[rps]
+	if !ok {
+		limiter = newRateLimiter(rps)
+		rateLimiters.limiters[rps] = limiter
+	}
+	return limiter
+}
+
+// Blocks until a request can be made (or ctx is done). Tokens are taken even
+// if they aren't yet available, so that waiters are served in order.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Added after the retry middleware, so that retries are also limited.
+func rateLimitMiddleware(limiter *rateLimiter) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("DevxRateLimit", func(
+			ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+		) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if err := limiter.Wait(ctx); err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
@@ -0,0 +1,44 @@
+package awsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(20)
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The first 20 are allowed immediately, then the rest at 20/s.
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("30 requests at 20/s took %s; want at least 500ms", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := newRateLimiter(0.1)
+	_ = limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := limiter.Wait(ctx); err != context.Canceled {
+		t.Errorf("got %v; want context.Canceled", err)
+	}
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	if sharedRateLimiter(7) != sharedRateLimiter(7) {
+		t.Error("got separate limiters for the same rate; want one shared")
+	}
+	if sharedRateLimiter(7) == sharedRateLimiter(8) {
+		t.Error("got one limiter for different rates; want separate")
+	}
+}
@@ -0,0 +1,73 @@
+package awsclient
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+)
+
+// DefaultRegion is used if no region is given, configured or found in instance
+// metadata (and there's no configured default).
+const DefaultRegion = "eu-west-1"
+
+// IMDSRegionTimeout bounds the instance metadata lookup, which otherwise
+// retries for a while when not running on EC2.
+var IMDSRegionTimeout = time.Second
+
+// Resolves the region the SDK didn't find (in AWS_REGION or the profile) from
+// instance metadata, then the configured default, then DefaultRegion. Returns
+// the region and where it came from, for logging.
+func resolveRegion(ctx context.Context, opts Options, sdkRegion string) (string, string) {
+	switch {
+	case opts.Region != "":
+		return opts.Region, "given"
+	case sdkRegion != "" && sdkRegion == os.Getenv("AWS_REGION"):
+		return sdkRegion, "AWS_REGION"
+	case sdkRegion != "":
+		return sdkRegion, "AWS profile"
+	}
+
+	if region := imdsRegion(ctx); region != "" {
+		return region, "instance metadata"
+	}
+
+	if opts.DefaultRegion != "" {
+		return opts.DefaultRegion, "configured default"
+	}
+
+	return DefaultRegion, "default"
+}
+
+// The instance metadata region, looked up at most once per process, since
+// off EC2 (e.g. on a laptop) every lookup waits for IMDSRegionTimeout.
+var imdsRegionCache struct {
+	once   sync.Once
+	region string
+}
+
+func imdsRegion(ctx context.Context) string {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return ""
+	}
+
+	imdsRegionCache.once.Do(func() {
+		imdsRegionCache.region = lookupIMDSRegion(ctx)
+	})
+	return imdsRegionCache.region
+}
+
+func lookupIMDSRegion(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, IMDSRegionTimeout)
+	defer cancel()
+
+	out, err := imds.New(imds.Options{}).GetRegion(ctx, &imds.GetRegionInput{})
+	if err != nil {
+		return ""
+	}
+
+	return out.Region
+}
@@ -0,0 +1,47 @@
+package awsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResolveRegion(t *testing.T) {
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	tests := []struct {
+		opts       Options
+		sdkRegion  string
+		want, from string
+	}{
+		{Options{Region: "eu-west-2"}, "us-east-1", "eu-west-2", "given"},
+		{Options{}, "us-east-1", "us-east-1", "AWS_REGION"},
+		{Options{}, "us-west-2", "us-west-2", "AWS profile"},
+		{Options{DefaultRegion: "us-east-2"}, "", "us-east-2", "configured default"},
+		{Options{}, "", DefaultRegion, "default"},
+	}
+
+	for _, tc := range tests {
+		got, from := resolveRegion(context.Background(), tc.opts, tc.sdkRegion)
+		if got != tc.want || from != tc.from {
+			t.Errorf("%+v, %s: got %s (%s); want %s (%s)", tc.opts, tc.sdkRegion, got, from, tc.want, tc.from)
+		}
+	}
+}
+
+func TestIMDSRegionCached(t *testing.T) {
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "false")
+
+	// Only the first lookup can wait for instance metadata (e.g. off EC2).
+	first := imdsRegion(context.Background())
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		if region := imdsRegion(context.Background()); region != first {
+			t.Errorf("got %s; want %s", region, first)
+		}
+		if time.Since(start) > 100*time.Millisecond {
+			t.Errorf("lookup took %s; want it cached", time.Since(start))
+		}
+	}
+}
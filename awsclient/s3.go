@@ -0,0 +1,77 @@
+package awsclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PutObject writes an object encrypted with KMS, using the given key (an ID,
+// ARN or alias) or, if empty, the account's AWS managed key for S3.
+func PutObject(ctx context.Context, cfg aws.Config, bucket string, key string, body []byte, kmsKeyID string) error {
+	input := &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ContentType:          aws.String("application/json"),
+		ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+	}
+	if kmsKeyID != "" {
+		input.SSEKMSKeyId = aws.String(kmsKeyID)
+	}
+
+	if _, err := newS3(cfg).PutObject(ctx, input); err != nil {
+		return fmt.Errorf("unable to write s3://%s/%s: %w", bucket, key, err)
+	}
+
+	return nil
+}
+
+// ListObjects returns the keys of every object under the prefix, in
+// lexicographical order.
+func ListObjects(ctx context.Context, cfg aws.Config, bucket string, prefix string) ([]string, error) {
+	pages := s3.NewListObjectsV2Paginator(newS3(cfg), &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	var keys []string
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+
+		for _, c := range page.Contents {
+			keys = append(keys, aws.ToString(c.Key))
+		}
+	}
+
+	return keys, nil
+}
+
+// DeleteObject deletes an object (in a versioned bucket, adding a delete
+// marker).
+func DeleteObject(ctx context.Context, cfg aws.Config, bucket string, key string) error {
+	_, err := newS3(cfg).DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete s3://%s/%s: %w", bucket, key, err)
+	}
+
+	return nil
+}
+
+// Custom endpoints (e.g. LocalStack) are addressed path-style, and AWS
+// virtual-hosted style.
+func newS3(cfg aws.Config) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = cfg.EndpointResolverWithOptions != nil
+	})
+}
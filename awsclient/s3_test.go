@@ -0,0 +1,78 @@
+package awsclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestS3(t *testing.T) {
+	objects := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Content-Sha256") == "" {
+			t.Errorf("%s %s: missing payload hash", r.Method, r.URL)
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch r.Method {
+		case http.MethodPut:
+			if got := r.Header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"); got != "alias/backups" {
+				t.Errorf("got KMS key %q", got)
+			}
+			body, _ := io.ReadAll(r.Body)
+			objects[key] = string(body)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			// One key per page, to exercise pagination.
+			after := r.URL.Query().Get("continuation-token")
+			for _, k := range []string{"backups/a.json", "backups/b.json"} {
+				if _, ok := objects[k]; ok && k > after {
+					fmt.Fprintf(w, "<ListBucketResult><IsTruncated>true</IsTruncated><Contents><Key>%s</Key></Contents><NextContinuationToken>%s</NextContinuationToken></ListBucketResult>", k, k)
+					return
+				}
+			}
+			fmt.Fprint(w, "<ListBucketResult><IsTruncated>false</IsTruncated></ListBucketResult>")
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"backups/a.json", "backups/b.json"} {
+		if err := PutObject(ctx, cfg, "bucket", key, []byte("[]"), "alias/backups"); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+	}
+
+	keys, err := ListObjects(ctx, cfg, "bucket", "backups/")
+	if err != nil || !reflect.DeepEqual(keys, []string{"backups/a.json", "backups/b.json"}) {
+		t.Fatalf("list: got %v, %v", keys, err)
+	}
+
+	if err := DeleteObject(ctx, cfg, "bucket", "backups/a.json"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := objects["backups/a.json"]; ok {
+		t.Errorf("delete: object still exists")
+	}
+}
@@ -0,0 +1,79 @@
+package awsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// ErrSimulationUnavailable is returned by Simulate if the policy simulator
+// can't be used, e.g. as the caller lacks iam:SimulatePrincipalPolicy. Callers
+// should carry on without the check.
+var ErrSimulationUnavailable = errors.New("IAM policy simulation unavailable")
+
+// Identity returns the caller's account and the ARN of their IAM user or role
+// (rather than of the assumed role session), as needed by Simulate.
+func Identity(ctx context.Context, cfg aws.Config) (string, string, error) {
+	out, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get AWS identity: %w", err)
+	}
+
+	principal, err := principalARN(aws.ToString(out.Arn))
+	return aws.ToString(out.Account), principal, err
+}
+
+// Converts an assumed role session ARN (arn:aws:sts::1234:assumed-role/name/session)
+// to that of the role. Note, role paths aren't included in session ARNs, so
+// roles with a path can't be simulated.
+func principalARN(callerARN string) (string, error) {
+	parsed, err := arn.Parse(callerARN)
+	if err != nil {
+		return "", fmt.Errorf("invalid caller ARN '%s': %w", callerARN, err)
+	}
+
+	if parsed.Service == "sts" && strings.HasPrefix(parsed.Resource, "assumed-role/") {
+		parts := strings.Split(parsed.Resource, "/")
+		parsed.Service = "iam"
+		parsed.Resource = "role/" + parts[1]
+	}
+
+	return parsed.String(), nil
+}
+
+// Simulate checks with the IAM policy simulator that the principal is allowed
+// every action on every resource, returning an error naming those it isn't.
+func Simulate(ctx context.Context, cfg aws.Config, principal string, actions []string, resources []string) error {
+	pages := iam.NewSimulatePrincipalPolicyPaginator(iam.NewFromConfig(cfg), &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principal),
+		ActionNames:     actions,
+		ResourceArns:    resources,
+	})
+
+	var denied []string
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSimulationUnavailable, err)
+		}
+
+		for _, r := range page.EvaluationResults {
+			if r.EvalDecision != types.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, fmt.Sprintf("%s on %s", aws.ToString(r.EvalActionName), aws.ToString(r.EvalResourceName)))
+			}
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("%s lacks %s", principal, strings.Join(denied, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package awsclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestPrincipalARN(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:sts::123456789012:assumed-role/developer/janus-session": "arn:aws:iam::123456789012:role/developer",
+		"arn:aws:iam::123456789012:user/alice":                           "arn:aws:iam::123456789012:user/alice",
+	}
+
+	for caller, want := range tests {
+		got, err := principalARN(caller)
+		if err != nil || got != want {
+			t.Errorf("%s: got %s, %v; want %s", caller, got, err, want)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	denied := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if denied {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>not allowed</Message></Error></ErrorResponse>`))
+			return
+		}
+
+		_ = r.ParseForm()
+		if r.Form.Get("Action") != "SimulatePrincipalPolicy" || r.Form.Get("ActionNames.member.1") != "ssm:PutParameter" {
+			t.Errorf("got form %v", r.Form)
+		}
+
+		_, _ = w.Write([]byte(`<SimulatePrincipalPolicyResponse><SimulatePrincipalPolicyResult><IsTruncated>false</IsTruncated><EvaluationResults>
+			<member><EvalActionName>ssm:PutParameter</EvalActionName><EvalResourceName>arn:param</EvalResourceName><EvalDecision>allowed</EvalDecision></member>
+			<member><EvalActionName>ssm:AddTagsToResource</EvalActionName><EvalResourceName>arn:param</EvalResourceName><EvalDecision>implicitDeny</EvalDecision></member>
+		</EvaluationResults></SimulatePrincipalPolicyResult></SimulatePrincipalPolicyResponse>`))
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...any) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+
+	err := Simulate(context.Background(), cfg, "arn:aws:iam::123456789012:role/ci", []string{"ssm:PutParameter", "ssm:AddTagsToResource"}, []string{"arn:param"})
+	if err == nil || errors.Is(err, ErrSimulationUnavailable) || !strings.Contains(err.Error(), "role/ci lacks ssm:AddTagsToResource on arn:param") {
+		t.Errorf("got %v; want the denied action", err)
+	}
+
+	denied = true
+	err = Simulate(context.Background(), cfg, "arn:aws:iam::123456789012:role/ci", []string{"ssm:PutParameter"}, []string{"arn:param"})
+	if !errors.Is(err, ErrSimulationUnavailable) {
+		t.Errorf("got %v; want ErrSimulationUnavailable", err)
+	}
+}
@@ -0,0 +1,29 @@
+package awsclient
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+)
+
+// IsSSOExpired returns whether err is due to a missing or expired IAM Identity
+// Center (SSO) session, which 'aws sso login' fixes.
+func IsSSOExpired(err error) bool {
+	var invalid *ssocreds.InvalidTokenError
+	return errors.As(err, &invalid)
+}
+
+// SSOLoginCommand returns the AWS CLI command to log in to the named SSO
+// session or, failing that, the SSO session for the profile.
+func SSOLoginCommand(profile string, ssoSession string) []string {
+	cmd := []string{"aws", "sso", "login"}
+
+	switch {
+	case ssoSession != "":
+		return append(cmd, "--sso-session", ssoSession)
+	case profile != "":
+		return append(cmd, "--profile", profile)
+	default:
+		return cmd
+	}
+}
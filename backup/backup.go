@@ -0,0 +1,58 @@
+// Package backup names and prunes snapshots of a service's parameters kept in
+// S3. Snapshots are manifests (see package drift), so can be compared against
+// with 'drift report'.
+package backup
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// Snapshot keys sort in time order.
+const timeFormat = "20060102T150405Z"
+
+// ParseS3URL splits an 's3://bucket/prefix' URL. The prefix may be empty.
+func ParseS3URL(s string) (string, string, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 URL '%s' (must be s3://bucket/prefix)", s)
+	}
+
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// Dir returns the key prefix for the service's snapshots, e.g.
+// 'backups/PROD/deploy/example/'.
+func Dir(prefix string, service store.Service) string {
+	return path.Join(prefix, strings.Trim(service.Prefix(), "/")) + "/"
+}
+
+// Key returns the key for a snapshot of the service taken at t.
+func Key(prefix string, service store.Service, t time.Time) string {
+	return Dir(prefix, service) + t.UTC().Format(timeFormat) + ".json"
+}
+
+// Expired returns the snapshot keys beyond the newest keep, oldest first.
+// Other keys under the prefix are ignored.
+func Expired(keys []string, keep int) []string {
+	var snapshots []string
+	for _, k := range keys {
+		name := strings.TrimSuffix(path.Base(k), ".json")
+		if _, err := time.Parse(timeFormat, name); err == nil && strings.HasSuffix(k, ".json") {
+			snapshots = append(snapshots, k)
+		}
+	}
+	sort.Strings(snapshots)
+
+	if len(snapshots) <= keep {
+		return nil
+	}
+
+	return snapshots[:len(snapshots)-keep]
+}
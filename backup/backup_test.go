@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestParseS3URL(t *testing.T) {
+	bucket, prefix, err := ParseS3URL("s3://my-bucket/devx/backups/")
+	if err != nil || bucket != "my-bucket" || prefix != "devx/backups" {
+		t.Errorf("got %s, %s, %v", bucket, prefix, err)
+	}
+
+	for _, invalid := range []string{"my-bucket/backups", "https://my-bucket/backups", "s3:///backups"} {
+		if _, _, err := ParseS3URL(invalid); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	service := store.Service{Stage: "PROD", Stack: "deploy", App: "example"}
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	if got, want := Key("backups", service, at), "backups/PROD/deploy/example/20240301T123000Z.json"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	if got, want := Key("", service, at), "PROD/deploy/example/20240301T123000Z.json"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	keys := []string{
+		"b/PROD/deploy/example/20240303T000000Z.json",
+		"b/PROD/deploy/example/20240301T000000Z.json",
+		"b/PROD/deploy/example/notes.txt",
+		"b/PROD/deploy/example/20240302T000000Z.json",
+	}
+
+	want := []string{"b/PROD/deploy/example/20240301T000000Z.json"}
+	if got := Expired(keys, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	if got := Expired(keys, 3); got != nil {
+		t.Errorf("got %v; want nothing expired", got)
+	}
+}
@@ -0,0 +1,149 @@
+// Package cdk generates snippets of CDK code or CloudFormation that reference
+// a service's parameters by name, so infrastructure code uses the names they
+// are actually stored under.
+package cdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type Lang string
+
+const (
+	TypeScript Lang = "typescript" // CDK constructs, e.g. StringParameter.fromSecureStringParameterAttributes
+	JSON       Lang = "json"       // CloudFormation dynamic references, e.g. '{{resolve:ssm-secure:...}}'
+)
+
+func (l Lang) Validate() error {
+	switch l {
+	case TypeScript, JSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported language '%s' (must be one of 'typescript', 'json')", l)
+	}
+}
+
+// Snippet returns code referencing each of the parameters, sorted by name.
+// Only SSM parameters and Secrets Manager secrets can be referenced.
+func Snippet(service store.Service, params []store.Parameter, lang Lang) ([]byte, error) {
+	if err := lang.Validate(); err != nil {
+		return nil, err
+	}
+
+	sorted := append([]store.Parameter{}, params...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	refs := []ref{}
+	seen := map[string]string{} // identifiers to names, to catch clashes
+	for _, p := range sorted {
+		if !p.DeletedDate.IsZero() {
+			continue
+		}
+		if p.Store != "ssm" && p.Store != "secretsmanager" {
+			return nil, fmt.Errorf("'%s' is in the %s store, which CloudFormation can't reference", p.RelativeName(), p.Store)
+		}
+
+		r := ref{p, identifier(p.RelativeName())}
+		if other, ok := seen[r.id]; ok {
+			return nil, fmt.Errorf("'%s' and '%s' would both be named '%s'", other, p.RelativeName(), r.id)
+		}
+		seen[r.id] = p.RelativeName()
+		refs = append(refs, r)
+	}
+
+	if lang == JSON {
+		return dynamicReferences(refs)
+	}
+	return typeScript(service, refs), nil
+}
+
+type ref struct {
+	store.Parameter
+	id string // camelCase, e.g. 'dbPassword'
+}
+
+func typeScript(service store.Service, refs []ref) []byte {
+	var b bytes.Buffer
+	b.WriteString(`import { aws_secretsmanager as secretsmanager, aws_ssm as ssm } from "aws-cdk-lib";` + "\n\n")
+	fmt.Fprintf(&b, "// Parameters of %s, generated by devx-config.\n", service.Prefix())
+
+	for _, r := range refs {
+		name := quote(r.Name)
+		constructID := quote(strings.ToUpper(r.id[:1]) + r.id[1:])
+
+		switch {
+		case r.Store == "secretsmanager":
+			fmt.Fprintf(&b, "const %s = secretsmanager.Secret.fromSecretNameV2(this, %s, %s);\n", r.id, constructID, name)
+		case r.IsSecret:
+			fmt.Fprintf(&b, "const %s = ssm.StringParameter.fromSecureStringParameterAttributes(this, %s, {\n  parameterName: %s,\n});\n", r.id, constructID, name)
+		default:
+			fmt.Fprintf(&b, "const %s = ssm.StringParameter.valueForStringParameter(this, %s);\n", r.id, name)
+		}
+	}
+
+	return b.Bytes()
+}
+
+// Dynamic references, keyed by each parameter's Key (e.g. 'db_password'), as
+// for an environment. Note, 'ssm-secure' references only work in certain
+// resource properties (e.g. database passwords).
+func dynamicReferences(refs []ref) ([]byte, error) {
+	out := map[string]string{}
+	for _, r := range refs {
+		switch {
+		case r.Store == "secretsmanager":
+			out[r.Key()] = fmt.Sprintf("{{resolve:secretsmanager:%s:SecretString}}", r.Name)
+		case r.IsSecret:
+			out[r.Key()] = fmt.Sprintf("{{resolve:ssm-secure:%s}}", r.Name)
+		default:
+			out[r.Key()] = fmt.Sprintf("{{resolve:ssm:%s}}", r.Name)
+		}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// Returns name as a camelCase identifier, e.g. 'dbPassword' for 'db/password'.
+func identifier(name string) string {
+	var b strings.Builder
+	for _, word := range nonAlphanumeric.Split(name, -1) {
+		if word == "" {
+			continue
+		}
+		if word == strings.ToUpper(word) { // e.g. 'DB_HOST'
+			word = strings.ToLower(word)
+		}
+		if b.Len() == 0 {
+			b.WriteString(strings.ToLower(word[:1]) + word[1:])
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	id := b.String()
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "param" + id
+	}
+
+	return id
+}
+
+// JSON strings are valid TypeScript strings.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
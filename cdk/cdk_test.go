@@ -0,0 +1,75 @@
+package cdk
+
+import (
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestSnippet(t *testing.T) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/api/port", Store: "ssm"},
+		{Service: service, Name: "/PROD/deploy/api/db/password", Store: "ssm", IsSecret: true},
+		{Service: service, Name: "/PROD/deploy/api/api-key", Store: "secretsmanager", IsSecret: true},
+	}
+
+	got, err := Snippet(service, params, TypeScript)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `import { aws_secretsmanager as secretsmanager, aws_ssm as ssm } from "aws-cdk-lib";
+
+// Parameters of /PROD/deploy/api, generated by devx-config.
+const apiKey = secretsmanager.Secret.fromSecretNameV2(this, "ApiKey", "/PROD/deploy/api/api-key");
+const dbPassword = ssm.StringParameter.fromSecureStringParameterAttributes(this, "DbPassword", {
+  parameterName: "/PROD/deploy/api/db/password",
+});
+const port = ssm.StringParameter.valueForStringParameter(this, "/PROD/deploy/api/port");
+`
+	if string(got) != want {
+		t.Errorf("typescript: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = Snippet(service, params, JSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want = `{
+  "api-key": "{{resolve:secretsmanager:/PROD/deploy/api/api-key:SecretString}}",
+  "db_password": "{{resolve:ssm-secure:/PROD/deploy/api/db/password}}",
+  "port": "{{resolve:ssm:/PROD/deploy/api/port}}"
+}
+`
+	if string(got) != want {
+		t.Errorf("json: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := Snippet(service, params, "python"); err == nil {
+		t.Errorf("python: expected an error")
+	}
+
+	plugin := []store.Parameter{{Service: service, Name: "/PROD/deploy/api/token", Store: "vault"}}
+	if _, err := Snippet(service, plugin, JSON); err == nil {
+		t.Errorf("plugin store: expected an error")
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"db/password":     "dbPassword",
+		"DB_HOST":         "dbHost",
+		"api-key":         "apiKey",
+		"2fa/secret":      "param2faSecret",
+		"tls/cert.pem":    "tlsCertPem",
+		"nested//name/-x": "nestedNameX",
+	}
+
+	for name, want := range tests {
+		if got := identifier(name); got != want {
+			t.Errorf("%s: got %s; want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package clipboard provides minimal access to the system clipboard by
+// shelling out to the platform's clipboard utilities (pbcopy on macOS, and
+// wl-copy, xclip or xsel on Linux).
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+type tool struct {
+	copy, paste []string
+}
+
+var ErrUnavailable = errors.New("no clipboard utility found (install pbcopy, wl-copy, xclip or xsel)")
+
+func tools() []tool {
+	if runtime.GOOS == "darwin" {
+		return []tool{{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}}
+	}
+
+	return []tool{
+		{copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+		{copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+		{copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}},
+	}
+}
+
+func find() (tool, error) {
+	for _, t := range tools() {
+		if _, err := exec.LookPath(t.copy[0]); err == nil {
+			return t, nil
+		}
+	}
+
+	return tool{}, ErrUnavailable
+}
+
+// Write places value on the system clipboard.
+func Write(value string) error {
+	t, err := find()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(t.copy[0], t.copy[1:]...)
+	cmd.Stdin = strings.NewReader(value)
+	return cmd.Run()
+}
+
+// Read returns the current contents of the system clipboard.
+func Read() (string, error) {
+	t, err := find()
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(t.paste[0], t.paste[1:]...)
+	cmd.Stdout = &out
+	err = cmd.Run()
+	return out.String(), err
+}
+
+// Clear empties the clipboard, but only if it still holds value, so that
+// anything the user has copied since is left alone.
+func Clear(value string) error {
+	got, err := Read()
+	if err != nil {
+		return err
+	}
+
+	if got != value {
+		return nil
+	}
+
+	return Write("")
+}
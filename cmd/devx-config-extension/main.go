@@ -0,0 +1,111 @@
+// Command devx-config-extension is devx-config as an AWS Lambda extension (see
+// package extension). Build it for the function's architecture and add it to a
+// layer as 'extensions/devx-config-extension':
+//
+//	GOOS=linux GOARCH=arm64 go build -o layer/extensions/devx-config-extension ./cmd/devx-config-extension
+//
+// It is configured with environment variables:
+//
+//	DEVX_CONFIG_EXTENSION_PORT   port to serve on (default 2773)
+//	DEVX_CONFIG_EXTENSION_TTL    how long to cache parameters for (default 5m)
+//	DEVX_CONFIG_LOG_LEVEL        as --log-level (default 'info')
+//
+// as well as the function's APP, STACK and STAGE, or a devx-config.yaml in the
+// function's package, as for the CLI.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/guardian/devx-config/extension"
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/pkg/devxconfig"
+)
+
+func main() {
+	logger, err := log.New(os.Stderr, envOr("DEVX_CONFIG_LOG_LEVEL", "info"), log.FormatPlain)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := run(context.Background(), logger); err != nil {
+		logger.Errorf("%v", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, logger log.Logger) error {
+	host := os.Getenv(extension.RuntimeAPIEnv)
+	if host == "" {
+		return fmt.Errorf("%s isn't set; this must be run as a Lambda extension", extension.RuntimeAPIEnv)
+	}
+
+	port, err := strconv.Atoi(envOr("DEVX_CONFIG_EXTENSION_PORT", strconv.Itoa(extension.DefaultPort)))
+	if err != nil {
+		return fmt.Errorf("invalid DEVX_CONFIG_EXTENSION_PORT: %w", err)
+	}
+
+	ttl, err := time.ParseDuration(envOr("DEVX_CONFIG_EXTENSION_TTL", "5m"))
+	if err != nil {
+		return fmt.Errorf("invalid DEVX_CONFIG_EXTENSION_TTL: %w", err)
+	}
+
+	api := extension.NewAPI(host)
+	id, err := api.Register(ctx, filepath.Base(os.Args[0]))
+	if err != nil {
+		return err
+	}
+
+	// Parameters are read through store.Cached, re-read once older than ttl.
+	client, err := devxconfig.New(ctx, devxconfig.Options{Logger: logger, CacheTTL: ttl})
+	if err != nil {
+		return err
+	}
+
+	// Warm the cache at cold start, so the function's first reads are local.
+	params, err := client.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read parameters for %s: %w", client.Service().Prefix(), err)
+	}
+	logger.Infof("cached %d parameters for %s", len(params), client.Service().Prefix())
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: extension.Handler(client.List, os.Getenv("AWS_SESSION_TOKEN")), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("unable to serve: %v", err)
+		}
+	}()
+
+	for {
+		event, err := api.Next(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if event.EventType == "SHUTDOWN" {
+			logger.Debugf("shutting down: %s", event.ShutdownReason)
+			return srv.Shutdown(ctx)
+		}
+	}
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
@@ -5,19 +5,290 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 var DefaultLocalPath = ".devx-config"
 var DefaultEC2Path = "/etc/config/tags.json" // set by Amigo 'cdk-base' role
 
+// Local config files can also be YAML or TOML, which (unlike JSON) allow
+// comments. They are looked for in this order.
+var LocalPaths = []string{DefaultLocalPath, ".devx-config.yaml", ".devx-config.yml", ".devx-config.toml"}
+
+// Note, JSON keys are matched case-insensitively, so 'app' works as well as
+// the canonical 'App'. YAML and TOML keys are camelCase.
 type Config struct {
-	Stack, Stage, App string
+	Stack string `yaml:"stack,omitempty" toml:"stack,omitempty"`
+	Stage string `yaml:"stage,omitempty" toml:"stage,omitempty"`
+	App   string `yaml:"app,omitempty" toml:"app,omitempty"`
+
+	// Optional settings, which aren't part of a service's identity.
+	LogFile        string            `json:",omitempty" yaml:"logFile,omitempty" toml:"logFile,omitempty"`
+	Region         string            `json:",omitempty" yaml:"region,omitempty" toml:"region,omitempty"`
+	DefaultRegion  string            `json:",omitempty" yaml:"defaultRegion,omitempty" toml:"defaultRegion,omitempty"` // used if no region is set here, by AWS_REGION, the AWS profile or instance metadata
+	StoreRegions   map[string]string `json:",omitempty" yaml:"storeRegions,omitempty" toml:"storeRegions,omitempty"`   // per-store region overrides, keyed by store name (e.g. 'ssm')
+	EndpointURL    string            `json:",omitempty" yaml:"endpointUrl,omitempty" toml:"endpointUrl,omitempty"`
+	StoreEndpoints map[string]string `json:",omitempty" yaml:"storeEndpoints,omitempty" toml:"storeEndpoints,omitempty"` // per-store endpoint URL overrides, keyed by store name
+	AWSProfile     string            `json:",omitempty" yaml:"awsProfile,omitempty" toml:"awsProfile,omitempty"`         // used if --profile isn't given
+	SSOSession     string            `json:",omitempty" yaml:"ssoSession,omitempty" toml:"ssoSession,omitempty"`         // used if --sso-session isn't given
+	Output         string            `json:",omitempty" yaml:"output,omitempty" toml:"output,omitempty"`                 // used if --output isn't given
+	KMSKey         string            `json:",omitempty" yaml:"kmsKey,omitempty" toml:"kmsKey,omitempty"`                 // for encrypting secrets, e.g. 'alias/my-key'
+	Account        string            `json:",omitempty" yaml:"account,omitempty" toml:"account,omitempty"`               // if set, AWS credentials must be for this account
+
+	Preflight bool `json:",omitempty" yaml:"preflight,omitempty" toml:"preflight,omitempty"` // as --preflight
+
+	// Used if --proxy or --ca-bundle aren't given, e.g. behind an office
+	// proxy. These are machine-specific, so belong in the user config file.
+	Proxy    string `json:",omitempty" yaml:"proxy,omitempty" toml:"proxy,omitempty"`
+	CABundle string `json:",omitempty" yaml:"caBundle,omitempty" toml:"caBundle,omitempty"`
+
+	// Used if --max-retries or --retry-mode aren't given.
+	MaxRetries int    `json:",omitempty" yaml:"maxRetries,omitempty" toml:"maxRetries,omitempty"`
+	RetryMode  string `json:",omitempty" yaml:"retryMode,omitempty" toml:"retryMode,omitempty"` // 'standard' or 'adaptive'
+
+	// A role to assume (used if --role-arn isn't given), with an optional
+	// external ID and session name. Roles in RoleChain (e.g. one in a central
+	// account that can assume roles in member accounts) are assumed first, in
+	// turn, starting from the AWS profile's credentials.
+	RoleChain   []string `json:",omitempty" yaml:"roleChain,omitempty" toml:"roleChain,omitempty"`
+	RoleARN     string   `json:",omitempty" yaml:"roleArn,omitempty" toml:"roleArn,omitempty"`
+	ExternalID  string   `json:",omitempty" yaml:"externalId,omitempty" toml:"externalId,omitempty"`
+	SessionName string   `json:",omitempty" yaml:"sessionName,omitempty" toml:"sessionName,omitempty"`
+
+	// Extra tags (e.g. Owner or Repo) for parameters, alongside App, Stack and
+	// Stage.
+	Tags map[string]string `json:",omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+
+	// Go template for the path beneath which a service's parameters are
+	// stored, e.g. '/{{.Stage}}/{{.App}}' (see store.Service).
+	PrefixTemplate string `json:",omitempty" yaml:"prefixTemplate,omitempty" toml:"prefixTemplate,omitempty"`
+
+	// Where to look up services (by --service) that aren't in the workspace
+	// config: an SSM path ('ssm:/path') or HTTP(S) URL. See LookupService.
+	Registry string `json:",omitempty" yaml:"registry,omitempty" toml:"registry,omitempty"`
+
+	// Allowed stages, an optional regular expression for others (e.g.
+	// '^PREVIEW-[0-9]+$'), and aliases for them (e.g. 'production' for
+	// 'PROD'). Any stage is allowed if neither stages nor a pattern are set.
+	// See NormaliseStage.
+	Stages       []string          `json:",omitempty" yaml:"stages,omitempty" toml:"stages,omitempty"`
+	StagePattern string            `json:",omitempty" yaml:"stagePattern,omitempty" toml:"stagePattern,omitempty"`
+	StageAliases map[string]string `json:",omitempty" yaml:"stageAliases,omitempty" toml:"stageAliases,omitempty"`
+
+	// The store used for parameters that don't match any route, and rules for
+	// routing others (e.g. '*_SECRET' to 'secretsmanager'). Used if --store
+	// isn't given.
+	DefaultStore string  `json:",omitempty" yaml:"defaultStore,omitempty" toml:"defaultStore,omitempty"`
+	Routes       []Route `json:",omitempty" yaml:"routes,omitempty" toml:"routes,omitempty"`
+
+	// Values that shadow those in the stores when the Go library reads
+	// parameters, keyed by name (e.g. 'db/host: localhost'), so developers can
+	// override individual values locally. Best kept in the user config file.
+	Overrides map[string]string `json:",omitempty" yaml:"overrides,omitempty" toml:"overrides,omitempty"`
+
+	// Transformations applied to the values of parameters whose names match
+	// each rule's pattern, as they are read and written (see package
+	// transform), e.g. 'base64' for 'tls/*'.
+	Transforms []Transform `json:",omitempty" yaml:"transforms,omitempty" toml:"transforms,omitempty"`
+
+	// Where to publish an event for each change to a parameter (see package
+	// events), e.g. 'sns:<topic ARN>' or 'webhook:<URL>'.
+	EventSinks []string `json:",omitempty" yaml:"eventSinks,omitempty" toml:"eventSinks,omitempty"`
+
+	// Another config file (relative to this one) whose settings this file
+	// inherits and overrides, e.g. '../shared/.devx-config'.
+	Extends string `json:",omitempty" yaml:"extends,omitempty" toml:"extends,omitempty"`
+
+	// Named sets of overrides for the settings above, selected with
+	// --config-profile (or 'default', if present, otherwise).
+	Profiles map[string]Config `json:",omitempty" yaml:"profiles,omitempty" toml:"profiles,omitempty"`
+
+	// Overrides for the settings above, keyed by stage and applied to
+	// whichever stage is in use (e.g. a different AWS profile for PROD).
+	StageOverrides map[string]Config `json:",omitempty" yaml:"stageOverrides,omitempty" toml:"stageOverrides,omitempty"`
+
+	// Services in a monorepo workspace, keyed by name. See WithService.
+	Services map[string]Service `json:",omitempty" yaml:"services,omitempty" toml:"services,omitempty"`
+}
+
+const DefaultProfile = "default"
+
+const DefaultStore = "ssm"
+
+// DefaultStages are the canonical forms of the usual stages, used to normalise
+// their case when no stages are configured.
+var DefaultStages = []string{"CODE", "PROD", "TEST"}
+
+// Route sends parameters whose names match Pattern (a glob, as per path.Match)
+// to Store.
+type Route struct {
+	Pattern string `yaml:"pattern" toml:"pattern"`
+	Store   string `yaml:"store" toml:"store"`
+}
+
+// Transform applies the named transformations (in order when reading, and in
+// reverse when writing) to parameters whose names match Pattern (a glob, as
+// per path.Match, or every parameter if empty). A transformation may take an argument after a colon, e.g.
+// 'kms:alias/other-key'.
+type Transform struct {
+	Pattern string   `yaml:"pattern" toml:"pattern"`
+	Apply   []string `yaml:"apply" toml:"apply"`
+}
+
+// StoreFor returns the store for the named parameter: that of the first
+// matching route, or else the default store.
+func (c Config) StoreFor(name string) string {
+	for _, route := range c.Routes {
+		if ok, _ := path.Match(route.Pattern, name); ok {
+			return route.Store
+		}
+	}
+
+	return c.defaultStore()
+}
+
+func (c Config) defaultStore() string {
+	if c.DefaultStore != "" {
+		return c.DefaultStore
+	}
+
+	return DefaultStore
+}
+
+// Stores returns all stores that parameters might be in: the default store
+// followed by any others that are routed to.
+func (c Config) Stores() []string {
+	stores := []string{c.defaultStore()}
+	seen := map[string]bool{stores[0]: true}
+
+	for _, route := range c.Routes {
+		if !seen[route.Store] {
+			stores = append(stores, route.Store)
+			seen[route.Store] = true
+		}
+	}
+
+	return stores
+}
+
+// WithProfile returns the config with the named profile's settings merged on
+// top. An empty name selects the 'default' profile if there is one.
+func (c Config) WithProfile(name string) (Config, error) {
+	if name == "" {
+		name = DefaultProfile
+		if _, ok := c.Profiles[name]; !ok {
+			return c, nil
+		}
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return c, fmt.Errorf("config profile '%s' not found", name)
+	}
+
+	profile.Profiles = nil // profiles don't nest
+	return Merge(c, profile), nil
+}
+
+// WithStage returns the config with the overrides for stage (matched
+// case-insensitively) merged on top. Overrides can't change the stage itself.
+func (c Config) WithStage(stage string) Config {
+	for name, override := range c.StageOverrides {
+		if strings.EqualFold(name, stage) {
+			override.Stage = ""
+			override.StageOverrides = nil
+			return Merge(c, override)
+		}
+	}
+
+	return c
+}
+
+// Validate checks that the fields identifying a service are all present.
+func (c Config) Validate() error {
+	if c.App == "" || c.Stack == "" || c.Stage == "" {
+		return fmt.Errorf("mandatory flag missing or empty (got app='%s', stack='%s', stage='%s')", c.App, c.Stack, c.Stage)
+	}
+
+	return nil
+}
+
+// NormaliseStage returns the canonical form of stage, resolving aliases and
+// case (so 'prod' becomes 'PROD'). If stages or a stage pattern are
+// configured, it returns an error for any other stage, catching typos (e.g.
+// 'PORD') before anything is written under them; otherwise any stage is
+// allowed, so existing services with their own stage names keep working.
+func (c Config) NormaliseStage(stage string) (string, error) {
+	for alias, target := range c.StageAliases {
+		if strings.EqualFold(alias, stage) {
+			stage = target
+			break
+		}
+	}
+
+	stages := c.Stages
+	if len(stages) == 0 {
+		stages = DefaultStages
+	}
+
+	for _, s := range stages {
+		if strings.EqualFold(s, stage) {
+			return s, nil
+		}
+	}
+
+	if len(c.Stages) == 0 && c.StagePattern == "" {
+		return stage, nil
+	}
+
+	if c.StagePattern != "" {
+		re, err := regexp.Compile(c.StagePattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid stage pattern: %w", err)
+		}
+
+		if re.MatchString(stage) {
+			return stage, nil
+		}
+
+		return "", fmt.Errorf("unknown stage '%s' (must be one of %s, or match '%s')", stage, strings.Join(stages, ", "), c.StagePattern)
+	}
+
+	return "", fmt.Errorf("unknown stage '%s' (must be one of %s)", stage, strings.Join(stages, ", "))
+}
+
+// RegionFor returns the region configured for the named store, falling back to
+// the general region (which may be empty).
+func (c Config) RegionFor(store string) string {
+	if region := c.StoreRegions[store]; region != "" {
+		return region
+	}
+
+	return c.Region
 }
 
 func (c *Config) Unmarshal(data []byte) error {
 	return json.Unmarshal(data, c)
 }
 
+// UnmarshalFormat is like Unmarshal, but for the given format (see FormatOf).
+func (c *Config) UnmarshalFormat(data []byte, format string) error {
+	switch format {
+	case FormatYAML:
+		return yaml.Unmarshal(data, c)
+	case FormatTOML:
+		return toml.Unmarshal(data, c)
+	default:
+		return c.Unmarshal(data)
+	}
+}
+
 func Merge(configs ...Config) Config {
 	var out Config
 
@@ -31,13 +302,146 @@ func Merge(configs ...Config) Config {
 		if config.Stage != "" {
 			out.Stage = config.Stage
 		}
+		if config.LogFile != "" {
+			out.LogFile = config.LogFile
+		}
+		if config.Region != "" {
+			out.Region = config.Region
+		}
+		if config.DefaultRegion != "" {
+			out.DefaultRegion = config.DefaultRegion
+		}
+		for store, region := range config.StoreRegions {
+			if out.StoreRegions == nil {
+				out.StoreRegions = map[string]string{}
+			}
+			out.StoreRegions[store] = region
+		}
+		if config.EndpointURL != "" {
+			out.EndpointURL = config.EndpointURL
+		}
+		for store, url := range config.StoreEndpoints {
+			if out.StoreEndpoints == nil {
+				out.StoreEndpoints = map[string]string{}
+			}
+			out.StoreEndpoints[store] = url
+		}
+		if config.AWSProfile != "" {
+			out.AWSProfile = config.AWSProfile
+		}
+		if config.SSOSession != "" {
+			out.SSOSession = config.SSOSession
+		}
+		if config.Output != "" {
+			out.Output = config.Output
+		}
+		if config.KMSKey != "" {
+			out.KMSKey = config.KMSKey
+		}
+		if config.Account != "" {
+			out.Account = config.Account
+		}
+		if config.Preflight {
+			out.Preflight = true
+		}
+		if config.Proxy != "" {
+			out.Proxy = config.Proxy
+		}
+		if config.CABundle != "" {
+			out.CABundle = config.CABundle
+		}
+		if config.MaxRetries != 0 {
+			out.MaxRetries = config.MaxRetries
+		}
+		if config.RetryMode != "" {
+			out.RetryMode = config.RetryMode
+		}
+		if len(config.RoleChain) > 0 {
+			out.RoleChain = config.RoleChain
+		}
+		if config.RoleARN != "" {
+			out.RoleARN = config.RoleARN
+		}
+		if config.ExternalID != "" {
+			out.ExternalID = config.ExternalID
+		}
+		if config.SessionName != "" {
+			out.SessionName = config.SessionName
+		}
+		if config.Registry != "" {
+			out.Registry = config.Registry
+		}
+		if config.PrefixTemplate != "" {
+			out.PrefixTemplate = config.PrefixTemplate
+		}
+		for k, v := range config.Tags {
+			if out.Tags == nil {
+				out.Tags = map[string]string{}
+			}
+			out.Tags[k] = v
+		}
+		if len(config.Stages) > 0 {
+			out.Stages = config.Stages
+		}
+		if config.StagePattern != "" {
+			out.StagePattern = config.StagePattern
+		}
+		for alias, stage := range config.StageAliases {
+			if out.StageAliases == nil {
+				out.StageAliases = map[string]string{}
+			}
+			out.StageAliases[alias] = stage
+		}
+		if config.DefaultStore != "" {
+			out.DefaultStore = config.DefaultStore
+		}
+		for name, value := range config.Overrides {
+			if out.Overrides == nil {
+				out.Overrides = map[string]string{}
+			}
+			out.Overrides[name] = value
+		}
+		if len(config.Routes) > 0 {
+			out.Routes = config.Routes
+		}
+		if len(config.Transforms) > 0 {
+			out.Transforms = config.Transforms
+		}
+		if len(config.EventSinks) > 0 {
+			out.EventSinks = config.EventSinks
+		}
+		for name, profile := range config.Profiles {
+			if out.Profiles == nil {
+				out.Profiles = map[string]Config{}
+			}
+			out.Profiles[name] = profile
+		}
+		for stage, override := range config.StageOverrides {
+			if out.StageOverrides == nil {
+				out.StageOverrides = map[string]Config{}
+			}
+			out.StageOverrides[stage] = override
+		}
+		for name, service := range config.Services {
+			if out.Services == nil {
+				out.Services = map[string]Service{}
+			}
+			out.Services[name] = service
+		}
 	}
 
 	return out
 }
 
+// DefaultFiles returns the local config file (see LocalFile) and EC2 tags
+// file, if present.
 func DefaultFiles() []io.ReadCloser {
-	paths := []string{DefaultLocalPath, DefaultEC2Path}
+	paths := []string{}
+	if path := LocalFile(); path != "" {
+		paths = append(paths, path)
+	}
+
+	paths = append(paths, DefaultEC2Path)
 	files := []io.ReadCloser{}
 
 	for _, path := range paths {
@@ -50,44 +454,221 @@ func DefaultFiles() []io.ReadCloser {
 	return files
 }
 
-// Reads any file configs and merges with passed arg values. When both present,
-// the arg value is preferred. Only the first file that contains config data is
-// used.
-func Read(argConfig Config, files ...io.ReadCloser) (Config, error) {
+// UserFiles returns the user's global config file, if any, from the XDG config
+// directory (e.g. '~/.config/devx-config/config.yaml'). It can be YAML, TOML
+// or JSON.
+func UserFiles() []io.ReadCloser {
+	files := []io.ReadCloser{}
+	for _, path := range userPaths() {
+		file, err := os.Open(path)
+		if err == nil {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+func userPaths() []string {
+	// Note, os.UserConfigDir isn't XDG-based on macOS.
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	paths := []string{}
+	for _, name := range []string{"config.yaml", "config.yml", "config.toml", "config.json"} {
+		paths = append(paths, filepath.Join(dir, "devx-config", name))
+	}
+
+	return paths
+}
+
+// FilePaths returns the paths of the user and local config files that exist
+// (not including the EC2 tags file).
+func FilePaths() []string {
+	candidates := userPaths()
+	if path := LocalFile(); path != "" {
+		candidates = append(candidates, path)
+	}
+
+	paths := []string{}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// ReadLayers reads each layer of files (as per ReadFiles) and merges the
+// results, with later layers taking precedence. E.g. user config beneath
+// project config.
+func ReadLayers(layers ...[]io.ReadCloser) (Config, error) {
+	configs := []Config{}
+	for _, files := range layers {
+		c, err := ReadFiles(files...)
+		if err != nil {
+			return Config{}, err
+		}
+
+		configs = append(configs, c)
+	}
+
+	return Merge(configs...), nil
+}
+
+// Reads config from the first file that contains config data. Unlike Read, the
+// result isn't validated, so can be used for optional settings.
+func ReadFiles(files ...io.ReadCloser) (Config, error) {
 	fileConfig := Config{}
 
 	for _, f := range files {
 		defer f.Close()
 		data, err := io.ReadAll(f)
 		if err == nil {
-			err = fileConfig.Unmarshal(data)
+			err = fileConfig.UnmarshalFormat(data, FormatOf(f))
 			if err != nil {
 				return fileConfig, err
 			}
 
-			break
+			from := "."
+			if named, ok := f.(interface{ Name() string }); ok {
+				from = named.Name()
+			}
+
+			return resolveExtends(fileConfig, from, map[string]bool{})
 		}
 	}
 
+	return fileConfig, nil
+}
+
+// Merges the chain of files that conf extends beneath it, failing if the chain
+// loops. Paths are relative to the file that extends them.
+func resolveExtends(conf Config, from string, seen map[string]bool) (Config, error) {
+	if conf.Extends == "" {
+		return conf, nil
+	}
+
+	if abs, err := filepath.Abs(from); err == nil {
+		seen[abs] = true
+	}
+
+	path := conf.Extends
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if seen[abs] {
+		return Config{}, fmt.Errorf("config files extend each other in a loop (at '%s')", path)
+	}
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return Config{}, fmt.Errorf("unable to read extended config file: %w", err)
+	}
+
+	var parent Config
+	if err := parent.UnmarshalFormat(data, FormatOf(namedPath(abs))); err != nil {
+		return Config{}, fmt.Errorf("unable to parse extended config file '%s': %w", path, err)
+	}
+
+	parent, err = resolveExtends(parent, abs, seen)
+	if err != nil {
+		return Config{}, err
+	}
+
+	conf.Extends = ""
+	return Merge(parent, conf), nil
+}
+
+// Reads any file configs and merges with passed arg values. When both present,
+// the arg value is preferred. Only the first file that contains config data is
+// used. Anything still missing is inferred from the repo where possible (see
+// RepoDefaults).
+func Read(argConfig Config, files ...io.ReadCloser) (Config, error) {
+	fileConfig, err := ReadFiles(files...)
+	if err != nil {
+		return fileConfig, err
+	}
+
 	merged := Merge(fileConfig, argConfig)
+	if merged.Validate() != nil {
+		merged = Merge(RepoDefaults(), merged)
+	}
+
+	return merged, merged.Validate()
+}
+
+// LocalPath returns the path of the local config file in the working
+// directory, or DefaultLocalPath if there isn't one. FileEnv takes precedence.
+func LocalPath() string {
+	if path := os.Getenv(FileEnv); path != "" {
+		return path
+	}
+
+	for _, p := range LocalPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	return DefaultLocalPath
+}
+
+// ReadFile reads a single config file, without resolving Extends.
+func ReadFile(path string) (Config, error) {
+	var c Config
 
-	if merged.App == "" || merged.Stack == "" || merged.Stage == "" {
-		return merged, fmt.Errorf("mandatory flag missing or empty (got app='%s', stack='%s', stage='%s')", merged.App, merged.Stack, merged.Stage)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
 	}
 
-	return merged, nil
+	return c, c.UnmarshalFormat(data, FormatOf(namedPath(path)))
+}
+
+// Write sets the app, stack and stage in the local config file (see
+// LocalPath), creating it (as JSON) if it doesn't exist, and returns its
+// absolute path. Other settings, and comments in YAML and TOML files, are
+// preserved.
+func Write(config Config) (string, error) {
+	return writeFields([][2]string{{"App", config.App}, {"Stack", config.Stack}, {"Stage", config.Stage}})
 }
 
-func Write(config Config) error {
-	out, err := json.Marshal(config)
+// WriteKMSKey sets kmsKey in the local config file, in the same way as Write.
+func WriteKMSKey(key string) (string, error) {
+	return writeFields([][2]string{{"KMSKey", key}})
+}
+
+func writeFields(fields [][2]string) (string, error) {
+	path := LocalPath()
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return path, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	out, err := setFields(existing, FormatOf(namedPath(path)), fields)
 	if err != nil {
-		return fmt.Errorf("unable to marshal JSON: %w", err)
+		return path, fmt.Errorf("unable to update config file: %w", err)
 	}
 
-	err = os.WriteFile(DefaultLocalPath, out, 0644)
+	err = os.WriteFile(path, out, 0644)
 	if err != nil {
-		return fmt.Errorf("unable to write config file: %w", err)
+		return path, fmt.Errorf("unable to write config file: %w", err)
 	}
 
-	return nil
+	return filepath.Abs(path)
 }
@@ -1,7 +1,13 @@
 package config
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,7 +16,7 @@ import (
 func TestRead(t *testing.T) {
 	file := io.NopCloser(strings.NewReader(`{"Stack":"deploy","Stage":"PROD","App":"example"}`))
 
-	want := Config{"deploy", "CODE", "example"}
+	want := Config{Stack: "deploy", Stage: "CODE", App: "example"}
 	got, err := Read(Config{Stage: "CODE"}, file)
 	if err != nil {
 		t.Fatalf("unexpected read error: %v", err)
@@ -20,3 +26,364 @@ func TestRead(t *testing.T) {
 		t.Fatalf("got: %v; want %v", got, want)
 	}
 }
+
+func TestRegionFor(t *testing.T) {
+	conf := Merge(Config{Region: "eu-west-1"}, Config{StoreRegions: map[string]string{"ssm": "us-east-1"}})
+
+	if got := conf.RegionFor("ssm"); got != "us-east-1" {
+		t.Errorf("got: %s; want us-east-1", got)
+	}
+
+	if got := conf.RegionFor("secretsmanager"); got != "eu-west-1" {
+		t.Errorf("got: %s; want eu-west-1", got)
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	file := io.NopCloser(strings.NewReader(`{
+		"Stack": "deploy", "App": "example", "Stage": "CODE",
+		"Profiles": {"prod": {"Stage": "PROD", "AWSProfile": "deployTools"}}
+	}`))
+
+	conf, err := ReadFiles(file)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+
+	got, err := conf.WithProfile("prod")
+	if err != nil {
+		t.Fatalf("unexpected profile error: %v", err)
+	}
+
+	if got.Stage != "PROD" || got.AWSProfile != "deployTools" || got.App != "example" {
+		t.Fatalf("got: %+v; want PROD stage and deployTools profile for example app", got)
+	}
+
+	if _, err := conf.WithProfile("missing"); err == nil {
+		t.Fatalf("expected error for missing profile")
+	}
+}
+
+func TestReadLayers(t *testing.T) {
+	user := io.NopCloser(strings.NewReader(`{"AWSProfile":"developerPlayground","Region":"eu-west-1"}`))
+	project := io.NopCloser(strings.NewReader(`{"App":"example","Region":"us-east-1"}`))
+
+	got, err := ReadLayers([]io.ReadCloser{user}, []io.ReadCloser{project})
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+
+	want := Config{App: "example", AWSProfile: "developerPlayground", Region: "us-east-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v; want %+v", got, want)
+	}
+}
+
+func TestStoreFor(t *testing.T) {
+	conf := Config{Routes: []Route{{Pattern: "*_SECRET", Store: "secretsmanager"}}}
+
+	if got := conf.StoreFor("DB_SECRET"); got != "secretsmanager" {
+		t.Errorf("got: %s; want secretsmanager", got)
+	}
+
+	if got := conf.StoreFor("PORT"); got != "ssm" {
+		t.Errorf("got: %s; want ssm", got)
+	}
+
+	if got := conf.Stores(); !reflect.DeepEqual(got, []string{"ssm", "secretsmanager"}) {
+		t.Errorf("got: %v; want [ssm secretsmanager]", got)
+	}
+}
+
+func TestParseRiffRaff(t *testing.T) {
+	data := []byte(`
+stacks: [deploy]
+regions: [eu-west-1]
+deployments:
+  cloudformation:
+    type: cloud-formation
+    app: example
+  example:
+    type: autoscaling
+    dependencies: [cloudformation]
+`)
+
+	got, err := parseRiffRaff(data)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	want := Config{App: "example", Stack: "deploy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v; want %+v", got, want)
+	}
+}
+
+func TestNormaliseStage(t *testing.T) {
+	conf := Config{StagePattern: "^PREVIEW-[0-9]+$", StageAliases: map[string]string{"production": "PROD"}}
+
+	for stage, want := range map[string]string{"prod": "PROD", "Production": "PROD", "CODE": "CODE", "PREVIEW-123": "PREVIEW-123"} {
+		got, err := conf.NormaliseStage(stage)
+		if err != nil || got != want {
+			t.Errorf("%s: got: %s (err %v); want %s", stage, got, err, want)
+		}
+	}
+
+	if _, err := conf.NormaliseStage("PORD"); err == nil {
+		t.Errorf("expected error for unknown stage")
+	}
+
+	// Without configured stages, any stage is allowed.
+	for stage, want := range map[string]string{"prod": "PROD", "INFRA": "INFRA", "dev": "dev"} {
+		if got, err := (Config{}).NormaliseStage(stage); err != nil || got != want {
+			t.Errorf("unconfigured %s: got: %s (err %v); want %s", stage, got, err, want)
+		}
+	}
+	if _, err := (Config{Stages: []string{"CODE", "PROD"}}).NormaliseStage("TEST"); err == nil {
+		t.Errorf("expected error for a stage not configured")
+	}
+}
+
+func TestReadFilesExtends(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("shared/.devx-config.yaml", "stack: deploy\nregion: eu-west-1\nstage: CODE\n")
+	service := write("payments/.devx-config", `{"Extends": "../shared/.devx-config.yaml", "App": "payments", "Stage": "PROD"}`)
+
+	f, err := os.Open(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadFiles(f)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+
+	want := Config{App: "payments", Stack: "deploy", Stage: "PROD", Region: "eu-west-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v; want %+v", got, want)
+	}
+
+	write("a/.devx-config", `{"Extends": "../b/.devx-config"}`)
+	write("b/.devx-config", `{"Extends": "../a/.devx-config"}`)
+
+	f, err = os.Open(filepath.Join(dir, "a/.devx-config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadFiles(f); err == nil {
+		t.Fatalf("expected error for extends loop")
+	}
+}
+
+func TestWithService(t *testing.T) {
+	conf := Config{Stack: "shared", Services: map[string]Service{
+		"payments-api": {Path: "services/payments", App: "payments-api"},
+		"checkout":     {Path: "services/checkout", App: "checkout", Stack: "retail"},
+	}}
+
+	got, err := conf.WithService("", "/repo", "/repo/services/checkout/src")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.App != "checkout" || got.Stack != "retail" {
+		t.Errorf("got: %+v; want checkout app in retail stack", got)
+	}
+
+	got, err = conf.WithService("payments-api", "/repo", "/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.App != "payments-api" || got.Stack != "shared" {
+		t.Errorf("got: %+v; want payments-api app in shared stack", got)
+	}
+
+	if _, err := conf.WithService("missing", "/repo", "/repo"); err == nil {
+		t.Errorf("expected error for missing service")
+	}
+}
+
+func TestLint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".devx-config")
+	err := os.WriteFile(path, []byte(`{"App": "example", "Stack": "deploy", "Stage": "PORD", "Stages": ["CODE", "PROD"], "Regoin": "eu-west-1"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := Lint(path)
+	if err != nil {
+		t.Fatalf("unexpected lint error: %v", err)
+	}
+
+	if len(problems) != 2 || !strings.Contains(problems[0], "Regoin") || !strings.Contains(problems[1], "PORD") {
+		t.Fatalf("got: %q; want unknown field and stage problems", problems)
+	}
+}
+
+func TestWithStage(t *testing.T) {
+	conf := Config{Stage: "CODE", AWSProfile: "developerPlayground", StageOverrides: map[string]Config{
+		"PROD": {AWSProfile: "deployTools", Region: "us-east-1", Stage: "CODE"},
+	}}
+
+	got := conf.WithStage("prod")
+	if got.AWSProfile != "deployTools" || got.Region != "us-east-1" || got.Stage != "CODE" {
+		t.Errorf("got: %+v; want deployTools profile in us-east-1", got)
+	}
+
+	if got := conf.WithStage("CODE"); got.AWSProfile != "developerPlayground" {
+		t.Errorf("got: %+v; want developerPlayground profile", got)
+	}
+}
+
+func TestECSTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/abc":
+			fmt.Fprint(w, `{"Labels": {"App": "example", "Stack": "deploy", "Stage": "CODE"}}`)
+		case "/v4/abc/taskWithTags":
+			fmt.Fprint(w, `{"TaskTags": {"Stage": "PROD"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv(ECSMetadataEnv, server.URL+"/v4/abc")
+
+	got, err := ECSTags(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Config{App: "example", Stack: "deploy", Stage: "PROD"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v; want %+v", got, want)
+	}
+}
+
+func TestLambdaEnv(t *testing.T) {
+	t.Setenv(LambdaFunctionEnv, "example-CODE")
+	t.Setenv("APP", "example")
+	t.Setenv("STACK", "deploy")
+	t.Setenv("STAGE", "CODE")
+
+	got, err := LambdaEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Config{App: "example", Stack: "deploy", Stage: "CODE"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v; want %+v", got, want)
+	}
+}
+
+func TestLookupService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/payments-api" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprint(w, `{"app": "payments-api", "stack": "payments", "stage": "PROD", "region": "us-east-1", "logFile": "/ignored"}`)
+	}))
+	defer server.Close()
+
+	got, err := LookupService(context.Background(), server.URL+"/services", "payments-api", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Config{App: "payments-api", Stack: "payments", Stage: "PROD", Region: "us-east-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %+v; want %+v", got, want)
+	}
+
+	getParameter := func(ctx context.Context, name string) (string, error) {
+		if name != "/platform/services/payments-api" {
+			t.Errorf("got parameter name %s; want /platform/services/payments-api", name)
+		}
+		return `{"App": "payments-api", "Account": "123456789012"}`, nil
+	}
+
+	got, err = LookupService(context.Background(), "ssm:/platform/services/", "payments-api", getParameter)
+	if err != nil || got.Account != "123456789012" {
+		t.Fatalf("got: %+v (err %v); want account 123456789012", got, err)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("DEPLOY_STAGE", "CODE")
+	t.Setenv("TEAM_PROFILE", "deployTools")
+
+	conf := Config{
+		App: "example", Stage: "${DEPLOY_STAGE}", LogFile: "/tmp/${APP}-${STAGE}.log",
+		StageOverrides: map[string]Config{"PROD": {AWSProfile: "${TEAM_PROFILE}"}},
+	}
+
+	got := conf.Interpolate(Config{App: "other"})
+	if got.Stage != "CODE" || got.LogFile != "/tmp/other-CODE.log" || got.StageOverrides["PROD"].AWSProfile != "deployTools" {
+		t.Fatalf("got: %+v; want CODE stage, /tmp/other-CODE.log and deployTools for PROD", got)
+	}
+}
+
+func TestInterpolateRoleChain(t *testing.T) {
+	t.Setenv("HUB_ACCOUNT", "111111111111")
+
+	conf := Config{RoleChain: []string{"arn:aws:iam::${HUB_ACCOUNT}:role/config-hub"}}
+
+	got := conf.Interpolate(Config{})
+	if len(got.RoleChain) != 1 || got.RoleChain[0] != "arn:aws:iam::111111111111:role/config-hub" {
+		t.Fatalf("got: %v; want the hub account's role", got.RoleChain)
+	}
+	if conf.RoleChain[0] != "arn:aws:iam::${HUB_ACCOUNT}:role/config-hub" {
+		t.Errorf("original config was modified: %v", conf.RoleChain)
+	}
+}
+
+func TestLocalFile(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir()) // as os.Getwd resolves them
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := filepath.Join(root, "services", "api")
+	for _, dir := range []string{filepath.Join(root, ".git"), sub} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, ".devx-config.yaml"), []byte("app: example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := LocalFile(), filepath.Join(root, ".devx-config.yaml"); got != want {
+		t.Errorf("got: %s; want %s", got, want)
+	}
+
+	t.Setenv(FileEnv, "/elsewhere/.devx-config")
+	if got := LocalFile(); got != "/elsewhere/.devx-config" {
+		t.Errorf("got: %s; want /elsewhere/.devx-config", got)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+)
+
+// MetadataTimeout bounds lookups against the EC2 instance (and ECS task)
+// metadata services, as IMDS otherwise retries for a while when not running on
+// EC2.
+var MetadataTimeout = 2 * time.Second
+
+// EC2Tags reads the App, Stack and Stage tags of the current EC2 instance from
+// the instance metadata service (IMDSv2). This is a fallback for instances
+// without DefaultEC2Path, and requires tags in instance metadata to be enabled.
+func EC2Tags(ctx context.Context) (Config, error) {
+	ctx, cancel := context.WithTimeout(ctx, MetadataTimeout)
+	defer cancel()
+
+	client := imds.New(imds.Options{})
+	tag := func(name string) (string, error) {
+		out, err := client.GetMetadata(ctx, &imds.GetMetadataInput{Path: "tags/instance/" + name})
+		if err != nil {
+			return "", fmt.Errorf("unable to read instance tag '%s': %w", name, err)
+		}
+		defer out.Content.Close()
+
+		data, err := io.ReadAll(out.Content)
+		return strings.TrimSpace(string(data)), err
+	}
+
+	var conf Config
+	var err error
+
+	if conf.App, err = tag("App"); err != nil {
+		return Config{}, err
+	}
+	if conf.Stack, err = tag("Stack"); err != nil {
+		return Config{}, err
+	}
+	if conf.Stage, err = tag("Stage"); err != nil {
+		return Config{}, err
+	}
+
+	return conf, nil
+}
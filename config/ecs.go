@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ECSMetadataEnv is set by the ECS agent (on EC2 and Fargate) to the task
+// metadata endpoint for the container.
+const ECSMetadataEnv = "ECS_CONTAINER_METADATA_URI_V4"
+
+// ECSTags reads App, Stack and Stage from the ECS task metadata endpoint: the
+// task's tags where available (this needs 'ecs:ListTagsForResource'), falling
+// back to the container's Docker labels.
+func ECSTags(ctx context.Context) (Config, error) {
+	uri := os.Getenv(ECSMetadataEnv)
+	if uri == "" {
+		return Config{}, errors.New("not running in ECS (" + ECSMetadataEnv + " is not set)")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, MetadataTimeout)
+	defer cancel()
+
+	var container struct {
+		Labels map[string]string
+	}
+	if err := getJSON(ctx, uri, &container); err != nil {
+		return Config{}, fmt.Errorf("unable to read container metadata: %w", err)
+	}
+
+	var task struct {
+		TaskTags map[string]string
+	}
+	_ = getJSON(ctx, uri+"/taskWithTags", &task) // optional
+
+	return Merge(configFromTags(container.Labels), configFromTags(task.TaskTags)), nil
+}
+
+func configFromTags(tags map[string]string) Config {
+	return Config{App: tags["App"], Stack: tags["Stack"], Stage: tags["Stage"]}
+}
+
+func getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatOf returns the format of f, based on its file extension if it has a
+// name (as *os.File does). Anything else is assumed to be JSON.
+func FormatOf(f any) string {
+	named, ok := f.(interface{ Name() string })
+	if !ok {
+		return FormatJSON
+	}
+
+	switch filepath.Ext(named.Name()) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+type namedPath string
+
+func (p namedPath) Name() string {
+	return string(p)
+}
+
+// Sets top-level string fields (given as canonical JSON names, e.g. 'App') in
+// data, leaving everything else (including comments) as-is where the format
+// allows.
+func setFields(data []byte, format string, fields [][2]string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return setYAMLFields(data, fields)
+	case FormatTOML:
+		return setTOMLFields(data, fields), nil
+	default:
+		return setJSONFields(data, fields)
+	}
+}
+
+// Converts a canonical field name to its YAML and TOML key, e.g. 'App' to
+// 'app' and 'KMSKey' to 'kmsKey'.
+func camelCase(name string) string {
+	upper := 0
+	for upper < len(name) && name[upper] >= 'A' && name[upper] <= 'Z' {
+		upper++
+	}
+
+	// The last capital of a leading acronym starts the next word.
+	if upper > 1 && upper < len(name) {
+		upper--
+	}
+
+	return strings.ToLower(name[:upper]) + name[upper:]
+}
+
+func setJSONFields(data []byte, fields [][2]string) ([]byte, error) {
+	doc := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range fields {
+		// Keys are matched case-insensitively on read, so must be on write too.
+		for key := range doc {
+			if strings.EqualFold(key, field[0]) {
+				delete(doc, key)
+			}
+		}
+
+		value, _ := json.Marshal(field[1])
+		doc[field[0]] = value
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func setYAMLFields(data []byte, fields [][2]string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping at the top level")
+	}
+
+	for _, field := range fields {
+		key := camelCase(field[0])
+		found := false
+
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == key {
+				root.Content[i+1].SetString(field[1])
+				found = true
+			}
+		}
+
+		if !found {
+			value := &yaml.Node{}
+			value.SetString(field[1])
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var (
+	tomlTable   = regexp.MustCompile(`^\s*\[`)
+	tomlComment = regexp.MustCompile(`\s+#[^"']*$`)
+)
+
+// TOML libraries don't preserve comments, so top-level keys are updated
+// line-by-line instead. New keys are added before the first table.
+func setTOMLFields(data []byte, fields [][2]string) []byte {
+	lines := []string{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	topLevelEnd := len(lines)
+	for i, line := range lines {
+		if tomlTable.MatchString(line) {
+			topLevelEnd = i
+			break
+		}
+	}
+
+	for _, field := range fields {
+		key := camelCase(field[0])
+		assignment := fmt.Sprintf("%s = %s", key, strconv.Quote(field[1]))
+		keyPattern := regexp.MustCompile(`^\s*"?` + regexp.QuoteMeta(key) + `"?\s*=`)
+
+		found := false
+		for i := 0; i < topLevelEnd; i++ {
+			if keyPattern.MatchString(lines[i]) {
+				lines[i] = assignment + tomlComment.FindString(lines[i])
+				found = true
+			}
+		}
+
+		if !found {
+			insertAt := topLevelEnd
+			for insertAt > 0 && strings.TrimSpace(lines[insertAt-1]) == "" {
+				insertAt--
+			}
+
+			lines = append(lines[:insertAt], append([]string{assignment}, lines[insertAt:]...)...)
+			topLevelEnd++
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,66 @@
+package config
+
+import "testing"
+
+func TestSetYAMLFields(t *testing.T) {
+	in := `# Our service
+app: old # the app
+region: eu-west-1
+`
+	got, err := setFields([]byte(in), FormatYAML, [][2]string{{"App", "new"}, {"Stage", "CODE"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `# Our service
+app: new # the app
+region: eu-west-1
+stage: CODE
+`
+	if string(got) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSetTOMLFields(t *testing.T) {
+	in := `# Our service
+app = "old" # the app
+
+[profiles.prod]
+stage = "PROD"
+`
+	got, err := setFields([]byte(in), FormatTOML, [][2]string{{"App", "new"}, {"Stage", "CODE"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `# Our service
+app = "new" # the app
+stage = "CODE"
+
+[profiles.prod]
+stage = "PROD"
+`
+	if string(got) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	var conf Config
+	if err := conf.UnmarshalFormat(got, FormatTOML); err != nil {
+		t.Fatalf("unable to read back TOML: %v", err)
+	}
+
+	if conf.App != "new" || conf.Profiles["prod"].Stage != "PROD" {
+		t.Fatalf("got: %+v", conf)
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := map[string]string{"App": "app", "KMSKey": "kmsKey", "AWSProfile": "awsProfile", "URL": "url"}
+
+	for name, want := range tests {
+		if got := camelCase(name); got != want {
+			t.Errorf("%s: got %s; want %s", name, got, want)
+		}
+	}
+}
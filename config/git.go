@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// GitDefaults guesses config from the git repository in the working directory:
+// the app from the repository name (as per the 'origin' remote, or failing
+// that, the directory name), and the stage as 'CODE', whatever the branch, so
+// that accepting the defaults never targets PROD. Fields are left empty if
+// they can't be determined.
+func GitDefaults() Config {
+	var conf Config
+
+	if remote, err := git("remote", "get-url", "origin"); err == nil && remote != "" {
+		conf.App = strings.TrimSuffix(path.Base(strings.ReplaceAll(remote, ":", "/")), ".git")
+	} else if root, err := git("rev-parse", "--show-toplevel"); err == nil {
+		conf.App = path.Base(root)
+	}
+
+	if _, err := git("rev-parse", "--git-dir"); err == nil {
+		conf.Stage = "CODE"
+	}
+
+	return conf
+}
+
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
@@ -0,0 +1,70 @@
+package config
+
+import "os"
+
+// Interpolate returns the config with '${NAME}' references in its settings
+// replaced. The app, stack and stage can only refer to environment variables
+// (e.g. 'stage: ${DEPLOY_STAGE}'); other settings can also use '${APP}',
+// '${STACK}' and '${STAGE}', which refer to the service (as given by args, in
+// preference to the config itself). This lets one file serve several stages,
+// e.g. 'logFile: ~/logs/${STAGE}.log'.
+func (c Config) Interpolate(args Config) Config {
+	c.App = os.ExpandEnv(c.App)
+	c.Stack = os.ExpandEnv(c.Stack)
+	c.Stage = os.ExpandEnv(c.Stage)
+
+	service := Merge(Config{App: c.App, Stack: c.Stack, Stage: c.Stage}, args)
+	vars := func(name string) string {
+		switch name {
+		case "APP":
+			return service.App
+		case "STACK":
+			return service.Stack
+		case "STAGE":
+			return service.Stage
+		default:
+			return os.Getenv(name)
+		}
+	}
+
+	c = expandSettings(c, vars)
+
+	overrides := map[string]Config{}
+	for stage, override := range c.StageOverrides {
+		overrides[stage] = expandSettings(override, vars)
+	}
+	if len(overrides) > 0 {
+		c.StageOverrides = overrides
+	}
+
+	return c
+}
+
+func expandSettings(c Config, vars func(string) string) Config {
+	c.LogFile = os.Expand(c.LogFile, vars)
+	c.Region = os.Expand(c.Region, vars)
+	c.DefaultRegion = os.Expand(c.DefaultRegion, vars)
+	c.AWSProfile = os.Expand(c.AWSProfile, vars)
+	c.KMSKey = os.Expand(c.KMSKey, vars)
+	c.Account = os.Expand(c.Account, vars)
+	c.RoleARN = os.Expand(c.RoleARN, vars)
+	if len(c.RoleChain) > 0 {
+		chain := make([]string, len(c.RoleChain))
+		for i, role := range c.RoleChain {
+			chain[i] = os.Expand(role, vars)
+		}
+		c.RoleChain = chain
+	}
+	c.SessionName = os.Expand(c.SessionName, vars)
+	c.Registry = os.Expand(c.Registry, vars)
+
+	regions := map[string]string{}
+	for store, region := range c.StoreRegions {
+		regions[store] = os.Expand(region, vars)
+	}
+	if len(regions) > 0 {
+		c.StoreRegions = regions
+	}
+
+	return c
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// LambdaFunctionEnv is set by the Lambda runtime, for functions and extensions.
+const LambdaFunctionEnv = "AWS_LAMBDA_FUNCTION_NAME"
+
+// LambdaEnv reads App, Stack and Stage from the APP, STACK and STAGE
+// environment variables that @guardian/cdk's GuLambdaFunction sets on every
+// function. Unlike tags, these are available without any AWS calls.
+func LambdaEnv() (Config, error) {
+	if os.Getenv(LambdaFunctionEnv) == "" {
+		return Config{}, errors.New("not running in Lambda (" + LambdaFunctionEnv + " is not set)")
+	}
+
+	return Config{App: os.Getenv("APP"), Stack: os.Getenv("STACK"), Stage: os.Getenv("STAGE")}, nil
+}
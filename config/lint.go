@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Lint checks the config file at path for likely mistakes: unknown fields,
+// missing mandatory values, conflicting profiles, invalid stages and
+// machine-specific settings in a file that isn't gitignored. It returns a
+// description of each problem found, and only errors if the file can't be
+// read.
+func Lint(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := FormatOf(namedPath(path))
+	problems := []string{}
+
+	if err := decodeStrict(data, format); err != nil {
+		problems = append(problems, fmt.Sprintf("unknown or invalid fields: %v", err))
+	}
+
+	var conf Config
+	if err := conf.UnmarshalFormat(data, format); err != nil {
+		return append(problems, fmt.Sprintf("unable to parse: %v", err)), nil
+	}
+
+	resolved, err := resolveExtends(conf, path, map[string]bool{})
+	if err != nil {
+		return append(problems, err.Error()), nil
+	}
+
+	if withDefault, err := resolved.WithProfile(""); err == nil && len(resolved.Services) == 0 {
+		if err := withDefault.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	names := map[string]string{}
+	for _, name := range sortedKeys(conf.Profiles) {
+		if other, ok := names[strings.ToLower(name)]; ok {
+			problems = append(problems, fmt.Sprintf("profiles '%s' and '%s' differ only in case", other, name))
+		}
+		names[strings.ToLower(name)] = name
+
+		if len(conf.Profiles[name].Profiles) > 0 {
+			problems = append(problems, fmt.Sprintf("profile '%s' defines its own profiles, which are ignored", name))
+		}
+	}
+
+	stages := map[string]string{"top level": resolved.Stage}
+	for name, profile := range conf.Profiles {
+		stages[fmt.Sprintf("profile '%s'", name)] = profile.Stage
+	}
+
+	for _, where := range sortedKeys(stages) {
+		if stages[where] == "" {
+			continue
+		}
+
+		if _, err := resolved.NormaliseStage(stages[where]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+		}
+	}
+
+	if hasMachineSpecific(conf) && !gitIgnored(path) {
+		problems = append(problems, "contains machine-specific settings (awsProfile or logFile) but isn't gitignored")
+	}
+
+	return problems, nil
+}
+
+func decodeStrict(data []byte, format string) error {
+	var c Config
+
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	case FormatTOML:
+		md, err := toml.Decode(string(data), &c)
+		if err != nil {
+			return err
+		}
+
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := []string{}
+			for _, key := range undecoded {
+				keys = append(keys, key.String())
+			}
+			return fmt.Errorf("%s", strings.Join(keys, ", "))
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AWS profiles, log file paths, proxies and CA bundles vary between machines,
+// so shouldn't be committed.
+func hasMachineSpecific(c Config) bool {
+	if c.AWSProfile != "" || c.LogFile != "" || c.Proxy != "" || c.CABundle != "" {
+		return true
+	}
+
+	for _, profile := range c.Profiles {
+		if profile.AWSProfile != "" || profile.LogFile != "" || profile.Proxy != "" || profile.CABundle != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Files outside a git repository are treated as ignored.
+func gitIgnored(path string) bool {
+	err := exec.Command("git", "check-ignore", "-q", path).Run()
+
+	var exitErr *exec.ExitError
+	return !errors.As(err, &exitErr) || exitErr.ExitCode() != 1
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
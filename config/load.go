@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/guardian/devx-config/log"
+)
+
+// Load reads the user and project config files, and applies the named config
+// profile and workspace service (which, if not in a file, is looked up in the
+// registry with getParameter, given the config read so far). Settings are
+// then interpolated and stage overrides applied for the service, with args
+// (e.g. from flags) taking precedence for the app, stack and stage. On error, the config read so far
+// is returned too, for commands that report problems themselves.
+func Load(ctx context.Context, profile string, service string, args Config, getParameter func(ctx context.Context, conf Config, name string) (string, error)) (Config, error) {
+	conf, err := ReadLayers(UserFiles(), DefaultFiles())
+	if err == nil {
+		conf, err = conf.WithProfile(profile)
+	}
+	if err == nil {
+		wd, _ := os.Getwd()
+		conf, err = conf.WithService(service, LocalDir(), wd)
+	}
+	if errors.Is(err, ErrServiceNotFound) && conf.Registry != "" {
+		var entry Config
+		entry, err = LookupService(ctx, conf.Registry, service, func(ctx context.Context, name string) (string, error) {
+			return getParameter(ctx, conf, name)
+		})
+		conf = Merge(conf, entry)
+	}
+	if err != nil {
+		return conf, err
+	}
+
+	conf = conf.Interpolate(args)
+
+	// Invalid stages are reported once the service is read.
+	stage := args.Stage
+	if stage == "" {
+		stage = conf.Stage
+	}
+	if normalised, err := conf.NormaliseStage(stage); err == nil {
+		stage = normalised
+	}
+
+	return conf.WithStage(stage), nil
+}
+
+// Infer fills in any of the app, stack and stage missing from conf using
+// riff-raff.yaml or cdk.json if possible, or else the Lambda function's
+// environment, the ECS task's tags (or container labels) or, on EC2 instances
+// without a tags file, the instance's tags. Failed lookups are logged at debug
+// level; the result should still be validated.
+func Infer(ctx context.Context, logger log.Logger, conf Config) Config {
+	if conf.Validate() != nil {
+		conf = Merge(RepoDefaults(), conf)
+	}
+
+	_, inLambda := os.LookupEnv(LambdaFunctionEnv)
+	if conf.Validate() != nil && inLambda {
+		env, err := LambdaEnv()
+		if err == nil {
+			conf = Merge(env, conf)
+		} else {
+			logger.Debugf("unable to read Lambda environment: %v", err)
+		}
+	}
+
+	_, inECS := os.LookupEnv(ECSMetadataEnv)
+	if conf.Validate() != nil && inECS {
+		tags, err := ECSTags(ctx)
+		if err == nil {
+			conf = Merge(tags, conf)
+		} else {
+			logger.Debugf("unable to read ECS task metadata: %v", err)
+		}
+	}
+
+	if conf.Validate() != nil && !inLambda && !inECS {
+		if _, err := os.Stat(DefaultEC2Path); err != nil {
+			tags, err := EC2Tags(ctx)
+			if err == nil {
+				conf = Merge(tags, conf)
+			} else {
+				logger.Debugf("unable to read EC2 instance tags: %v", err)
+			}
+		}
+	}
+
+	return conf
+}
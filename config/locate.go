@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileEnv, if set, gives the path of the local config file, overriding the
+// search from the working directory.
+const FileEnv = "DEVX_CONFIG_FILE"
+
+// LocalFile returns the path of the nearest local config file, looking in the
+// working directory and then each parent in turn up to the project root (see
+// ProjectRoot), as git does for '.git'. This allows a single file at the root
+// of a (mono)repo. It returns "" if there isn't one.
+func LocalFile() string {
+	if path := os.Getenv(FileEnv); path != "" {
+		return path
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		for _, p := range LocalPaths {
+			if _, err := os.Stat(filepath.Join(dir, p)); err == nil {
+				return filepath.Join(dir, p)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir || isProjectRoot(dir) {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LocalDir returns the directory of the local config file (see LocalFile), or
+// "" if there isn't one.
+func LocalDir() string {
+	path := LocalFile()
+	if path == "" {
+		return ""
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+
+	return dir
+}
+
+// ProjectRoot returns the nearest directory, from the working directory up,
+// that contains '.git', or the working directory if there isn't one.
+func ProjectRoot() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for dir := wd; ; dir = filepath.Dir(dir) {
+		if isProjectRoot(dir) {
+			return dir, nil
+		}
+
+		if filepath.Dir(dir) == dir {
+			return wd, nil
+		}
+	}
+}
+
+func isProjectRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// Migrate moves the config file at path (e.g. one in a subdirectory, from
+// before config was looked for upwards) to the project root, where it applies
+// to the whole project, and returns its new path. It fails rather than
+// overwrite an existing file there.
+func Migrate(path string) (string, error) {
+	root, err := ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+
+	from, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	to := filepath.Join(root, filepath.Base(from))
+	if from == to {
+		return to, nil
+	}
+
+	for _, p := range LocalPaths {
+		if _, err := os.Stat(filepath.Join(root, p)); err == nil {
+			return "", fmt.Errorf("a config file already exists at '%s'", filepath.Join(root, p))
+		}
+	}
+
+	if err := os.Rename(from, to); err != nil {
+		return "", fmt.Errorf("unable to move config file: %w", err)
+	}
+
+	return to, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var RegistryTimeout = 10 * time.Second
+
+// LookupService resolves the named service from a registry: either an SSM path
+// ('ssm:/path', read with getParameter) under which each service has a
+// parameter, or an HTTP(S) URL to which the name is appended. Either way, the
+// entry is a JSON object with (any of) 'app', 'stack', 'stage', 'account' and
+// 'region' fields.
+func LookupService(ctx context.Context, registry string, name string, getParameter func(ctx context.Context, name string) (string, error)) (Config, error) {
+	var entry Config
+
+	switch {
+	case strings.HasPrefix(registry, "ssm:"):
+		value, err := getParameter(ctx, strings.TrimSuffix(strings.TrimPrefix(registry, "ssm:"), "/")+"/"+name)
+		if err != nil {
+			return entry, fmt.Errorf("unable to look up service '%s' in registry: %w", name, err)
+		}
+
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return entry, fmt.Errorf("invalid registry entry for service '%s': %w", name, err)
+		}
+	case strings.HasPrefix(registry, "http://"), strings.HasPrefix(registry, "https://"):
+		ctx, cancel := context.WithTimeout(ctx, RegistryTimeout)
+		defer cancel()
+
+		if err := getJSON(ctx, strings.TrimSuffix(registry, "/")+"/"+url.PathEscape(name), &entry); err != nil {
+			return entry, fmt.Errorf("unable to look up service '%s' in registry: %w", name, err)
+		}
+	default:
+		return entry, fmt.Errorf("unsupported registry '%s' (must start with 'ssm:', 'http://' or 'https://')", registry)
+	}
+
+	// Only identity and location come from the registry.
+	return Config{App: entry.App, Stack: entry.Stack, Stage: entry.Stage, Account: entry.Account, Region: entry.Region}, nil
+}
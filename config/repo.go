@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Riff-Raff deployment config, of which only the parts identifying a service
+// are needed. See https://riffraff.gutools.co.uk/docs/reference/riff-raff.yaml.md.
+type riffRaffConfig struct {
+	Stacks      []string `yaml:"stacks"`
+	Deployments map[string]struct {
+		App    string   `yaml:"app"` // defaults to the deployment name
+		Stacks []string `yaml:"stacks"`
+	} `yaml:"deployments"`
+}
+
+// CDK config, with app and stack optionally given as context values.
+type cdkConfig struct {
+	Context struct {
+		App   string `json:"app"`
+		Stack string `json:"stack"`
+	} `json:"context"`
+}
+
+// RepoDefaults infers the app and stack from a riff-raff.yaml or cdk.json in
+// the working directory or at the root of its git repository (for cdk.json,
+// also in a 'cdk' directory there). Riff-Raff config takes precedence. Stages
+// aren't described by either file, so are never set.
+func RepoDefaults() Config {
+	dirs := []string{"."}
+	if root, err := git("rev-parse", "--show-toplevel"); err == nil && root != "" {
+		dirs = append(dirs, root)
+	}
+
+	var riffRaff, cdk Config
+
+	for _, dir := range dirs {
+		if data, err := os.ReadFile(filepath.Join(dir, "riff-raff.yaml")); err == nil && riffRaff.App == "" && riffRaff.Stack == "" {
+			riffRaff, _ = parseRiffRaff(data)
+		}
+
+		for _, path := range []string{filepath.Join(dir, "cdk.json"), filepath.Join(dir, "cdk", "cdk.json")} {
+			if data, err := os.ReadFile(path); err == nil && cdk.App == "" && cdk.Stack == "" {
+				cdk, _ = parseCDK(data)
+			}
+		}
+	}
+
+	return Merge(cdk, riffRaff)
+}
+
+// Fields are only set if unambiguous, i.e. all deployments share the same app
+// (or stack).
+func parseRiffRaff(data []byte) (Config, error) {
+	var rr riffRaffConfig
+	if err := yaml.Unmarshal(data, &rr); err != nil {
+		return Config{}, err
+	}
+
+	apps := map[string]bool{}
+	stacks := map[string]bool{}
+
+	for _, s := range rr.Stacks {
+		stacks[s] = true
+	}
+
+	for name, deployment := range rr.Deployments {
+		if deployment.App != "" {
+			apps[deployment.App] = true
+		} else {
+			apps[name] = true
+		}
+
+		for _, s := range deployment.Stacks {
+			stacks[s] = true
+		}
+	}
+
+	return Config{App: only(apps), Stack: only(stacks)}, nil
+}
+
+func parseCDK(data []byte) (Config, error) {
+	var cdk cdkConfig
+	if err := json.Unmarshal(data, &cdk); err != nil {
+		return Config{}, err
+	}
+
+	return Config{App: cdk.Context.App, Stack: cdk.Context.Stack}, nil
+}
+
+// Returns the single key in set, or "" if there are zero or several.
+func only(set map[string]bool) string {
+	if len(set) != 1 {
+		return ""
+	}
+
+	for key := range set {
+		return key
+	}
+
+	return ""
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var ErrServiceNotFound = errors.New("service not found in workspace config")
+
+// Service is a service within a monorepo workspace, found at Path (relative to
+// the config file declaring it).
+type Service struct {
+	Path  string `yaml:"path" toml:"path"`
+	App   string `yaml:"app" toml:"app"`
+	Stack string `yaml:"stack,omitempty" toml:"stack,omitempty"`
+}
+
+// WithService returns the config with the named workspace service's app and
+// stack merged on top. If name is empty, the service is instead inferred from
+// dir: the one with the most specific path (relative to root) containing it.
+// If there isn't one, the config is returned as-is.
+func (c Config) WithService(name string, root string, dir string) (Config, error) {
+	if name != "" {
+		service, ok := c.Services[name]
+		if !ok {
+			return c, fmt.Errorf("%w: '%s'", ErrServiceNotFound, name)
+		}
+
+		return Merge(c, Config{App: service.App, Stack: service.Stack}), nil
+	}
+
+	var match *Service
+	matchLen := -1
+
+	for _, service := range c.Services {
+		service := service
+		path := filepath.Clean(filepath.Join(root, service.Path))
+
+		if (dir == path || strings.HasPrefix(dir, path+string(filepath.Separator))) && len(path) > matchLen {
+			match, matchLen = &service, len(path)
+		}
+	}
+
+	if match == nil {
+		return c, nil
+	}
+
+	return Merge(c, Config{App: match.App, Stack: match.Stack}), nil
+}
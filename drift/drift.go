@@ -0,0 +1,110 @@
+// Package drift compares a service's live parameters against a manifest: the
+// JSON output of 'list' (or 'get'), saved as a backup or committed to a repo.
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type Status string
+
+const (
+	Added   Status = "added"   // live, but not in the manifest
+	Removed Status = "removed" // in the manifest, but no longer live
+	Changed Status = "changed" // in both, with different values
+)
+
+// Change is a parameter that has drifted from the manifest. Values are left
+// out, as they may be secret.
+type Change struct {
+	Name   string
+	Store  string
+	Status Status
+}
+
+type manifestParameter struct {
+	Name  string  `json:"name"`
+	Value *string `json:"value"`
+	Store string  `json:"store"`
+}
+
+// ReadManifest reads a manifest, which is either a JSON array of parameters or
+// a single parameter.
+func ReadManifest(r io.Reader) ([]store.Parameter, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []manifestParameter
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var single manifestParameter
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("not a JSON array or object of parameters: %w", err)
+		}
+		entries = []manifestParameter{single}
+	}
+
+	params := []store.Parameter{}
+	for i, e := range entries {
+		if e.Name == "" || e.Value == nil {
+			return nil, fmt.Errorf("parameter %d: name and value are required", i+1)
+		}
+		params = append(params, store.Parameter{Name: e.Name, Value: *e.Value, Store: e.Store})
+	}
+
+	return params, nil
+}
+
+// Compare returns the parameters that differ between the manifest and live,
+// sorted by name. Parameters are matched on their full name and, if the
+// manifest records it, their store.
+func Compare(manifest, live []store.Parameter) []Change {
+	type key struct{ name, store string }
+
+	stores := map[string]bool{} // whether the manifest records stores
+	expected := map[key]store.Parameter{}
+	for _, p := range manifest {
+		expected[key{p.Name, p.Store}] = p
+		stores[p.Store] = true
+	}
+	withStore := !(len(stores) == 1 && stores[""])
+
+	seen := map[key]bool{}
+	changes := []Change{}
+	for _, p := range live {
+		k := key{p.Name, p.Store}
+		if !withStore {
+			k.store = ""
+		}
+		seen[k] = true
+
+		want, ok := expected[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Name: p.Name, Store: p.Store, Status: Added})
+		case want.Value != p.Value:
+			changes = append(changes, Change{Name: p.Name, Store: p.Store, Status: Changed})
+		}
+	}
+
+	for k, p := range expected {
+		if !seen[k] {
+			changes = append(changes, Change{Name: p.Name, Store: p.Store, Status: Removed})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Store < changes[j].Store
+	})
+
+	return changes
+}
@@ -0,0 +1,58 @@
+package drift
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestReadManifest(t *testing.T) {
+	got, err := ReadManifest(strings.NewReader(`[{"key":"A","name":"/PROD/deploy/example/A","value":"1","secret":false,"store":"ssm"}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []store.Parameter{{Name: "/PROD/deploy/example/A", Value: "1", Store: "ssm"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v; want %+v", got, want)
+	}
+
+	if _, err := ReadManifest(strings.NewReader(`[{"name":"/PROD/deploy/example/A"}]`)); err == nil {
+		t.Errorf("expected an error for a parameter without a value")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	manifest := []store.Parameter{
+		{Name: "/PROD/deploy/example/SAME", Value: "1", Store: "ssm"},
+		{Name: "/PROD/deploy/example/CHANGED", Value: "old", Store: "ssm"},
+		{Name: "/PROD/deploy/example/REMOVED", Value: "x", Store: "ssm"},
+	}
+	live := []store.Parameter{
+		{Name: "/PROD/deploy/example/SAME", Value: "1", Store: "ssm"},
+		{Name: "/PROD/deploy/example/CHANGED", Value: "new", Store: "ssm"},
+		{Name: "/PROD/deploy/example/ADDED", Value: "y", Store: "ssm"},
+		{Name: "/PROD/deploy/example/SAME", Value: "1", Store: "secretsmanager"},
+	}
+
+	want := []Change{
+		{Name: "/PROD/deploy/example/ADDED", Store: "ssm", Status: Added},
+		{Name: "/PROD/deploy/example/CHANGED", Store: "ssm", Status: Changed},
+		{Name: "/PROD/deploy/example/REMOVED", Store: "ssm", Status: Removed},
+		{Name: "/PROD/deploy/example/SAME", Store: "secretsmanager", Status: Added},
+	}
+	if got := Compare(manifest, live); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v; want %+v", got, want)
+	}
+}
+
+func TestCompareWithoutStores(t *testing.T) {
+	manifest := []store.Parameter{{Name: "/PROD/deploy/example/A", Value: "1"}}
+	live := []store.Parameter{{Name: "/PROD/deploy/example/A", Value: "1", Store: "ssm"}}
+
+	if got := Compare(manifest, live); len(got) != 0 {
+		t.Errorf("got %+v; want no changes when the manifest doesn't record stores", got)
+	}
+}
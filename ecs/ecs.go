@@ -0,0 +1,163 @@
+// Package ecs generates the environment and secrets of ECS container
+// definitions from a service's parameters, so tasks get their config from ECS
+// rather than reading it themselves.
+package ecs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// KeyValuePair is an entry of a container definition's environment.
+type KeyValuePair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Secret is an entry of a container definition's secrets, which ECS reads
+// (with the task execution role) when the task starts.
+type Secret struct {
+	Name      string `json:"name"`
+	ValueFrom string `json:"valueFrom"`
+}
+
+// Definitions are the environment and secrets for a container, as in a task
+// definition, each sorted by name.
+type Definitions struct {
+	Environment []KeyValuePair `json:"environment"`
+	Secrets     []Secret       `json:"secrets"`
+}
+
+// Generate returns an environment variable for each plain parameter, and a
+// secret (referenced by ARN) for each SecureString or Secrets Manager secret,
+// named by the parameter's Key (e.g. 'db_password').
+func Generate(params []store.Parameter) (Definitions, error) {
+	defs := Definitions{Environment: []KeyValuePair{}, Secrets: []Secret{}}
+	seen := map[string]string{} // keys to names, to catch clashes
+	for _, p := range params {
+		if !p.DeletedDate.IsZero() {
+			continue
+		}
+
+		key := p.Key()
+		if other, ok := seen[key]; ok {
+			return Definitions{}, fmt.Errorf("'%s' and '%s' both have the key '%s'", other, p.RelativeName(), key)
+		}
+		seen[key] = p.RelativeName()
+
+		if !p.IsSecret {
+			defs.Environment = append(defs.Environment, KeyValuePair{Name: key, Value: p.Value})
+			continue
+		}
+
+		if p.ARN == "" {
+			return Definitions{}, fmt.Errorf("'%s' has no ARN (the %s store doesn't give one), so can't be referenced by ECS", p.RelativeName(), p.Store)
+		}
+		defs.Secrets = append(defs.Secrets, Secret{Name: key, ValueFrom: p.ARN})
+	}
+
+	sort.Slice(defs.Environment, func(i, j int) bool { return defs.Environment[i].Name < defs.Environment[j].Name })
+	sort.Slice(defs.Secrets, func(i, j int) bool { return defs.Secrets[i].Name < defs.Secrets[j].Name })
+
+	return defs, nil
+}
+
+// Patch merges defs into the environment and secrets of the named container
+// in a task definition (as JSON, e.g. from 'aws ecs describe-task-definition'
+// or a file registered with 'aws ecs register-task-definition'), replacing any
+// entries of the same name and keeping the rest. The container may be left
+// empty if there is only one. The task definition is otherwise unchanged,
+// although its keys are sorted.
+func Patch(taskDef []byte, defs Definitions, container string) ([]byte, error) {
+	var def map[string]any
+	if err := json.Unmarshal(taskDef, &def); err != nil {
+		return nil, fmt.Errorf("invalid task definition: %w", err)
+	}
+
+	// The output of describe-task-definition wraps the definition.
+	root := def
+	if inner, ok := def["taskDefinition"].(map[string]any); ok {
+		def = inner
+	}
+
+	containers, _ := def["containerDefinitions"].([]any)
+	if len(containers) == 0 {
+		return nil, errors.New("invalid task definition: no containerDefinitions")
+	}
+
+	var target map[string]any
+	var names []string
+	for _, c := range containers {
+		c, ok := c.(map[string]any)
+		if !ok {
+			return nil, errors.New("invalid task definition: invalid container definition")
+		}
+
+		name, _ := c["name"].(string)
+		names = append(names, name)
+		if name == container || (container == "" && len(containers) == 1) {
+			target = c
+		}
+	}
+
+	if target == nil {
+		if container == "" {
+			return nil, fmt.Errorf("the task definition has several containers, so one must be chosen (from '%s')", strings.Join(names, "', '"))
+		}
+		return nil, fmt.Errorf("the task definition has no container '%s' (only '%s')", container, strings.Join(names, "', '"))
+	}
+
+	env := map[string]any{}
+	for _, e := range defs.Environment {
+		env[e.Name] = map[string]any{"name": e.Name, "value": e.Value}
+	}
+	secrets := map[string]any{}
+	for _, s := range defs.Secrets {
+		secrets[s.Name] = map[string]any{"name": s.Name, "valueFrom": s.ValueFrom}
+	}
+
+	target["environment"] = merge(target["environment"], env)
+	target["secrets"] = merge(target["secrets"], secrets)
+
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+// Replaces the entries of existing (a JSON array of objects with names) that
+// are in entries, keyed by name, and adds the rest, sorted by name.
+func merge(existing any, entries map[string]any) []any {
+	var out []any
+	list, _ := existing.([]any)
+	for _, e := range list {
+		if m, ok := e.(map[string]any); ok {
+			if name, _ := m["name"].(string); entries[name] != nil {
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		out = append(out, entries[name])
+	}
+
+	if out == nil {
+		return []any{}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package ecs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestGenerate(t *testing.T) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/api/port", Value: "8080"},
+		{Service: service, Name: "/PROD/deploy/api/db/password", IsSecret: true, ARN: "arn:aws:ssm:eu-west-1:123456789012:parameter/PROD/deploy/api/db/password"},
+		{Service: service, Name: "/PROD/deploy/api/api-key", IsSecret: true, ARN: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:/PROD/deploy/api/api-key-AbCdEf"},
+	}
+
+	got, err := Generate(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Definitions{
+		Environment: []KeyValuePair{{Name: "port", Value: "8080"}},
+		Secrets: []Secret{
+			{Name: "api-key", ValueFrom: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:/PROD/deploy/api/api-key-AbCdEf"},
+			{Name: "db_password", ValueFrom: "arn:aws:ssm:eu-west-1:123456789012:parameter/PROD/deploy/api/db/password"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v; want %+v", got, want)
+	}
+
+	noARN := []store.Parameter{{Service: service, Name: "/PROD/deploy/api/token", IsSecret: true, Store: "vault"}}
+	if _, err := Generate(noARN); err == nil {
+		t.Errorf("no ARN: expected an error")
+	}
+}
+
+func TestPatch(t *testing.T) {
+	taskDef := `{
+		"family": "api",
+		"containerDefinitions": [
+			{"name": "app", "image": "api:1", "environment": [{"name": "port", "value": "80"}, {"name": "LOG_LEVEL", "value": "info"}]},
+			{"name": "sidecar", "image": "proxy:1"}
+		]
+	}`
+	defs := Definitions{
+		Environment: []KeyValuePair{{Name: "port", Value: "8080"}},
+		Secrets:     []Secret{{Name: "db_password", ValueFrom: "arn:aws:ssm:eu-west-1:123456789012:parameter/PROD/deploy/api/db/password"}},
+	}
+
+	out, err := Patch([]byte(taskDef), defs, "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got struct {
+		Family               string
+		ContainerDefinitions []struct {
+			Name        string
+			Image       string
+			Environment []KeyValuePair
+			Secrets     []Secret
+		}
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output: %v\n%s", err, out)
+	}
+
+	app, sidecar := got.ContainerDefinitions[0], got.ContainerDefinitions[1]
+	wantEnv := []KeyValuePair{{Name: "LOG_LEVEL", Value: "info"}, {Name: "port", Value: "8080"}}
+	if got.Family != "api" || app.Image != "api:1" || !reflect.DeepEqual(app.Environment, wantEnv) || !reflect.DeepEqual(app.Secrets, defs.Secrets) {
+		t.Errorf("got %+v; want app's port replaced, LOG_LEVEL kept and the secret added", app)
+	}
+	if sidecar.Environment != nil || sidecar.Secrets != nil {
+		t.Errorf("got %+v; want the sidecar unchanged", sidecar)
+	}
+
+	if _, err := Patch([]byte(taskDef), defs, ""); err == nil {
+		t.Errorf("no container: expected an error, as there are several")
+	}
+	if _, err := Patch([]byte(taskDef), defs, "missing"); err == nil {
+		t.Errorf("missing container: expected an error")
+	}
+
+	described := `{"taskDefinition": {"containerDefinitions": [{"name": "app"}]}}`
+	if out, err := Patch([]byte(described), defs, ""); err != nil || !json.Valid(out) {
+		t.Errorf("describe-task-definition output: got %s, %v; want it patched", out, err)
+	}
+}
@@ -0,0 +1,154 @@
+// Package events publishes a ChangeEvent for every change made to parameters
+// (by set, delete, label, unlabel and promote-replica), to sinks given by
+// name and target, e.g. 'webhook:https://example.com/hook'. The built-in sinks
+// are:
+//
+//	stdout                 JSON, one event per line
+//	eventbridge[:<bus>]    an EventBridge bus (the default bus if not given)
+//	sns:<topic ARN>        an SNS topic, with the event's JSON as the message
+//	webhook:<URL>          an HTTP POST of the event's JSON
+//
+// Others can be added with Register.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/guardian/devx-config/internal/registry"
+	"github.com/guardian/devx-config/store"
+)
+
+// Source and DetailType of EventBridge events.
+const (
+	Source     = "devx-config"
+	DetailType = "Parameter Changed"
+)
+
+type Action string
+
+const (
+	Set            Action = "set"
+	Delete         Action = "delete"
+	Label          Action = "label"
+	Unlabel        Action = "unlabel"
+	PromoteReplica Action = "promote-replica"
+)
+
+// ChangeEvent describes a change to a parameter. Values are never included.
+type ChangeEvent struct {
+	Time    time.Time `json:"time"`
+	Action  Action    `json:"action"`
+	App     string    `json:"app"`
+	Stack   string    `json:"stack"`
+	Stage   string    `json:"stage"`
+	Name    string    `json:"name"` // relative to the service's prefix
+	Prefix  string    `json:"prefix"`
+	Store   string    `json:"store"`
+	Region  string    `json:"region,omitempty"`
+	Labels  []string  `json:"labels,omitempty"`  // for label and unlabel
+	Version int64     `json:"version,omitempty"` // for label, if given
+	Actor   string    `json:"actor,omitempty"`   // ARN of the caller, if known
+}
+
+// NewChangeEvent returns an event for the named parameter of the service, at
+// the current time.
+func NewChangeEvent(action Action, service store.Service, storeName string, name string) ChangeEvent {
+	return ChangeEvent{
+		Time:   time.Now().UTC(),
+		Action: action,
+		App:    service.App,
+		Stack:  service.Stack,
+		Stage:  service.Stage,
+		Name:   name,
+		Prefix: service.Prefix(),
+		Store:  storeName,
+	}
+}
+
+// Publisher publishes change events.
+type Publisher interface {
+	Publish(ctx context.Context, e ChangeEvent) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, e ChangeEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e ChangeEvent) error {
+	return f(ctx, e)
+}
+
+// Multi publishes to each of its publishers in turn, returning all their
+// errors.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, e ChangeEvent) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Env is what a Factory is given to create a sink.
+type Env struct {
+	Stdout io.Writer
+
+	// Loads AWS config, for sinks that call AWS (e.g. 'sns').
+	AWS func(ctx context.Context) (aws.Config, error)
+}
+
+// Factory creates a sink, given the target after its name (e.g. the URL in
+// 'webhook:https://example.com/hook'), if any.
+type Factory func(target string, env Env) (Publisher, error)
+
+var factories registry.Registry[Factory]
+
+func init() {
+	Register("stdout", newStdout)
+	Register("eventbridge", newEventBridge)
+	Register("sns", newSNS)
+	Register("webhook", newWebhook)
+}
+
+// Register makes a sink available by name, replacing any already registered
+// with that name. It is typically called from an init function.
+func Register(name string, factory Factory) {
+	factories.Register(name, factory)
+}
+
+// Names returns the names of the registered sinks, sorted.
+func Names() []string {
+	return factories.Names()
+}
+
+// New creates a publisher for the sinks, e.g. 'stdout' or
+// 'sns:arn:aws:sns:eu-west-1:123456789012:config-changes'.
+func New(sinks []string, env Env) (Multi, error) {
+	var m Multi
+	for _, spec := range sinks {
+		name, target, _ := strings.Cut(spec, ":")
+
+		factory, ok := factories.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown event sink '%s' (must be one of '%s')", name, strings.Join(Names(), "', '"))
+		}
+
+		p, err := factory(target, env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event sink '%s': %w", spec, err)
+		}
+		m = append(m, p)
+	}
+
+	return m, nil
+}
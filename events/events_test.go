@@ -0,0 +1,67 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestNew(t *testing.T) {
+	var received []ChangeEvent
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e ChangeEvent
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		received = append(received, e)
+	}))
+	defer hook.Close()
+
+	var stdout bytes.Buffer
+	p, err := New([]string{"stdout", "webhook:" + hook.URL}, Env{Stdout: &stdout})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	e := NewChangeEvent(Set, service, "ssm", "db/password")
+	if err := p.Publish(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(stdout.String(), `"action":"set"`) || !strings.Contains(stdout.String(), `"prefix":"/PROD/deploy/api"`) {
+		t.Errorf("stdout: got %s", stdout.String())
+	}
+	if len(received) != 1 || received[0].Name != "db/password" || received[0].Store != "ssm" {
+		t.Errorf("webhook: got %+v; want the event", received)
+	}
+
+	for _, sinks := range [][]string{
+		{"carrier-pigeon"},
+		{"webhook:not a url"},
+		{"sns"},         // no topic
+		{"eventbridge"}, // no AWS
+	} {
+		if _, err := New(sinks, Env{}); err == nil {
+			t.Errorf("%v: expected an error", sinks)
+		}
+	}
+}
+
+func TestMulti(t *testing.T) {
+	failed := errors.New("failed")
+	calls := 0
+	m := Multi{
+		PublisherFunc(func(ctx context.Context, e ChangeEvent) error { calls++; return failed }),
+		PublisherFunc(func(ctx context.Context, e ChangeEvent) error { calls++; return nil }),
+	}
+
+	if err := m.Publish(context.Background(), ChangeEvent{}); !errors.Is(err, failed) || calls != 2 {
+		t.Errorf("got %v after %d calls; want the error, after publishing to both", err, calls)
+	}
+}
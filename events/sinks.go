@@ -0,0 +1,114 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/guardian/devx-config/awsclient"
+)
+
+func newStdout(target string, env Env) (Publisher, error) {
+	if target != "" {
+		return nil, errors.New("takes no target")
+	}
+	if env.Stdout == nil {
+		return nil, errors.New("stdout isn't available")
+	}
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(env.Stdout)
+	return PublisherFunc(func(ctx context.Context, e ChangeEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return enc.Encode(e)
+	}), nil
+}
+
+func newEventBridge(bus string, env Env) (Publisher, error) {
+	if env.AWS == nil {
+		return nil, errors.New("AWS isn't available")
+	}
+
+	return PublisherFunc(func(ctx context.Context, e ChangeEvent) error {
+		detail, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := env.AWS(ctx)
+		if err != nil {
+			return err
+		}
+
+		return awsclient.PutEvent(ctx, cfg, bus, Source, DetailType, detail)
+	}), nil
+}
+
+func newSNS(topicARN string, env Env) (Publisher, error) {
+	if topicARN == "" {
+		return nil, errors.New("a topic ARN is required")
+	}
+	if env.AWS == nil {
+		return nil, errors.New("AWS isn't available")
+	}
+
+	return PublisherFunc(func(ctx context.Context, e ChangeEvent) error {
+		message, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := env.AWS(ctx)
+		if err != nil {
+			return err
+		}
+
+		subject := fmt.Sprintf("devx-config: %s %s/%s", e.Action, e.Prefix, e.Name)
+		if len(subject) > 100 { // SNS's limit
+			subject = subject[:100]
+		}
+
+		return awsclient.PublishSNS(ctx, cfg, topicARN, subject, string(message))
+	}), nil
+}
+
+func newWebhook(target string, env Env) (Publisher, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, errors.New("an http(s) URL is required")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	return PublisherFunc(func(ctx context.Context, e ChangeEvent) error {
+		body, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("unable to call webhook: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("unable to call webhook: %s", resp.Status)
+		}
+
+		return nil
+	}), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/store"
+)
+
+// Exit codes are part of the CLI contract, so that scripts can distinguish
+// between failure modes. Don't change existing values.
+const (
+	OK            = 0
+	InternalError = 1 // anything not covered below
+	InvalidArgs   = 2 // bad flags, missing config, or input required but not available
+	NotFound      = 3 // the parameter (or version) does not exist
+	AccessDenied  = 4 // credentials are missing, expired, or lack permission
+	Throttled     = 5 // AWS rate limits were exceeded
+	Timeout       = 6 // the request timed out
+)
+
+// Returns the exit code for err, or fallback if it isn't one we recognise.
+func exitCodeFor(err error, fallback int) int {
+	if err == nil {
+		return OK
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Timeout
+	}
+
+	if awsclient.IsSSOExpired(err) {
+		return AccessDenied
+	}
+
+	err = store.WrapError(err)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return NotFound
+	case errors.Is(err, store.ErrAccessDenied):
+		return AccessDenied
+	case errors.Is(err, store.ErrThrottled):
+		return Throttled
+	default:
+		return fallback
+	}
+}
+
+var exitCodeNames = map[int]string{
+	InternalError: "internal_error",
+	InvalidArgs:   "invalid_args",
+	NotFound:      "not_found",
+	AccessDenied:  "access_denied",
+	Throttled:     "throttled",
+	Timeout:       "timeout",
+}
+
+// The structured form of an error, for '--output=json'.
+type jsonError struct {
+	Code         int    `json:"code"`
+	Name         string `json:"name"`
+	Message      string `json:"message"`
+	AWSErrorCode string `json:"awsErrorCode,omitempty"`
+	RequestID    string `json:"requestId,omitempty"`
+}
+
+func writeJSONError(w io.Writer, err error, msg string, exitCode int) error {
+	out := jsonError{Code: exitCode, Name: exitCodeNames[exitCode], Message: msg + "; " + err.Error()}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		out.AWSErrorCode = apiErr.ErrorCode()
+	}
+
+	var reqErr interface{ ServiceRequestID() string }
+	if errors.As(err, &reqErr) {
+		out.RequestID = reqErr.ServiceRequestID()
+	}
+
+	return json.NewEncoder(w).Encode(map[string]jsonError{"error": out})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, OK},
+		{errors.New("boom"), InternalError},
+		{fmt.Errorf("wrapped: %w", &smithy.GenericAPIError{Code: "ParameterNotFound"}), NotFound},
+		{&smithy.GenericAPIError{Code: "AccessDeniedException"}, AccessDenied},
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, Throttled},
+		{context.DeadlineExceeded, Timeout},
+		{fmt.Errorf("unable to get AWS credentials: %w", &ssocreds.InvalidTokenError{}), AccessDenied},
+	}
+
+	for _, tc := range tests {
+		if got := exitCodeFor(tc.err, InternalError); got != tc.want {
+			t.Errorf("%v: got %d; want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeJSONError(&buf, &smithy.GenericAPIError{Code: "ParameterNotFound", Message: "gone"}, "unable to get foo", NotFound)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"error":{"code":3,"name":"not_found","message":"unable to get foo; api error ParameterNotFound: gone","awsErrorCode":"ParameterNotFound"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+// Package extension runs devx-config as an AWS Lambda extension, in place of
+// the AWS Parameters and Secrets extension: the function's parameters are
+// read at cold start (using the usual prefix convention, with the service
+// taken from the function's APP, STACK and STAGE environment variables),
+// cached for a TTL (see store.Cached), and served over a local HTTP endpoint.
+//
+//	GET http://localhost:2773/v1/params          every parameter of the service
+//	GET http://localhost:2773/v1/params/{name}   one, e.g. 'db/password'
+//
+// As with the AWS extension, requests must send the function's session token
+// (AWS_SESSION_TOKEN) in the X-Aws-Parameters-Secrets-Token header.
+// Responses are JSON, as with '--output=json'.
+package extension
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/guardian/devx-config/output"
+	"github.com/guardian/devx-config/store"
+)
+
+const (
+	RuntimeAPIEnv = "AWS_LAMBDA_RUNTIME_API" // host:port of the Extensions API
+	DefaultPort   = 2773                     // as the AWS Parameters and Secrets extension
+	TokenHeader   = "X-Aws-Parameters-Secrets-Token"
+)
+
+// Event is an event from the Extensions API.
+type Event struct {
+	EventType      string `json:"eventType"` // 'INVOKE' or 'SHUTDOWN'
+	ShutdownReason string `json:"shutdownReason,omitempty"`
+}
+
+// API is a client for the Lambda Extensions API.
+type API struct {
+	base   string
+	client *http.Client
+}
+
+func NewAPI(host string) API {
+	return API{base: "http://" + host + "/2020-01-01/extension", client: &http.Client{}}
+}
+
+// Register registers the extension (name must be its executable's file name)
+// for invoke and shutdown events, returning its ID for Next.
+func (a API) Register(ctx context.Context, name string) (string, error) {
+	body := []byte(`{"events": ["INVOKE", "SHUTDOWN"]}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.base+"/register", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Lambda-Extension-Name", name)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to register extension: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unable to register extension: %s: %s", resp.Status, data)
+	}
+
+	id := resp.Header.Get("Lambda-Extension-Identifier")
+	if id == "" {
+		return "", errors.New("unable to register extension: no identifier returned")
+	}
+
+	return id, nil
+}
+
+// Next blocks until the next event.
+func (a API) Next(ctx context.Context, id string) (Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.base+"/event/next", nil)
+	if err != nil {
+		return Event{}, err
+	}
+	req.Header.Set("Lambda-Extension-Identifier", id)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return Event{}, fmt.Errorf("unable to get next event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Event{}, fmt.Errorf("unable to get next event: %s", resp.Status)
+	}
+
+	var event Event
+	err = json.NewDecoder(resp.Body).Decode(&event)
+	return event, err
+}
+
+// Lists every parameter of the service, e.g. devxconfig.Client.List with a
+// CacheTTL, so that reads are served from store.Cached rather than AWS.
+type ListFunc func(ctx context.Context) ([]store.Parameter, error)
+
+// Returns the named parameter (relative to the service), if it exists.
+func get(ctx context.Context, list ListFunc, name string) (store.Parameter, bool, error) {
+	params, err := list(ctx)
+	if err != nil {
+		return store.Parameter{}, false, err
+	}
+
+	for _, p := range params {
+		if p.RelativeName() == name {
+			return p, true, nil
+		}
+	}
+
+	return store.Parameter{}, false, nil
+}
+
+// Handler serves the parameters to requests with the token (see
+// TokenHeader).
+func Handler(list ListFunc, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(TokenHeader)), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid "+TokenHeader)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET is supported")
+			return
+		}
+
+		opts := output.Options{Format: output.JSON}
+		switch {
+		case r.URL.Path == "/v1/params":
+			params, err := list(r.Context())
+			if err != nil {
+				writeError(w, http.StatusBadGateway, "internal_error", err.Error())
+				return
+			}
+			if params == nil {
+				params = []store.Parameter{}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = output.Write(w, opts, params)
+		case strings.HasPrefix(r.URL.Path, "/v1/params/"):
+			name := strings.TrimPrefix(r.URL.Path, "/v1/params/")
+			p, ok, err := get(r.Context(), list, name)
+			if err != nil {
+				writeError(w, http.StatusBadGateway, "internal_error", err.Error())
+				return
+			}
+			if !ok {
+				writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("parameter '%s' not found", name))
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = output.WriteOne(w, opts, p)
+		default:
+			writeError(w, http.StatusNotFound, "not_found", "expected /v1/params or /v1/params/{name}")
+		}
+	})
+}
+
+// As the server package's errors.
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]map[string]string{"error": {"code": code, "message": message}})
+}
@@ -0,0 +1,106 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestAPI(t *testing.T) {
+	var events []string
+	runtime := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2020-01-01/extension/register":
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get("Lambda-Extension-Name") != "devx-config-extension" || !strings.Contains(string(body), "SHUTDOWN") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Lambda-Extension-Identifier", "ext-1")
+		case "/2020-01-01/extension/event/next":
+			events = append(events, r.Header.Get("Lambda-Extension-Identifier"))
+			_, _ = w.Write([]byte(`{"eventType": "SHUTDOWN", "shutdownReason": "spindown"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer runtime.Close()
+
+	api := NewAPI(strings.TrimPrefix(runtime.URL, "http://"))
+	ctx := context.Background()
+
+	id, err := api.Register(ctx, "devx-config-extension")
+	if err != nil || id != "ext-1" {
+		t.Fatalf("got %s, %v; want ext-1", id, err)
+	}
+
+	event, err := api.Next(ctx, id)
+	if err != nil || event.EventType != "SHUTDOWN" || event.ShutdownReason != "spindown" {
+		t.Errorf("got %+v, %v; want a SHUTDOWN event", event, err)
+	}
+	if len(events) != 1 || events[0] != "ext-1" {
+		t.Errorf("got identifiers %v; want [ext-1]", events)
+	}
+
+	if _, err := api.Register(ctx, "other"); err == nil {
+		t.Errorf("rejected registration: expected an error")
+	}
+}
+
+func testList(ctx context.Context) ([]store.Parameter, error) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	return []store.Parameter{{Service: service, Name: "/PROD/deploy/api/db/password", Value: "hunter2", IsSecret: true}}, nil
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler(testList, "session-token"))
+	defer server.Close()
+
+	get := func(path string, token string) (int, []byte) {
+		t.Helper()
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set(TokenHeader, token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, data
+	}
+
+	tests := []struct {
+		path, token string
+		want        int
+	}{
+		{"/v1/params", "", http.StatusUnauthorized},
+		{"/v1/params", "wrong", http.StatusUnauthorized},
+		{"/v1/params", "session-token", http.StatusOK},
+		{"/v1/params/db/password", "session-token", http.StatusOK},
+		{"/v1/params/missing", "session-token", http.StatusNotFound},
+		{"/secretsmanager/get", "session-token", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		if got, body := get(tc.path, tc.token); got != tc.want {
+			t.Errorf("%s: got %d (%s); want %d", tc.path, got, body, tc.want)
+		}
+	}
+
+	var param struct{ Name, Value string }
+	_, body := get("/v1/params/db/password", "session-token")
+	if err := json.Unmarshal(body, &param); err != nil || param.Value != "hunter2" {
+		t.Errorf("got %s; want db/password=hunter2", body)
+	}
+}
@@ -0,0 +1,102 @@
+//go:build integration
+
+package main
+
+// Runs the CLI end to end against LocalStack. Start it with:
+//
+//	docker run --rm -p 4566:4566 localstack/localstack
+//
+// and run with:
+//
+//	go test -tags=integration -run Integration .
+//
+// Set LOCALSTACK_ENDPOINT to use an instance elsewhere.
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegration(t *testing.T) {
+	endpoint := os.Getenv("LOCALSTACK_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:4566"
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "devx-config")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("unable to build: %v\n%s", err, out)
+	}
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+
+		cmd := exec.Command(bin, args...)
+		cmd.Dir = dir // so no local config file is found
+		cmd.Env = append(os.Environ(),
+			"AWS_ACCESS_KEY_ID=test",
+			"AWS_SECRET_ACCESS_KEY=test",
+			"AWS_PROFILE=",
+			"DEVX_CONFIG_ENDPOINT_URL="+endpoint,
+			"DEVX_CONFIG_APP=integration",
+			"DEVX_CONFIG_STACK=devx",
+			"DEVX_CONFIG_STAGE=CODE",
+			"DEVX_CONFIG_YES=true",
+			"DEVX_CONFIG_NON_INTERACTIVE=true",
+		)
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		if err != nil {
+			t.Logf("devx-config %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+		}
+
+		return stdout.String(), err
+	}
+
+	for _, st := range []string{"ssm", "secretsmanager"} {
+		t.Run(st, func(t *testing.T) {
+			if _, err := run(t, "--store", st, "set", "GREETING", "hello", "--not-secret"); err != nil {
+				t.Fatalf("set: %v", err)
+			}
+
+			out, err := run(t, "--store", st, "get", "GREETING")
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if !strings.Contains(out, "hello") {
+				t.Errorf("get: got %q; want it to contain 'hello'", out)
+			}
+
+			out, err = run(t, "--store", st, "list")
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if !strings.Contains(out, "GREETING") {
+				t.Errorf("list: got %q; want it to contain 'GREETING'", out)
+			}
+
+			// Secrets are deleted at once, so the test can be run again.
+			deleteArgs := []string{"--store", st, "delete", "GREETING"}
+			if st == "secretsmanager" {
+				deleteArgs = append(deleteArgs, "--without-recovery")
+			}
+			if _, err := run(t, deleteArgs...); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+
+			if _, err := run(t, "--store", st, "get", "GREETING"); err == nil {
+				t.Errorf("get after delete: expected an error")
+			} else if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != NotFound {
+				t.Errorf("get after delete: got %v; want exit code %d", err, NotFound)
+			}
+		})
+	}
+}
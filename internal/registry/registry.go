@@ -0,0 +1,50 @@
+// Package registry holds factories by name, for the pluggable parts of
+// devx-config (stores, transformations and event sinks).
+package registry
+
+import (
+	"sort"
+	"sync"
+)
+
+// Registry maps names to factories of type F. Its zero value is empty and
+// ready to use, and it is safe for concurrent use.
+type Registry[F any] struct {
+	mu        sync.RWMutex
+	factories map[string]F
+}
+
+// Register adds the factory under name, replacing any already registered with
+// that name.
+func (r *Registry[F]) Register(name string, factory F) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.factories == nil {
+		r.factories = map[string]F{}
+	}
+	r.factories[name] = factory
+}
+
+// Lookup returns the factory registered under name, if any.
+func (r *Registry[F]) Lookup(name string) (F, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	factory, ok := r.factories[name]
+	return factory, ok
+}
+
+// Names returns the registered names, sorted.
+func (r *Registry[F]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
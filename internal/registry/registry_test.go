@@ -0,0 +1,24 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	var r Registry[func() string]
+	if _, ok := r.Lookup("a"); ok {
+		t.Errorf("empty: expected no factory")
+	}
+
+	r.Register("b", func() string { return "b" })
+	r.Register("a", func() string { return "old" })
+	r.Register("a", func() string { return "a" })
+
+	if got, want := r.Names(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if f, ok := r.Lookup("a"); !ok || f() != "a" {
+		t.Errorf("expected the latest factory for 'a'")
+	}
+}
@@ -0,0 +1,141 @@
+// Package k8s generates Kubernetes Secret and ConfigMap manifests from a
+// service's parameters, for workloads running on Kubernetes (e.g. EKS).
+package k8s
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// ManagedBy is the app.kubernetes.io/managed-by label of generated objects.
+const ManagedBy = "devx-config"
+
+type Options struct {
+	Name      string // of the Secret and ConfigMap; defaults to the service's app
+	Namespace string // optional
+
+	// Generate only the Secret (of secret parameters), e.g. where other
+	// config is already managed in Kubernetes.
+	SecretsOnly bool
+}
+
+// Manifests returns YAML for a ConfigMap of the service's plain parameters and
+// a Secret of its secrets (either left out if empty), keyed by each
+// parameter's Key (e.g. 'db_password'), so they can be used with envFrom.
+func Manifests(service store.Service, params []store.Parameter, opts Options) ([]byte, error) {
+	name := opts.Name
+	if name == "" {
+		name = ObjectName(service.App)
+	}
+	if !validName.MatchString(name) || len(name) > 253 {
+		return nil, fmt.Errorf("invalid name '%s' (must be lower case alphanumeric characters, '-' or '.')", name)
+	}
+	if opts.Namespace != "" && (!validLabel.MatchString(opts.Namespace) || len(opts.Namespace) > 63) {
+		return nil, fmt.Errorf("invalid namespace '%s' (must be lower case alphanumeric characters or '-')", opts.Namespace)
+	}
+
+	plain, secrets := map[string]string{}, map[string]string{}
+	seen := map[string]string{} // keys to names, to catch clashes
+	for _, p := range params {
+		if !p.DeletedDate.IsZero() {
+			continue
+		}
+
+		key := DataKey(p)
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("'%s' and '%s' both have the key '%s'", other, p.RelativeName(), key)
+		}
+		seen[key] = p.RelativeName()
+
+		if p.IsSecret {
+			secrets[key] = base64.StdEncoding.EncodeToString([]byte(p.Value))
+		} else if !opts.SecretsOnly {
+			plain[key] = p.Value
+		}
+	}
+
+	meta := metadata{
+		Name:      name,
+		Namespace: opts.Namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/name":       ObjectName(service.App),
+			"app.kubernetes.io/managed-by": ManagedBy,
+		},
+		Annotations: map[string]string{"devx-config/prefix": service.Prefix()},
+	}
+
+	var objects []object
+	if len(plain) > 0 {
+		objects = append(objects, object{APIVersion: "v1", Kind: "ConfigMap", Metadata: meta, Data: plain})
+	}
+	if len(secrets) > 0 {
+		objects = append(objects, object{APIVersion: "v1", Kind: "Secret", Metadata: meta, Type: "Opaque", Data: secrets})
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("no parameters to generate manifests from")
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, o := range objects {
+		if err := enc.Encode(o); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Fields in the order kubectl shows them.
+type object struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   metadata          `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type metadata struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+var (
+	validName   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	validLabel  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	invalidName = regexp.MustCompile(`[^a-z0-9-]+`)
+	invalidKey  = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+)
+
+// ObjectName returns s (e.g. an app name) as a valid Kubernetes object name
+// and label value: lower case, with anything other than letters, digits and
+// '-' replaced by '-', and at most 63 characters.
+func ObjectName(s string) string {
+	name := invalidName.ReplaceAllString(strings.ToLower(s), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+
+	return strings.Trim(name, "-")
+}
+
+// DataKey returns the key for the parameter in a Secret or ConfigMap: its Key
+// (e.g. 'db_password' for 'db/password'), with any characters Kubernetes
+// doesn't allow replaced by '_'.
+func DataKey(p store.Parameter) string {
+	return invalidKey.ReplaceAllString(p.Key(), "_")
+}
@@ -0,0 +1,66 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestManifests(t *testing.T) {
+	service := store.Service{App: "My_API", Stack: "deploy", Stage: "PROD"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/My_API/db/host", Value: "db.internal"},
+		{Service: service, Name: "/PROD/deploy/My_API/db/password", Value: "hunter2", IsSecret: true},
+	}
+
+	got, err := Manifests(service, params, Options{Namespace: "api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-api
+  namespace: api
+  labels:
+    app.kubernetes.io/managed-by: devx-config
+    app.kubernetes.io/name: my-api
+  annotations:
+    devx-config/prefix: /PROD/deploy/My_API
+data:
+  db_host: db.internal
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-api
+  namespace: api
+  labels:
+    app.kubernetes.io/managed-by: devx-config
+    app.kubernetes.io/name: my-api
+  annotations:
+    devx-config/prefix: /PROD/deploy/My_API
+type: Opaque
+data:
+  db_password: aHVudGVyMg==
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = Manifests(service, params, Options{Name: "api-config", SecretsOnly: true})
+	if err != nil || strings.Contains(string(got), "ConfigMap") || !strings.Contains(string(got), "name: api-config") {
+		t.Errorf("secrets only: got %s, %v; want just the Secret, named api-config", got, err)
+	}
+
+	clash := append(params, store.Parameter{Service: service, Name: "/PROD/deploy/My_API/db.host", Value: "other"})
+	if _, err := Manifests(service, clash, Options{}); err == nil {
+		t.Errorf("clash: expected an error")
+	}
+
+	if _, err := Manifests(service, params, Options{Namespace: "Not Valid"}); err == nil {
+		t.Errorf("invalid namespace: expected an error")
+	}
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	maxFileBytes = 10 * 1024 * 1024
+	maxBackups   = 3
+)
+
+// OpenFile opens path for appending, creating it (and its directory) if
+// necessary. A leading '~/' is expanded to the user's home directory. Files
+// are rotated when opened if they have grown beyond 10MB, keeping up to three
+// old files (path.1 being the most recent).
+func OpenFile(path string) (*os.File, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand '~': %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("unable to create log directory: %w", err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > maxFileBytes {
+		if err := rotate(path); err != nil {
+			return nil, fmt.Errorf("unable to rotate log file: %w", err)
+		}
+	}
+
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+}
+
+func rotate(path string) error {
+	for i := maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(path, path+".1")
+}
+
+// WithFile returns a logger that also writes everything (including debug
+// messages) to w as JSON, as a record of operations performed.
+func (l Logger) WithFile(w io.Writer) Logger {
+	file := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return Logger{slog.New(teeHandler{l.l.Handler(), file})}
+}
+
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := teeHandler{}
+	for _, h := range t {
+		out = append(out, h.WithAttrs(attrs))
+	}
+
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := teeHandler{}
+	for _, h := range t {
+		out = append(out, h.WithGroup(name))
+	}
+
+	return out
+}
@@ -1,30 +1,123 @@
-// A simple logging interface that wraps the standard library 'log' package to
-// add a debug level. Note, this is not, therefore, a high-performance library.
-// If you need that, use something like
-// https://pkg.go.dev/github.com/golang/glog.
+// A simple levelled logging interface that wraps the standard library 'slog'
+// package. Messages are printf-style, as this is a CLI rather than a server;
+// contextual fields (command, service, store, etc.) are added with With. Note,
+// this is not, therefore, a high-performance library. If you need that, use
+// slog directly.
 package log
 
 import (
+	"context"
 	"fmt"
-	stdLog "log"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatPlain = "plain" // just the message, for humans
+	FormatText  = "text"  // slog's key=value format
+	FormatJSON  = "json"
 )
 
 type Logger struct {
-	debug bool
+	l *slog.Logger
+}
+
+// New returns a logger writing to w at or above level ('debug', 'info',
+// 'warn' or 'error') in the given format.
+func New(w io.Writer, level string, format string) (Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return Logger{}, fmt.Errorf("invalid log level '%s' (must be one of 'debug', 'info', 'warn', 'error')", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case FormatPlain:
+		return Logger{slog.New(&plainHandler{w: w, level: lvl, mu: &sync.Mutex{}})}, nil
+	case FormatText:
+		return Logger{slog.New(slog.NewTextHandler(w, opts))}, nil
+	case FormatJSON:
+		return Logger{slog.New(slog.NewJSONHandler(w, opts))}, nil
+	default:
+		return Logger{}, fmt.Errorf("invalid log format '%s' (must be one of 'plain', 'text', 'json')", format)
+	}
+}
+
+// With returns a logger that adds the given key/value pairs to every message.
+func (l Logger) With(args ...any) Logger {
+	return Logger{l.l.With(args...)}
 }
 
-func New(debug bool) Logger {
-	return Logger{debug}
+// For things that have gone wrong.
+func (l Logger) Errorf(format string, args ...any) {
+	l.l.Error(fmt.Sprintf(format, args...))
 }
 
-// For stuff users care about - wraps fmt. Always adds a trailing newline.
+// For things that might be wrong, but don't stop us.
+func (l Logger) Warnf(format string, args ...any) {
+	l.l.Warn(fmt.Sprintf(format, args...))
+}
+
+// For stuff users care about.
 func (l Logger) Infof(format string, args ...any) {
-	fmt.Printf(format+"\n", args...)
+	l.l.Info(fmt.Sprintf(format, args...))
 }
 
-// For stuff developers care about - wraps log and only logs if debug is true.
+// For stuff developers care about - only logs if the level is debug.
 func (l Logger) Debugf(format string, args ...any) {
-	if l.debug {
-		stdLog.Printf(format, args...)
+	l.l.Debug(fmt.Sprintf(format, args...))
+}
+
+// Writes just the message (prefixed with the level, other than for info), as
+// the previous fmt-based logger did. Contextual fields are only shown at debug
+// level.
+type plainHandler struct {
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+func (h *plainHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *plainHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if r.Level != slog.LevelInfo {
+		b.WriteString(r.Level.String() + ": ")
+	}
+
+	b.WriteString(r.Message)
+
+	if h.level <= slog.LevelDebug {
+		for _, attr := range h.attrs {
+			b.WriteString(" " + attr.String())
+		}
+		r.Attrs(func(attr slog.Attr) bool {
+			b.WriteString(" " + attr.String())
+			return true
+		})
 	}
+
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &next
+}
+
+// Groups aren't used by this CLI, so are flattened.
+func (h *plainHandler) WithGroup(_ string) slog.Handler {
+	return h
 }
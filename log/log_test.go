@@ -0,0 +1,24 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPlain(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatPlain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger = logger.With("command", "get")
+	logger.Debugf("hidden")
+	logger.Infof("hello %s", "world")
+	logger.Warnf("careful")
+
+	want := "hello world\nWARN: careful\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %q; want %q", got, want)
+	}
+}
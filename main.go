@@ -1,18 +1,49 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"flag"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
 
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/backup"
+	"github.com/guardian/devx-config/cdk"
+	"github.com/guardian/devx-config/clipboard"
 	"github.com/guardian/devx-config/config"
+	"github.com/guardian/devx-config/drift"
+	"github.com/guardian/devx-config/ecs"
+	"github.com/guardian/devx-config/events"
+	"github.com/guardian/devx-config/k8s"
 	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/output"
+	"github.com/guardian/devx-config/pkg/devxconfig"
+	"github.com/guardian/devx-config/progress"
+	"github.com/guardian/devx-config/prompt"
+	"github.com/guardian/devx-config/rotation"
+	"github.com/guardian/devx-config/server"
 	"github.com/guardian/devx-config/store"
+	"github.com/guardian/devx-config/systemd"
+	"github.com/guardian/devx-config/terraform"
+	"github.com/guardian/devx-config/transform"
 )
 
 type Cmd struct {
@@ -21,180 +52,1935 @@ type Cmd struct {
 	Run         func(args []string)
 }
 
-const (
-	InternalError = 1
-	InvalidArgs   = 2
-)
-
 func main() {
-	logger := log.New(readBoolFlag(os.Args[1:], "debug", "Whether to enable debug logs."))
+	logger, _ := log.New(os.Stderr, "info", log.FormatPlain) // until flags are parsed
+
+	// Passed down to every store and AWS call, rather than each creating its
+	// own.
+	ctx := context.Background()
 
-	rootCmd := &cobra.Command{Use: "app"}
+	rootCmd := &cobra.Command{Use: "devx-config"}
+	debug := rootCmd.PersistentFlags().Bool("debug", false, "Whether to enable debug logs (same as --log-level=debug).")
+	logLevel := rootCmd.PersistentFlags().String("log-level", "info", "Minimum level to log: 'debug', 'info', 'warn' or 'error'.")
+	logFormat := rootCmd.PersistentFlags().String("log-format", log.FormatPlain, "Log format: 'plain', 'text' or 'json'.")
+	logFile := rootCmd.PersistentFlags().String("log-file", "", "Also append JSON logs of all operations to this file (e.g. '~/.devx-config/logs/devx.log').")
 	app := rootCmd.PersistentFlags().String("app", "", "App for your service.")
 	stack := rootCmd.PersistentFlags().String("stack", "", "Stack for your service.")
 	stage := rootCmd.PersistentFlags().String("stage", "", "Stage for your service.")
 	profile := rootCmd.PersistentFlags().String("profile", "", "Janus profile for your service (when running locally).")
+	storeName := rootCmd.PersistentFlags().String("store", "", "Store to use: 'ssm', 'secretsmanager' or a plugin's name (defaults to the config file's routing, then 'ssm').")
+	serviceName := rootCmd.PersistentFlags().String("service", "", "Service to use from a workspace config file (defaults to the one containing the working directory).")
+	configProfile := rootCmd.PersistentFlags().String("config-profile", "", "Named profile to use from the config file (defaults to 'default', if present).")
+	yes := rootCmd.PersistentFlags().BoolP("yes", "y", false, "Assume 'yes' for all confirmation prompts.")
+	nonInteractive := rootCmd.PersistentFlags().Bool("non-interactive", false, "Never prompt; fail instead if input would be required.")
+	outputFormat := rootCmd.PersistentFlags().StringP("output", "o", "", "Output format for parameters: 'env', 'export', 'powershell', 'compose', 'json', 'csv', 'raw' or 'table' (default 'table' for list in a terminal, otherwise 'env').")
+	endpointURL := rootCmd.PersistentFlags().String("endpoint-url", "", "Custom AWS endpoint URL for all services, e.g. 'http://localhost:4566' for LocalStack.")
+	region := rootCmd.PersistentFlags().String("region", "", "AWS region (defaults to the config file, then AWS_REGION, the AWS profile's region, instance metadata, then eu-west-1).")
+	ssoSession := rootCmd.PersistentFlags().String("sso-session", "", "IAM Identity Center (SSO) session to log in to if yours has expired (defaults to that of the profile).")
+	mfaToken := rootCmd.PersistentFlags().String("mfa-token", "", "MFA code, for profiles that require one (prompted for if needed and not given).")
+	roleARN := rootCmd.PersistentFlags().String("role-arn", "", "ARN of an IAM role to assume (e.g. in CI, or to work across accounts).")
+	externalID := rootCmd.PersistentFlags().String("external-id", "", "External ID to pass when assuming --role-arn.")
+	sessionName := rootCmd.PersistentFlags().String("session-name", "", "Session name to use when assuming --role-arn (default 'devx-config').")
+	timeout := rootCmd.PersistentFlags().Duration("timeout", 0, "Timeout for each AWS request, e.g. '10s' (0 for the SDK default).")
+	proxy := rootCmd.PersistentFlags().String("proxy", "", "HTTP(S) proxy URL for AWS requests, e.g. 'http://proxy.example.com:8080' (defaults to HTTPS_PROXY).")
+	caBundle := rootCmd.PersistentFlags().String("ca-bundle", "", "PEM file of extra CA certificates to trust, e.g. that of a TLS-inspecting proxy such as Zscaler.")
+	maxRetries := rootCmd.PersistentFlags().Int("max-retries", 0, "Maximum retries for each failed (e.g. throttled) AWS request (0 for the SDK default of 2).")
+	retryMode := rootCmd.PersistentFlags().String("retry-mode", "", "AWS retry mode: 'standard' or 'adaptive' (which also slows down once throttled).")
+	maxRPS := rootCmd.PersistentFlags().Float64("max-rps", 0, "Maximum AWS requests per second, so bulk operations (e.g. list) leave API throughput for apps (0 for no limit).")
+	noCredCache := rootCmd.PersistentFlags().Bool("no-cred-cache", false, "Don't cache temporary AWS credentials between commands (or use cached ones).")
+	preflightFlag := rootCmd.PersistentFlags().Bool("preflight", false, "Before changing anything, check permissions with the IAM policy simulator (needs iam:SimulatePrincipalPolicy).")
+	traceAWS := rootCmd.PersistentFlags().Bool("trace-aws", false, "Log request IDs, retries, latencies and (body-less) HTTP requests/responses for AWS calls.")
+	format := rootCmd.PersistentFlags().String("format", "", "Go template for each parameter, e.g. '{{.Key}} {{.LastModified}}' (overrides --output).")
+	transformNames := rootCmd.PersistentFlags().StringSlice("transform", nil, "Transform every parameter read or written by get, list and set, after the config file's transforms, e.g. 'base64' or 'kms:alias/other-key'.")
+	eventSinks := rootCmd.PersistentFlags().StringSlice("event-sink", nil, "Publish an event for each change made by set, delete, label, unlabel and promote-replica, as well as to the config file's sinks: 'stdout', 'eventbridge[:<bus>]', 'sns:<topic ARN>' or 'webhook:<URL>'.")
+	multiline := rootCmd.PersistentFlags().String("multiline", string(output.Quote), "Encoding for multi-line values in env output: 'quote' or 'base64'.")
+
+	// Optional settings from config files, read once flags are parsed.
+	var fileConf config.Config
+
+	// The region is taken from (in order of preference) that given (by
+	// --regions), --region, the config file (for the store, then in general),
+	// AWS_REGION, the AWS profile, instance metadata, the config file's
+	// default, and finally eu-west-1.
+	awsOpts := func(storeName string, regionOverride string) awsclient.Options {
+		r := firstNonEmpty(regionOverride, *region, fileConf.RegionFor(storeName))
+		p := firstNonEmpty(*profile, fileConf.AWSProfile)
+		return awsclient.Options{
+			Profile:       p,
+			Region:        r,
+			DefaultRegion: fileConf.DefaultRegion,
+			Account:       fileConf.Account,
+			RoleChain:     fileConf.RoleChain,
+			RoleARN:       firstNonEmpty(*roleARN, fileConf.RoleARN),
+			ExternalID:    firstNonEmpty(*externalID, fileConf.ExternalID),
+			SessionName:   firstNonEmpty(*sessionName, fileConf.SessionName),
+			Timeout:       *timeout,
+			Proxy:         firstNonEmpty(*proxy, fileConf.Proxy),
+			CABundle:      firstNonEmpty(*caBundle, fileConf.CABundle),
+			MaxRetries:    firstNonZero(*maxRetries, fileConf.MaxRetries),
+			RetryMode:     firstNonEmpty(*retryMode, fileConf.RetryMode),
+			MaxRPS:        *maxRPS,
+			Endpoints:     endpoints(*endpointURL, fileConf),
+			Trace:         *traceAWS,
+			MFAToken: func() (string, error) {
+				if *mfaToken != "" {
+					return *mfaToken, nil
+				}
+
+				return newPrompter(*nonInteractive).Input(fmt.Sprintf("MFA code for profile '%s'", p), "", nil)
+			},
+			NoCredentialCache: *noCredCache,
+		}
+	}
+
+	// Loads AWS config. If an SSO session has expired, offers to log in again
+	// (--yes counts as consent) or else says how to.
+	loadAWSConfigWith := func(ctx context.Context, opts awsclient.Options) (aws.Config, error) {
+		cfg, err := awsclient.LoadConfig(ctx, logger, opts)
+		if !awsclient.IsSSOExpired(err) {
+			return cfg, err
+		}
+
+		login := awsclient.SSOLoginCommand(opts.Profile, firstNonEmpty(*ssoSession, fileConf.SSOSession))
+		loginStr := strings.Join(login, " ")
+
+		ok := *yes
+		if !ok {
+			ok, _ = newPrompter(*nonInteractive).YesNo(fmt.Sprintf("Your AWS SSO session has expired. Run '%s' now?", loginStr))
+		}
+
+		if !ok {
+			return cfg, fmt.Errorf("%w (run '%s' to log in again)", err, loginStr)
+		}
+
+		c := exec.Command(login[0], login[1:]...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stderr, os.Stderr // stdout is for data
+		if err := c.Run(); err != nil {
+			return cfg, fmt.Errorf("unable to run '%s': %w", loginStr, err)
+		}
+
+		return awsclient.LoadConfig(ctx, logger, opts)
+	}
+
+	// Loads AWS config for the store.
+	loadAWSConfig := func(ctx context.Context, storeName string, region string) (aws.Config, error) {
+		return loadAWSConfigWith(ctx, awsOpts(storeName, region))
+	}
+
+	// Registry entries in SSM are read using the region and profile from
+	// flags and config files (as read so far), rather than those of the
+	// service being looked up.
+	getRegistryParameter := func(ctx context.Context, conf config.Config, name string) (string, error) {
+		fileConf = conf
+		cfg, err := loadAWSConfig(ctx, "ssm", "")
+		if err != nil {
+			return "", err
+		}
+
+		out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{Name: &name, WithDecryption: aws.Bool(true)})
+		if err != nil {
+			return "", err
+		}
+
+		return *out.Parameter.Value, nil
+	}
+
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		err := bindEnv(rootCmd.PersistentFlags())
+		check(logger, err, "invalid environment variable", InvalidArgs)
+
+		level := *logLevel
+		if *debug {
+			level = "debug"
+		}
+
+		l, err := log.New(os.Stderr, level, *logFormat)
+		check(logger, err, "invalid logging options", InvalidArgs)
+
+		argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
+		fileConf, err = config.Load(ctx, *configProfile, *serviceName, argConf, getRegistryParameter)
+		jsonErrors = firstNonEmpty(*outputFormat, fileConf.Output) == string(output.JSON)
+
+		if mode := firstNonEmpty(*retryMode, fileConf.RetryMode); mode != "" {
+			_, err := aws.ParseRetryMode(mode)
+			check(logger, err, "invalid retry mode (must be 'standard' or 'adaptive')", InvalidArgs)
+		}
+		if firstNonZero(*maxRetries, fileConf.MaxRetries) < 0 {
+			check(logger, errors.New("must not be negative"), "invalid max retries", InvalidArgs)
+		}
+		if *maxRPS < 0 {
+			check(logger, errors.New("must not be negative"), "invalid --max-rps", InvalidArgs)
+		}
+
+		// Commands that check config files themselves report problems with
+		// them, rather than failing here.
+		if cmd.Annotations["checksConfig"] == "" {
+			check(logger, err, "unable to read config", InvalidArgs)
+		}
+
+		path := *logFile
+		if path == "" {
+			path = fileConf.LogFile
+		}
+
+		if path != "" {
+			f, err := log.OpenFile(path)
+			check(logger, err, "unable to open log file", InvalidArgs)
+			l = l.WithFile(f) // note, closed on exit
+		}
+
+		logger = l.With("command", cmd.Name())
+	}
+
+	// Resolves the service from args and config files, and adds it to the
+	// logger's context. Anything missing is inferred (see config.Infer).
+	readService := func() store.Service {
+		argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
+		conf := config.Infer(ctx, logger, config.Merge(fileConf, argConf))
+
+		service, err := devxconfig.ServiceFor(conf)
+		check(logger, err, "Unable to read config", InvalidArgs)
+
+		logger = logger.With("service", service.Prefix())
+		return service
+	}
+
+	// Stores are created on demand, as each needs its own AWS client.
+	newStoreWith := func(name string, opts awsclient.Options) store.Store {
+		factory, err := store.Lookup(name)
+		check(logger, err, "invalid store", InvalidArgs)
+
+		cfg, err := loadAWSConfigWith(ctx, opts)
+		check(logger, err, "unable to load AWS config", InternalError)
+
+		st, err := factory(store.Config{Logger: logger, AWS: cfg, KMSKey: fileConf.KMSKey, Tags: fileConf.Tags})
+		check(logger, err, fmt.Sprintf("unable to create %s store", name), InternalError)
+		return st
+	}
+	// The region is empty other than when reading from several (see
+	// --regions).
+	newStoreIn := func(name string, region string) store.Store {
+		return newStoreWith(name, awsOpts(name, region))
+	}
+	newStore := func(name string) store.Store {
+		return newStoreIn(name, "")
+	}
+	// Reads from each of the stores in turn, listing each name only once
+	// (from the first store that has it), as the library does.
+	newFallbackStore := func(names []string) store.Store {
+		stores := make([]store.Store, len(names))
+		for i, name := range names {
+			stores[i] = newStore(name)
+		}
+		return store.Fallback(stores[0], stores[1:]...)
+	}
+
+	// Creates each store for each region up front (rather than concurrently),
+	// so that any prompts, e.g. to log in, happen once.
+	newRegionalStores := func(regions []string, storeNames []string) map[string]map[string]store.Store {
+		stores := map[string]map[string]store.Store{}
+		for _, r := range regions {
+			stores[r] = map[string]store.Store{}
+			for _, name := range storeNames {
+				stores[r][name] = newStoreIn(name, r)
+			}
+		}
+
+		return stores
+	}
+
+	// Groups parameter names by the store they are routed to.
+	byStore := func(names []string) map[string][]string {
+		out := map[string][]string{}
+		for _, name := range names {
+			st := firstNonEmpty(*storeName, fileConf.StoreFor(name))
+			out[st] = append(out[st], name)
+		}
+
+		return out
+	}
+
+	// With --preflight, checks with the IAM policy simulator that the caller
+	// can make the change, so that a missing permission is reported clearly
+	// (and before anything is changed). If the simulator can't be used, the
+	// check is skipped.
+	preflight := func(storeName string, op store.Operation, service store.Service, names ...string) {
+		if !*preflightFlag && !fileConf.Preflight {
+			return
+		}
+
+		actions := store.Actions(storeName, op)
+		if len(actions) == 0 {
+			logger.Debugf("Skipping preflight check, as the %s store's IAM actions aren't known", storeName)
+			return
+		}
+
+		cfg, err := loadAWSConfig(ctx, storeName, "")
+		check(logger, err, "unable to load AWS config", InternalError)
+
+		account, principal, err := awsclient.Identity(ctx, cfg)
+		check(logger, err, "unable to run preflight check", InternalError)
+
+		var resources []string
+		for _, name := range names {
+			resources = append(resources, store.ResourceARN(storeName, awsclient.Partition(cfg.Region), cfg.Region, account, service.Prefix()+"/"+name))
+		}
+
+		err = awsclient.Simulate(ctx, cfg, principal, actions, resources)
+		if errors.Is(err, awsclient.ErrSimulationUnavailable) {
+			logger.Warnf("Skipping preflight check: %v", err)
+			return
+		}
+		check(logger, err, "preflight check failed", AccessDenied)
+	}
+
+	// Transforms parameters as they are read and written (see package
+	// transform), by the config file's rules and then --transform. Created on
+	// first use, as the config file must have been read.
+	var pipeline *transform.Pipeline
+	transforms := func() transform.Pipeline {
+		if pipeline != nil {
+			return *pipeline
+		}
+
+		rules := append([]config.Transform{}, fileConf.Transforms...)
+		if len(*transformNames) > 0 {
+			rules = append(rules, config.Transform{Apply: *transformNames})
+		}
+
+		p, err := transform.New(rules, transform.Env{AWS: func(ctx context.Context) (aws.Config, error) {
+			return loadAWSConfig(ctx, "", "")
+		}})
+		check(logger, err, "invalid transforms", InvalidArgs)
+
+		pipeline = &p
+		return p
+	}
+
+	// Publishes an event for a change to a parameter, to the config file's
+	// sinks and --event-sink (see package events). The change has already
+	// been made, so failures are only warned about.
+	var publisher events.Multi
+	var actor *string
+	publish := func(e events.ChangeEvent) {
+		if publisher == nil {
+			sinks := append(append([]string{}, fileConf.EventSinks...), *eventSinks...)
+			if len(sinks) == 0 {
+				return
+			}
+
+			var err error
+			publisher, err = events.New(sinks, events.Env{Stdout: os.Stdout, AWS: func(ctx context.Context) (aws.Config, error) {
+				return loadAWSConfig(ctx, "", "")
+			}})
+			check(logger, err, "invalid event sinks", InvalidArgs)
+		}
+
+		if actor == nil {
+			actor = new(string)
+			if cfg, err := loadAWSConfig(ctx, e.Store, e.Region); err == nil {
+				if _, principal, err := awsclient.Identity(ctx, cfg); err == nil {
+					*actor = principal
+				}
+			}
+		}
+		e.Actor = *actor
+
+		if err := publisher.Publish(ctx, e); err != nil {
+			logger.Warnf("Unable to publish change to '%s': %v", e.Name, err)
+		}
+	}
+
+	// Returns the SSM store for commands that only support SSM, failing if the
+	// parameter is routed elsewhere.
+	ssmStore := func(name string, feature string) store.SSM {
+		stName := firstNonEmpty(*storeName, fileConf.StoreFor(name))
+		st, ok := newStore(stName).(store.SSM)
+		if !ok {
+			check(logger, fmt.Errorf("'%s' is in the %s store", name, stName), feature+" is only supported for SSM", InvalidArgs)
+		}
+
+		return st
+	}
+
+	// Returns the Secrets Manager store, for commands only it supports, failing
+	// if the "secretsmanager" store has been replaced (e.g. by a plugin).
+	secretsManagerStore := func(feature string) store.SecretsManager {
+		st, ok := newStore("secretsmanager").(store.SecretsManager)
+		if !ok {
+			check(logger, errors.New("the secretsmanager store isn't Secrets Manager"), feature+" is only supported for Secrets Manager", InvalidArgs)
+		}
+
+		return st
+	}
+
+	// Returns every parameter of the service, from each of the configured
+	// stores (or just --store).
+	listAll := func(service store.Service) []store.Parameter {
+		storeNames := fileConf.Stores()
+		if *storeName != "" {
+			storeNames = []string{*storeName}
+		}
+
+		params, err := newFallbackStore(storeNames).List(ctx, service, store.Filter{})
+		check(logger, err, fmt.Sprintf("unable to list parameters for service '%s'", service.Prefix()), InternalError)
+
+		return params
+	}
 
 	getCmd := &cobra.Command{
-		Use:   "get",
-		Short: "Get parameter for a service",
-		Run: func(cmd *cobra.Command, args []string) {
-			name := cmd.Flags().String("name", "", "Name of parameter to retrieve")
-			cmd.MarkFlagRequired("name")
-			cmd.ParseFlags(args)
+		Use:   "get [name...]",
+		Short: "Get parameter(s) for a service",
+	}
+	getName := getCmd.Flags().String("name", "", "Name of parameter to retrieve")
+	getCopy := getCmd.Flags().Bool("copy", false, "Copy the value to the clipboard instead of printing it.")
+	getClearAfter := getCmd.Flags().Int("clear-after", 0, "With --copy, clear the clipboard after this many seconds (0 to disable).")
+	getFailOnMissing := getCmd.Flags().Bool("fail-on-missing", true, "Exit with an error if the parameter does not exist (set to false for optional parameters).")
+	getJSONKey := getCmd.Flags().String("json-key", "", "Parse the value as a JSON object and return only this field.")
+	getVersionStage := getCmd.Flags().String("version-stage", "", "Get this version stage of a Secrets Manager secret, e.g. 'AWSPREVIOUS' (defaults to 'AWSCURRENT').")
+	getVersionID := getCmd.Flags().String("version-id", "", "Get this version (by ID) of a Secrets Manager secret.")
+	getVersion := getCmd.Flags().Int64("version", 0, "Get this version of an SSM parameter (defaults to the latest).")
+	getCmd.MarkFlagsMutuallyExclusive("version", "version-stage")
+	getCmd.MarkFlagsMutuallyExclusive("version", "version-id")
+	getCmd.MarkFlagsMutuallyExclusive("version-stage", "version-id")
+	getRegions := getCmd.Flags().StringSlice("regions", nil, "Get from each of these regions concurrently, e.g. 'eu-west-1,us-east-1' (results are labelled by region).")
+	getShared := getCmd.Flags().Bool("shared", false, "Get an SSM parameter shared into this account (e.g. by a central platform account) with AWS RAM, by --arn.")
+	getARN := getCmd.Flags().String("arn", "", "With --shared, the ARN of the parameter to get.")
+	getCmd.MarkFlagsRequiredTogether("shared", "arn")
+	getCmd.Run = func(cmd *cobra.Command, args []string) {
+		opts := outputOpts(logger, firstNonEmpty(*outputFormat, fileConf.Output), output.Env, *multiline, *format)
 
-			argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
-			conf, err := config.Read(argConf, config.DefaultFiles()...)
-			check(logger, err, "Unable to read config", InvalidArgs)
+		if *getShared && (len(args) > 0 || *getName != "" || len(*getRegions) > 0 || *getVersion != 0 || *getVersionStage != "" || *getVersionID != "") {
+			check(logger, errors.New("--shared gets a single parameter by --arn, so can't be used with names, --regions or versions"), "invalid arguments", InvalidArgs)
+		}
 
-			ssm := store.NewSSM(logger, ssmClient(context.TODO(), logger, *profile))
+		if (*getVersionStage != "" || *getVersionID != "") && (len(args) > 1 || len(*getRegions) > 0) {
+			check(logger, errors.New("--version-stage and --version-id can only be used to get a single secret"), "invalid arguments", InvalidArgs)
+		}
+		if *getVersion != 0 && (len(args) > 1 || len(*getRegions) > 0) {
+			check(logger, errors.New("--version can only be used to get a single parameter"), "invalid arguments", InvalidArgs)
+		}
+		if *getVersion < 0 {
+			check(logger, errors.New("must be positive"), "invalid --version", InvalidArgs)
+		}
 
-			service := store.Service{App: conf.App, Stack: conf.Stack, Stage: conf.Stage}
-			item, err := ssm.Get(service, *name)
-			check(logger, err, fmt.Sprintf("unable to get %s for service '%s'", *name, service.Prefix()), 1)
+		if len(*getRegions) > 0 {
+			if *getCopy {
+				check(logger, errors.New("--copy can't be used with --regions"), "invalid arguments", InvalidArgs)
+			}
+			if len(args) > 1 && (*getName != "" || *getJSONKey != "") {
+				check(logger, errors.New("--name and --json-key can't be used with several names"), "invalid arguments", InvalidArgs)
+			}
 
-			logger.Infof(item.String())
-		},
+			names := args
+			if len(names) <= 1 {
+				names = []string{nameArg(logger, args, *getName)}
+			}
+
+			service := readService()
+			groups := byStore(names)
+
+			var storeNames []string
+			for st := range groups {
+				storeNames = append(storeNames, st)
+			}
+			stores := newRegionalStores(*getRegions, storeNames)
+
+			items, err := fanOutRegions(*getRegions, func(region string) ([]store.Parameter, error) {
+				var items []store.Parameter
+				for st, names := range groups {
+					got, err := stores[region][st].GetMany(ctx, service, names)
+					if errors.Is(err, store.ErrNotFound) && !*getFailOnMissing {
+						logger.Debugf("some parameters not found in %s: %v", region, err)
+						err = nil
+					}
+					if err != nil {
+						return nil, err
+					}
+
+					items = append(items, got...)
+				}
+
+				store.SortByNames(items, names)
+
+				items, err := transforms().Read(ctx, items)
+				if err != nil {
+					return nil, err
+				}
+
+				if *getJSONKey != "" {
+					for i := range items {
+						var err error
+						if items[i], err = items[i].JSONField(*getJSONKey); err != nil {
+							return nil, err
+						}
+					}
+				}
+
+				return items, nil
+			})
+			check(logger, err, fmt.Sprintf("unable to get parameters for service '%s'", service.Prefix()), InternalError)
+
+			err = output.Write(os.Stdout, opts, items)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		if len(args) > 1 {
+			if *getName != "" || *getCopy || *getJSONKey != "" {
+				check(logger, errors.New("--name, --copy and --json-key can't be used with several names"), "invalid arguments", InvalidArgs)
+			}
+
+			service := readService()
+
+			// Parameters are fetched in batches from each store they are
+			// routed to.
+			var items []store.Parameter
+			for st, names := range byStore(args) {
+				got, err := newStore(st).GetMany(ctx, service, names)
+				if errors.Is(err, store.ErrNotFound) && !*getFailOnMissing {
+					logger.Debugf("some parameters not found: %v", err)
+					err = nil
+				}
+
+				check(logger, err, fmt.Sprintf("unable to get parameters for service '%s'", service.Prefix()), InternalError)
+				items = append(items, got...)
+			}
+
+			store.SortByNames(items, args)
+
+			items, err := transforms().Read(ctx, items)
+			check(logger, err, "unable to transform parameters", InternalError)
+
+			err = output.Write(os.Stdout, opts, items)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		var name, source string
+		var service store.Service
+		var arn store.ParameterARN
+		if *getShared {
+			var err error
+			arn, err = store.ParseParameterARN(*getARN)
+			check(logger, err, "invalid --arn", InvalidArgs)
+			name, source = arn.Name, fmt.Sprintf("account %s", arn.Account)
+		} else {
+			name = nameArg(logger, args, *getName)
+			service = readService()
+			source = fmt.Sprintf("service '%s'", service.Prefix())
+		}
+
+		stName := firstNonEmpty(*storeName, fileConf.StoreFor(name))
+
+		var item store.Parameter
+		var err error
+		switch {
+		case *getShared:
+			// Shared parameters can only be read in their own region.
+			st, ok := newStoreIn("ssm", arn.Region).(store.SSM)
+			if !ok {
+				check(logger, errors.New("the ssm store isn't SSM"), "--shared is only supported for SSM", InvalidArgs)
+			}
+			item, err = st.GetShared(ctx, arn)
+		case *getVersionStage != "" || *getVersionID != "":
+			sm, ok := newStore(stName).(store.SecretsManager)
+			if !ok {
+				check(logger, fmt.Errorf("'%s' is in the %s store", name, stName), "--version-stage and --version-id are only supported for Secrets Manager", InvalidArgs)
+			}
+
+			if *getVersionStage != "" {
+				item, err = sm.GetVersionStage(ctx, service, name, *getVersionStage)
+			} else {
+				item, err = sm.GetVersion(ctx, service, name, *getVersionID)
+			}
+		case *getVersion != 0:
+			item, err = ssmStore(name, "--version").GetVersion(ctx, service, name, strconv.FormatInt(*getVersion, 10))
+		default:
+			item, err = newStore(stName).Get(ctx, service, name)
+		}
+
+		if errors.Is(err, store.ErrNotFound) && !*getFailOnMissing {
+			logger.Debugf("parameter '%s' not found: %v", name, err)
+			return
+		}
+
+		check(logger, err, fmt.Sprintf("unable to get %s for %s", name, source), InternalError)
+
+		// A transform (e.g. json-flatten) may turn the parameter into several.
+		items, err := transforms().Read(ctx, []store.Parameter{item})
+		check(logger, err, "unable to transform parameter", InternalError)
+		if len(items) != 1 {
+			if *getCopy || *getJSONKey != "" {
+				check(logger, fmt.Errorf("'%s' is transformed into %d parameters", name, len(items)), "--copy and --json-key can't be used", InvalidArgs)
+			}
+
+			err = output.Write(os.Stdout, opts, items)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+		item = items[0]
+
+		if *getJSONKey != "" {
+			item, err = item.JSONField(*getJSONKey)
+			check(logger, err, "unable to extract JSON key", InternalError)
+		}
+
+		if !*getCopy {
+			err = output.WriteOne(os.Stdout, opts, item)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		err = clipboard.Write(item.Value)
+		check(logger, err, "unable to copy to clipboard", InternalError)
+
+		if *getClearAfter <= 0 {
+			logger.Infof("Copied '%s' to the clipboard.", name)
+			return
+		}
+
+		logger.Infof("Copied '%s' to the clipboard; it will be cleared in %d seconds.", name, *getClearAfter)
+
+		// Interrupting the wait (e.g. with Ctrl-C) clears the clipboard at
+		// once, rather than leaving the value on it.
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-time.After(time.Duration(*getClearAfter) * time.Second):
+		case <-interrupted:
+			logger.Infof("Interrupted, so clearing the clipboard now.")
+		}
+		signal.Stop(interrupted)
+
+		err = clipboard.Clear(item.Value)
+		check(logger, err, "unable to clear clipboard", InternalError)
 	}
 
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all parameters for a service",
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.ParseFlags(args)
+	}
+	listNoHeader := listCmd.Flags().Bool("no-header", false, "Omit the header row from table output.")
+	listPrefix := listCmd.Flags().String("prefix", "", "Only list parameters whose name starts with this (e.g. 'db/').")
+	listContains := listCmd.Flags().String("contains", "", "Only list parameters whose name contains this.")
+	listRegex := listCmd.Flags().String("regex", "", "Only list parameters whose name matches this regular expression.")
+	listNamesOnly := listCmd.Flags().Bool("names-only", false, "Only output parameter names.")
+	listNull := listCmd.Flags().BoolP("null", "0", false, "Separate names-only (or raw) output with NUL rather than newline, for use with 'xargs -0'.")
+	listSort := listCmd.Flags().String("sort", string(store.SortByName), "Sort by 'name', 'modified' (most recent first) or 'size' (largest first).")
+	listReverse := listCmd.Flags().Bool("reverse", false, "Reverse the sort order.")
+	listIncludeUntagged := listCmd.Flags().Bool("include-untagged", false, "Also list Secrets Manager secrets under the service's path without App, Stack and Stage tags (see backfill-tags).")
+	listIncludeDeleted := listCmd.Flags().Bool("include-deleted", false, "List Secrets Manager secrets scheduled for deletion (without values), with the date they were deleted.")
+	listExcludeDeleted := listCmd.Flags().Bool("exclude-deleted", false, "Skip Secrets Manager secrets scheduled for deletion without warning about them.")
+	listCmd.MarkFlagsMutuallyExclusive("include-deleted", "exclude-deleted")
+	listRegions := listCmd.Flags().StringSlice("regions", nil, "List each of these regions concurrently, e.g. 'eu-west-1,us-east-1' (results are labelled by region).")
+	listCmd.Run = func(cmd *cobra.Command, args []string) {
+		defaultFormat := output.Env
+		if prompt.IsTerminal(os.Stdout) {
+			defaultFormat = output.Table
+		}
+
+		opts := outputOpts(logger, firstNonEmpty(*outputFormat, fileConf.Output), defaultFormat, *multiline, *format)
+		opts.NoHeader = *listNoHeader
+		opts.NamesOnly = *listNamesOnly
+		opts.Null = *listNull
+
+		sortKey := store.SortKey(*listSort)
+		check(logger, sortKey.Validate(), "invalid --sort", InvalidArgs)
+
+		filter := store.Filter{Prefix: *listPrefix, Contains: *listContains, IncludeUntagged: *listIncludeUntagged}
+		switch {
+		case *listIncludeDeleted:
+			filter.Deleted = store.IncludeDeleted
+		case *listExcludeDeleted:
+			filter.Deleted = store.ExcludeDeleted
+		}
+		if *listRegex != "" {
+			re, err := regexp.Compile(*listRegex)
+			check(logger, err, "invalid --regex", InvalidArgs)
+			filter.Regex = re
+		}
+
+		service := readService()
+
+		storeNames := fileConf.Stores()
+		if *storeName != "" {
+			storeNames = []string{*storeName}
+		}
+
+		if len(*listRegions) > 0 {
+			stores := newRegionalStores(*listRegions, storeNames)
+			spinner := progress.New(os.Stderr, prompt.IsTerminal(os.Stderr), "Listing parameters", 0)
+
+			items, err := fanOutRegions(*listRegions, func(region string) ([]store.Parameter, error) {
+				var items []store.Parameter
+				for _, name := range storeNames {
+					err := stores[region][name].ListPages(ctx, service, filter, func(page []store.Parameter) error {
+						spinner.Add(len(page))
+						page, err := transforms().Read(ctx, page)
+						items = append(items, page...)
+						return err
+					})
+					if err != nil {
+						return nil, fmt.Errorf("unable to list %s: %w", name, err)
+					}
+				}
+
+				return items, store.Sort(items, sortKey, *listReverse)
+			})
+			spinner.Stop()
+			check(logger, err, fmt.Sprintf("unable to list parameters for service '%s'", service.Prefix()), InternalError)
 
-			argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
-			conf, err := config.Read(argConf, config.DefaultFiles()...)
-			check(logger, err, "Unable to read config", InvalidArgs)
+			err = output.Write(os.Stdout, opts, items)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
 
-			ssm := store.NewSSM(logger, ssmClient(context.TODO(), logger, *profile))
+		// Line-based output is written as each page arrives unless it needs
+		// sorting first; otherwise everything is collected before writing.
+		stream := opts.Streamable() && !cmd.Flags().Changed("sort") && !*listReverse
+		spinner := progress.New(os.Stderr, prompt.IsTerminal(os.Stderr), "Listing parameters", 0)
 
-			service := store.Service{App: conf.App, Stack: conf.Stack, Stage: conf.Stage}
-			items, err := ssm.List(service)
-			check(logger, err, fmt.Sprintf("unable to list for service '%s'", service.Prefix()), 1)
+		var items []store.Parameter
+		err := newFallbackStore(storeNames).ListPages(ctx, service, filter, func(page []store.Parameter) error {
+			spinner.Add(len(page))
+			page, err := transforms().Read(ctx, page)
+			if err != nil {
+				return err
+			}
 
-			for _, item := range items {
-				logger.Infof(item.String())
+			if !stream {
+				items = append(items, page...)
+				return nil
 			}
-		},
+
+			spinner.Pause(func() { err = output.Write(os.Stdout, opts, page) })
+			return err
+		})
+		spinner.Stop()
+		check(logger, err, fmt.Sprintf("unable to list parameters for service '%s'", service.Prefix()), InternalError)
+
+		if stream {
+			return
+		}
+
+		err = store.Sort(items, sortKey, *listReverse)
+		check(logger, err, "unable to sort parameters", InvalidArgs)
+
+		err = output.Write(os.Stdout, opts, items)
+		check(logger, err, "unable to write output", InternalError)
 	}
 
 	setCmd := &cobra.Command{
-		Use:   "set",
+		Use:   "set [name] [value]",
 		Short: "Set parameter for a service",
-		Run: func(cmd *cobra.Command, args []string) {
-			name := cmd.Flags().String("name", "", "Name of parameter to set")
-			value := cmd.Flags().String("value", "", "Value of parameter to set")
-			cmd.MarkFlagRequired("name")
-			cmd.MarkFlagRequired("value")
-			cmd.ParseFlags(args)
+		Args:  cobra.MaximumNArgs(2),
+	}
+	setName := setCmd.Flags().String("name", "", "Name of parameter to set")
+	setValue := setCmd.Flags().String("value", "", "Value of parameter to set (note, this will appear in your shell history)")
+	setValueFile := setCmd.Flags().String("value-file", "", "Read the value of parameter to set from a file")
+	setValueStdin := setCmd.Flags().Bool("value-stdin", false, "Read the value of parameter to set from stdin")
+	setSecret := setCmd.Flags().Bool("secret", false, "Store the parameter as a secret (skips the prompt).")
+	setNotSecret := setCmd.Flags().Bool("not-secret", false, "Store the parameter as plain text (skips the prompt).")
+	setJSONKey := setCmd.Flags().String("json-key", "", "Update only this field of an existing JSON object parameter.")
+	setExpireAfter := setCmd.Flags().String("expire-after", "", "Delete the (SSM) parameter after this long, e.g. '90d' or '12h'.")
+	setNotifyBeforeExpiry := setCmd.Flags().String("notify-before-expiry", "", "With --expire-after, send an EventBridge notification this long before expiry, e.g. '14d'.")
+	setNotifyNoChange := setCmd.Flags().String("notify-no-change", "", "Send an EventBridge notification if the (SSM) parameter isn't changed for this long, e.g. '30d'.")
+	setTier := setCmd.Flags().String("tier", "", "SSM parameter tier: 'standard', 'advanced' or 'intelligent' (defaults to standard, or advanced for values over 4KB).")
+	setVerify := setCmd.Flags().Bool("verify", false, "Read the value back after writing it, failing if it can't be read (e.g. for lack of KMS permissions) or doesn't match.")
+	setVerifyRole := setCmd.Flags().String("verify-role-arn", "", "With --verify, read back as this role (e.g. your service's read-only one) instead, assumed in place of --role-arn.")
+	setCmd.MarkFlagsMutuallyExclusive("value", "value-file", "value-stdin")
+	setCmd.MarkFlagsMutuallyExclusive("secret", "not-secret")
+	setCmd.Run = func(cmd *cobra.Command, args []string) {
+		name := nameArg(logger, args, *setName)
+		service := readService()
 
-			argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
-			conf, err := config.Read(argConf, config.DefaultFiles()...)
-			check(logger, err, "Unable to read config", InvalidArgs)
+		value, err := readValue(cmd, args, *setValue, *setValueFile, *setValueStdin)
+		check(logger, err, "Unable to read value", InvalidArgs)
 
-			ssm := store.NewSSM(logger, ssmClient(context.TODO(), logger, *profile))
-			service := store.Service{App: conf.App, Stack: conf.Stack, Stage: conf.Stage}
+		setOpts := store.SetOptions{Tier: store.Tier(*setTier)}
+		check(logger, setOpts.Tier.Validate(), "invalid --tier", InvalidArgs)
 
-			isSecret := askYesNo("Is this parameter a secret?")
+		policyFlags := []struct {
+			value string
+			dest  *time.Duration
+		}{
+			{*setExpireAfter, &setOpts.Policies.ExpireAfter},
+			{*setNotifyBeforeExpiry, &setOpts.Policies.NotifyBeforeExpiry},
+			{*setNotifyNoChange, &setOpts.Policies.NotifyNoChange},
+		}
 
-			err = ssm.Set(service, *name, *value, isSecret)
-			check(logger, err, fmt.Sprintf("unable to set '%s=%s' for service '%s'", *name, *value, service.Prefix()), 1)
-		},
+		for _, f := range policyFlags {
+			if f.value != "" {
+				*f.dest, err = store.ParseAge(f.value)
+				check(logger, err, "invalid policy duration", InvalidArgs)
+			}
+		}
+
+		stName := firstNonEmpty(*storeName, fileConf.StoreFor(name))
+		preflight(stName, store.OpSet, service, name)
+		st := newStore(stName)
+
+		isSecret := *setSecret
+		askSecret := !*setSecret && !*setNotSecret
+
+		if *setJSONKey != "" {
+			existing, err := st.Get(ctx, service, name)
+			check(logger, err, fmt.Sprintf("unable to get %s for service '%s'", name, service.Prefix()), InternalError)
+
+			transformed, err := transforms().Read(ctx, []store.Parameter{existing})
+			check(logger, err, "unable to transform parameter", InternalError)
+			if len(transformed) != 1 {
+				check(logger, fmt.Errorf("'%s' is transformed into %d parameters", name, len(transformed)), "--json-key can't be used", InvalidArgs)
+			}
+
+			value, err = store.SetJSONField(transformed[0].Value, *setJSONKey, value)
+			check(logger, err, fmt.Sprintf("unable to set JSON key '%s'", *setJSONKey), InternalError)
+
+			if askSecret {
+				isSecret, askSecret = existing.IsSecret, false
+			}
+		}
+
+		if askSecret {
+			choice, err := newPrompter(*nonInteractive).Select("Is this parameter a secret?", []string{"Yes", "No"}, 0)
+			check(logger, err, "unable to determine whether parameter is a secret (use --secret or --not-secret)", InvalidArgs)
+			isSecret = choice == 0
+		}
+
+		// The value as stored, after any transforms.
+		value, err = transforms().Write(ctx, service, name, value)
+		check(logger, err, "unable to transform value", InvalidArgs)
+
+		err = st.Set(ctx, service, name, value, isSecret, setOpts)
+		check(logger, err, fmt.Sprintf("unable to set '%s' for service '%s'", name, service.Prefix()), InternalError)
+		publish(events.NewChangeEvent(events.Set, service, stName, name))
+
+		if *setVerify || *setVerifyRole != "" {
+			reader := st
+			if *setVerifyRole != "" {
+				opts := awsOpts(stName, "")
+				opts.RoleARN, opts.ExternalID = *setVerifyRole, ""
+				reader = newStoreWith(stName, opts)
+			}
+
+			err = store.Verify(ctx, reader, service, name, value, verifyAttempts, verifyDelay)
+			check(logger, err, "verification failed", InternalError)
+			logger.Infof("Verified '%s' reads back as written.", name)
+		}
 	}
 
 	deleteCmd := &cobra.Command{
-		Use:   "delete",
+		Use:   "delete [name]",
 		Short: "Delete parameter for a service",
-		Run: func(cmd *cobra.Command, args []string) {
-			name := cmd.Flags().String("name", "", "Name of parameter to set")
-			cmd.MarkFlagRequired("name")
-			cmd.ParseFlags(args)
+		Args:  cobra.MaximumNArgs(1),
+	}
+	deleteName := deleteCmd.Flags().String("name", "", "Name of parameter to delete")
+	deleteWithoutRecovery := deleteCmd.Flags().Bool("without-recovery", false, "Delete a Secrets Manager secret immediately, rather than after the 30 day recovery window.")
+	deleteCmd.Run = func(cmd *cobra.Command, args []string) {
+		name := nameArg(logger, args, *deleteName)
+		service := readService()
 
-			argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
-			conf, err := config.Read(argConf, config.DefaultFiles()...)
-			check(logger, err, "Unable to read config", InvalidArgs)
+		if !*yes {
+			ok, err := confirmDestructive(newPrompter(*nonInteractive), service.Stage, fmt.Sprintf("Are you sure you want to delete '%s' from %s?", name, service.Stage), name)
+			check(logger, err, "unable to confirm delete (use --yes to skip confirmation)", InvalidArgs)
 
-			ok := askYesNo(fmt.Sprintf("Are you sure you want to delete '%s'?", *name))
 			if !ok {
 				logger.Infof("Config item '%s' has NOT been deleted.", name)
 				return
 			}
+		}
+
+		stName := firstNonEmpty(*storeName, fileConf.StoreFor(name))
+		preflight(stName, store.OpDelete, service, name)
+		st := newStore(stName)
+		if *deleteWithoutRecovery {
+			sm, ok := st.(store.SecretsManager)
+			if !ok {
+				check(logger, fmt.Errorf("'%s' is in the %s store", name, stName), "--without-recovery is only supported by the secretsmanager store", InvalidArgs)
+			}
+			st = sm.WithoutRecovery()
+		}
+
+		err := st.Delete(ctx, service, name)
+		check(logger, err, fmt.Sprintf("unable to delete '%s' for service '%s'", name, service.Prefix()), InternalError)
+		publish(events.NewChangeEvent(events.Delete, service, stName, name))
+	}
+
+	historyCmd := &cobra.Command{
+		Use:   "history [name]",
+		Short: "Show the versions of an SSM parameter, with their labels",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	historyName := historyCmd.Flags().String("name", "", "Name of parameter")
+	historyTrail := historyCmd.Flags().Bool("cloudtrail", false, "Look up who made each change (principal and source IP) in CloudTrail. Only covers the last 90 days.")
+	historyCmd.Run = func(cmd *cobra.Command, args []string) {
+		name := nameArg(logger, args, *historyName)
+		service := readService()
+
+		versions, err := ssmStore(name, "history").ListVersions(ctx, service, name)
+		check(logger, err, fmt.Sprintf("unable to get history of '%s' for service '%s'", name, service.Prefix()), InternalError)
+
+		if *historyTrail {
+			if start, end, ok := trailWindow(versions, time.Now()); ok {
+				cfg, err := loadAWSConfig(ctx, "ssm", "")
+				check(logger, err, "unable to load AWS config", InternalError)
+
+				fullName := service.Prefix() + "/" + name
+				trail, truncated, err := awsclient.LookupEvents(ctx, cfg, fullName, "PutParameter", start, end)
+				check(logger, err, "unable to get CloudTrail history", InternalError)
+				if truncated {
+					logger.Warnf("Only the newest %d pages of CloudTrail events for '%s' were read, so older versions may have no principal.", awsclient.MaxTrailPages, name)
+				}
+
+				annotateVersions(versions, trail, fullName)
+			} else {
+				logger.Warnf("No versions of '%s' are recent enough to be in CloudTrail.", name)
+			}
+		}
+
+		opts := outputOpts(logger, firstNonEmpty(*outputFormat, fileConf.Output), output.Table, *multiline, "")
+		err = output.WriteHistory(os.Stdout, opts, versions)
+		check(logger, err, "unable to write output", InternalError)
+	}
+
+	labelCmd := &cobra.Command{
+		Use:   "label [name] [label...]",
+		Short: "Attach labels to a version of an SSM parameter (moving them from any other version)",
+		Args:  cobra.MinimumNArgs(2),
+	}
+	labelVersion := labelCmd.Flags().Int64("version", 0, "Version to label (defaults to the latest).")
+	labelCmd.Run = func(cmd *cobra.Command, args []string) {
+		name, labels := args[0], args[1:]
+		service := readService()
+
+		preflight("ssm", store.OpLabel, service, name)
+		err := ssmStore(name, "labelling").Label(ctx, service, name, *labelVersion, labels)
+		check(logger, err, fmt.Sprintf("unable to label '%s' for service '%s'", name, service.Prefix()), InternalError)
+
+		e := events.NewChangeEvent(events.Label, service, "ssm", name)
+		e.Labels, e.Version = labels, *labelVersion
+		publish(e)
+
+		logger.Infof("Labelled '%s' with %s.", name, strings.Join(labels, ", "))
+	}
+
+	unlabelCmd := &cobra.Command{
+		Use:   "unlabel [name] [label...]",
+		Short: "Remove labels from a version of an SSM parameter",
+		Args:  cobra.MinimumNArgs(2),
+	}
+	unlabelVersion := unlabelCmd.Flags().Int64("version", 0, "Version to remove the labels from (see 'history').")
+	_ = unlabelCmd.MarkFlagRequired("version")
+	unlabelCmd.Run = func(cmd *cobra.Command, args []string) {
+		name, labels := args[0], args[1:]
+		service := readService()
+
+		preflight("ssm", store.OpUnlabel, service, name)
+		err := ssmStore(name, "labelling").Unlabel(ctx, service, name, *unlabelVersion, labels)
+		check(logger, err, fmt.Sprintf("unable to unlabel '%s' for service '%s'", name, service.Prefix()), InternalError)
+
+		e := events.NewChangeEvent(events.Unlabel, service, "ssm", name)
+		e.Labels, e.Version = labels, *unlabelVersion
+		publish(e)
+
+		logger.Infof("Removed %s from version %d of '%s'.", strings.Join(labels, ", "), *unlabelVersion, name)
+	}
+
+	replicasCmd := &cobra.Command{
+		Use:   "replicas [name]",
+		Short: "Show the per-region replication status of a Secrets Manager secret",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	replicasName := replicasCmd.Flags().String("name", "", "Name of secret")
+	replicasCmd.Run = func(cmd *cobra.Command, args []string) {
+		name := nameArg(logger, args, *replicasName)
+		service := readService()
+
+		st := secretsManagerStore("replicas")
+
+		replicas, err := st.Replicas(ctx, service, name)
+		check(logger, err, fmt.Sprintf("unable to describe '%s' for service '%s'", name, service.Prefix()), InternalError)
+
+		opts := outputOpts(logger, firstNonEmpty(*outputFormat, fileConf.Output), output.Table, *multiline, "")
+		err = output.WriteReplicas(os.Stdout, opts, replicas)
+		check(logger, err, "unable to write output", InternalError)
+	}
+
+	promoteReplicaCmd := &cobra.Command{
+		Use:   "promote-replica [name]",
+		Short: "Promote the replica of a Secrets Manager secret in --region to a standalone secret, e.g. for regional failover",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	promoteReplicaName := promoteReplicaCmd.Flags().String("name", "", "Name of secret")
+	promoteReplicaCmd.Run = func(cmd *cobra.Command, args []string) {
+		name := nameArg(logger, args, *promoteReplicaName)
+		service := readService()
+
+		// The replica is promoted from its own region, so that must be
+		// given explicitly rather than defaulting to the primary's.
+		if *region == "" {
+			check(logger, errors.New("--region is required (the region of the replica to promote)"), "invalid arguments", InvalidArgs)
+		}
+
+		if !*yes {
+			question := fmt.Sprintf("Are you sure you want to stop replicating '%s' to %s? The replica will no longer be updated from the primary.", name, *region)
+			ok, err := confirmDestructive(newPrompter(*nonInteractive), service.Stage, question, name)
+			check(logger, err, "unable to confirm promotion (use --yes to skip confirmation)", InvalidArgs)
+
+			if !ok {
+				logger.Infof("Replica of '%s' has NOT been promoted.", name)
+				return
+			}
+		}
+
+		preflight("secretsmanager", store.OpPromoteReplica, service, name)
+		st := secretsManagerStore("promote-replica")
+
+		err := st.PromoteReplica(ctx, service, name)
+		check(logger, err, fmt.Sprintf("unable to promote replica of '%s' in %s", name, *region), InternalError)
+
+		e := events.NewChangeEvent(events.PromoteReplica, service, "secretsmanager", name)
+		e.Region = *region
+		publish(e)
+
+		logger.Infof("Promoted the replica of '%s' in %s to a standalone secret.", name, *region)
+	}
+
+	backfillTagsCmd := &cobra.Command{
+		Use:   "backfill-tags",
+		Short: "Tag all existing parameters for a service (with App, Stack, Stage and any configured tags)",
+		Run: func(cmd *cobra.Command, args []string) {
+			service := readService()
+
+			storeNames := fileConf.Stores()
+			if *storeName != "" {
+				storeNames = []string{*storeName}
+			}
+
+			spinner := progress.New(os.Stderr, prompt.IsTerminal(os.Stderr), "Tagging parameters", 0)
+
+			// Every parameter of the service is tagged, so the check is
+			// against all of them.
+			for _, name := range storeNames {
+				preflight(name, store.OpTag, service, "*")
+			}
+
+			count := 0
+			for _, name := range storeNames {
+				st := newStore(name)
+				err := st.ListPages(ctx, service, store.Filter{IncludeUntagged: true}, func(page []store.Parameter) error {
+					for _, item := range page {
+						// Secrets named otherwise (e.g. by CDK) are only
+						// found by their tags, so are already tagged.
+						if !strings.HasPrefix(item.Name, service.Prefix()+"/") {
+							continue
+						}
+
+						if err := st.Tag(ctx, service, item.RelativeName()); err != nil {
+							return fmt.Errorf("unable to tag '%s': %w", item.RelativeName(), err)
+						}
+						count++
+					}
 
-			ssm := store.NewSSM(logger, ssmClient(context.TODO(), logger, *profile))
-			service := store.Service{App: conf.App, Stack: conf.Stack, Stage: conf.Stage}
+					spinner.Add(len(page))
+					return nil
+				})
 
-			err = ssm.Delete(service, *name)
-			check(logger, err, fmt.Sprintf("unable to delete '%s' for service '%s'", *name, service.Prefix()), 1)
+				if err != nil {
+					spinner.Stop()
+				}
+				check(logger, err, fmt.Sprintf("unable to tag %s parameters for service '%s'", name, service.Prefix()), InternalError)
+			}
+			spinner.Stop()
+
+			logger.Infof("Tagged %d parameter(s).", count)
 		},
 	}
 
 	setConfig := &cobra.Command{
 		Use:   "set-local-config",
 		Short: "Set local config (app, stack, stage) for a service to automatically set these in the future",
+	}
+	setConfigForce := setConfig.Flags().Bool("force", false, "Overwrite an existing local config's app, stack and stage without confirmation.")
+	setConfig.Run = func(cmd *cobra.Command, args []string) {
+		argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
+		conf, err := config.Read(argConf) // note, don't check existing files
+
+		// Only prompt for whatever wasn't given or inferred from the repo
+		// (usually just the stage).
+		if err != nil {
+			p := newPrompter(*nonInteractive)
+			defaults := config.GitDefaults()
+
+			ask := func(label string, value string, defaultValue string) string {
+				if value != "" {
+					return value
+				}
+
+				v, err := p.Input(label, defaultValue, validatePathSegment)
+				check(logger, err, fmt.Sprintf("unable to read %s (pass --app, --stack and --stage)", strings.ToLower(label)), InvalidArgs)
+				return v
+			}
+
+			conf = config.Config{
+				App:   ask("App", conf.App, defaults.App),
+				Stack: ask("Stack", conf.Stack, defaults.Stack),
+				Stage: ask("Stage", conf.Stage, defaults.Stage),
+			}
+		}
+
+		conf.Stage, err = fileConf.NormaliseStage(conf.Stage)
+		check(logger, err, "invalid stage", InvalidArgs)
+
+		// Changing the service of an existing config is confirmed, as it may
+		// well be a mistake.
+		path := config.LocalPath()
+		if existing, err := config.ReadFile(path); err == nil && !*setConfigForce && !*yes {
+			changed := existing.App != "" && existing.App != conf.App ||
+				existing.Stack != "" && existing.Stack != conf.Stack ||
+				existing.Stage != "" && existing.Stage != conf.Stage
+
+			if changed {
+				question := fmt.Sprintf("Overwrite app/stack/stage in %s (currently %s/%s/%s)?", path, existing.App, existing.Stack, existing.Stage)
+				ok, err := newPrompter(*nonInteractive).YesNo(question)
+				check(logger, err, "unable to confirm overwrite (use --force)", InvalidArgs)
+
+				if !ok {
+					logger.Infof("Local config has NOT been changed.")
+					return
+				}
+			}
+		}
+
+		path, err = config.Write(conf)
+		check(logger, err, "unable to write local config", InternalError)
+
+		fmt.Fprintln(os.Stdout, path)
+	}
+
+	rotationCmd := &cobra.Command{
+		Use:   "rotation",
+		Short: "Manage rotation of Secrets Manager secrets",
+	}
+
+	rotationDeployCmd := &cobra.Command{
+		Use:   "deploy [name]",
+		Short: "Deploy (via CloudFormation) one of AWS's standard rotation functions for a secret, and turn rotation on",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	rotationName := rotationDeployCmd.Flags().String("name", "", "Name of secret to rotate")
+	rotationType := rotationDeployCmd.Flags().String("type", "", "Rotation function: 'rds-postgres' or 'generic' (a random password, for secrets with nothing else to update).")
+	rotationSchedule := rotationDeployCmd.Flags().String("schedule", "rate(30 days)", "How often to rotate, as a 'rate(...)' or 'cron(...)' expression.")
+	rotationSubnets := rotationDeployCmd.Flags().StringSlice("subnet-ids", nil, "For rds-postgres, subnets from which the function can reach the database.")
+	rotationSecurityGroups := rotationDeployCmd.Flags().StringSlice("security-group-ids", nil, "For rds-postgres, security groups for the function that allow it to reach the database.")
+	rotationPrint := rotationDeployCmd.Flags().Bool("print", false, "Print the CloudFormation template rather than deploying it.")
+	_ = rotationDeployCmd.MarkFlagRequired("type")
+	rotationDeployCmd.Run = func(cmd *cobra.Command, args []string) {
+		name := nameArg(logger, args, *rotationName)
+		service := readService()
+		secretID := service.Prefix() + "/" + name
+
+		tmpl, err := rotation.Template(rotation.Options{
+			Type:             rotation.Type(*rotationType),
+			SecretID:         secretID,
+			Schedule:         *rotationSchedule,
+			SubnetIDs:        *rotationSubnets,
+			SecurityGroupIDs: *rotationSecurityGroups,
+		})
+		check(logger, err, "invalid rotation options", InvalidArgs)
+
+		if *rotationPrint {
+			_, err = fmt.Fprintln(os.Stdout, string(tmpl))
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		// Rotation starts as soon as it is turned on, so the value changes.
+		if !*yes {
+			ok, err := confirmDestructive(newPrompter(*nonInteractive), service.Stage, fmt.Sprintf("Deploying rotation for '%s' will rotate it immediately. Continue?", name), name)
+			check(logger, err, "unable to confirm rotation (use --yes to skip confirmation)", InvalidArgs)
+
+			if !ok {
+				logger.Infof("Rotation for '%s' has NOT been deployed.", name)
+				return
+			}
+		}
+
+		// The template is deployed with the AWS CLI, using the same
+		// credentials (after any roles, MFA, etc.) and region as the store.
+		cfg, err := loadAWSConfig(ctx, "secretsmanager", "")
+		check(logger, err, "unable to load AWS config", InternalError)
+		creds, err := cfg.Credentials.Retrieve(ctx)
+		check(logger, err, "unable to get AWS credentials", AccessDenied)
+
+		// The template holds secrets, and check exits without running
+		// deferred functions, so the file is removed before each check.
+		f, err := os.CreateTemp("", "devx-config-rotation-*.json")
+		check(logger, err, "unable to write template", InternalError)
+		_, err = f.Write(tmpl)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+		check(logger, err, "unable to write template", InternalError)
+
+		stackName := rotation.StackName(secretID)
+		c := exec.Command("aws", "cloudformation", "deploy",
+			"--template-file", f.Name(),
+			"--stack-name", stackName,
+			"--capabilities", "CAPABILITY_IAM", "CAPABILITY_AUTO_EXPAND",
+			"--tags", "App="+service.App, "Stack="+service.Stack, "Stage="+service.Stage,
+		)
+		// Credentials in the environment take precedence over any profile.
+		c.Env = append(os.Environ(),
+			"AWS_ACCESS_KEY_ID="+creds.AccessKeyID,
+			"AWS_SECRET_ACCESS_KEY="+creds.SecretAccessKey,
+			"AWS_REGION="+cfg.Region,
+		)
+		if creds.SessionToken != "" {
+			c.Env = append(c.Env, "AWS_SESSION_TOKEN="+creds.SessionToken)
+		}
+		c.Stdout, c.Stderr = os.Stderr, os.Stderr // stdout is for data
+
+		logger.Infof("Deploying CloudFormation stack '%s'...", stackName)
+		err = c.Run()
+		_ = os.Remove(f.Name())
+		check(logger, err, fmt.Sprintf("unable to deploy stack '%s' (is the AWS CLI installed?)", stackName), InternalError)
+
+		logger.Infof("Rotation is on for '%s' (%s).", name, *rotationSchedule)
+	}
+	rotationCmd.AddCommand(rotationDeployCmd)
+
+	k8sCmd := &cobra.Command{
+		Use:   "k8s",
+		Short: "Use a service's parameters in Kubernetes",
+	}
+
+	k8sGenerateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a Kubernetes ConfigMap and Secret of the service's parameters (keyed as for env output, e.g. 'db_password')",
+		Args:  cobra.NoArgs,
+	}
+	k8sNamespace := k8sGenerateCmd.Flags().String("namespace", "", "Namespace of the ConfigMap and Secret (defaults to that of the kubectl context).")
+	k8sName := k8sGenerateCmd.Flags().String("name", "", "Name of the ConfigMap and Secret (defaults to the app).")
+	k8sSecretsOnly := k8sGenerateCmd.Flags().Bool("secrets-only", false, "Only generate the Secret, of secret parameters.")
+	k8sApply := k8sGenerateCmd.Flags().Bool("apply", false, "Apply the manifests with 'kubectl apply' rather than printing them.")
+	k8sGenerateCmd.Run = func(cmd *cobra.Command, args []string) {
+		service := readService()
+
+		params, err := transforms().Read(ctx, listAll(service))
+		check(logger, err, "unable to transform parameters", InternalError)
+
+		manifests, err := k8s.Manifests(service, params, k8s.Options{Name: *k8sName, Namespace: *k8sNamespace, SecretsOnly: *k8sSecretsOnly})
+		check(logger, err, "unable to generate manifests", InvalidArgs)
+
+		if !*k8sApply {
+			_, err = os.Stdout.Write(manifests)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		// Manifests are passed on stdin, so secrets never touch the disk.
+		c := exec.Command("kubectl", "apply", "-f", "-")
+		c.Stdin = bytes.NewReader(manifests)
+		c.Stdout, c.Stderr = os.Stderr, os.Stderr // stdout is for data
+
+		err = c.Run()
+		check(logger, err, "unable to apply manifests (is kubectl installed?)", InternalError)
+	}
+	k8sCmd.AddCommand(k8sGenerateCmd)
+
+	ecsCmd := &cobra.Command{
+		Use:   "ecs",
+		Short: "Use a service's parameters in ECS task definitions",
+	}
+
+	ecsGenerateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the environment and secrets (by ARN) of an ECS container definition from the service's parameters",
+		Args:  cobra.NoArgs,
+	}
+	ecsPatch := ecsGenerateCmd.Flags().String("patch", "", "Task definition (JSON) to merge the environment and secrets into, printing the result, e.g. 'task-def.json'.")
+	ecsContainer := ecsGenerateCmd.Flags().String("container", "", "With --patch, the container to merge into (needed if there are several).")
+	ecsGenerateCmd.Run = func(cmd *cobra.Command, args []string) {
+		service := readService()
+
+		// Transforms aren't applied: ECS reads secrets itself, as stored.
+		defs, err := ecs.Generate(listAll(service))
+		check(logger, err, "unable to generate container definitions", InvalidArgs)
+
+		var out []byte
+		if *ecsPatch == "" {
+			out, err = json.MarshalIndent(defs, "", "  ")
+			out = append(out, '\n')
+		} else {
+			taskDef, rerr := os.ReadFile(*ecsPatch)
+			check(logger, rerr, "unable to read task definition", InvalidArgs)
+
+			out, err = ecs.Patch(taskDef, defs, *ecsContainer)
+		}
+		check(logger, err, "unable to generate container definitions", InvalidArgs)
+
+		_, err = os.Stdout.Write(out)
+		check(logger, err, "unable to write output", InternalError)
+	}
+	ecsCmd.AddCommand(ecsGenerateCmd)
+
+	cdkCmd := &cobra.Command{
+		Use:   "cdk",
+		Short: "Reference a service's parameters from infrastructure code",
+	}
+
+	cdkSnippetCmd := &cobra.Command{
+		Use:   "snippet",
+		Short: "Print CDK code (or CloudFormation dynamic references) referencing each of the service's parameters by name",
+		Args:  cobra.NoArgs,
+	}
+	cdkLang := cdkSnippetCmd.Flags().String("lang", string(cdk.TypeScript), "Language: 'typescript' (CDK) or 'json' (CloudFormation dynamic references).")
+	cdkSnippetCmd.Run = func(cmd *cobra.Command, args []string) {
+		lang := cdk.Lang(*cdkLang)
+		check(logger, lang.Validate(), "invalid --lang", InvalidArgs)
+
+		service := readService()
+
+		snippet, err := cdk.Snippet(service, listAll(service), lang)
+		check(logger, err, "unable to generate snippet", InvalidArgs)
+
+		_, err = os.Stdout.Write(snippet)
+		check(logger, err, "unable to write output", InternalError)
+	}
+	cdkCmd.AddCommand(cdkSnippetCmd)
+
+	terraformCmd := &cobra.Command{
+		Use:   "terraform",
+		Short: "Bring a service's parameters under Terraform's management",
+	}
+
+	terraformExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print Terraform resources, with import blocks, for the service's existing parameters and secrets (without secret values)",
+		Args:  cobra.NoArgs,
+	}
+	terraformExportCmd.Run = func(cmd *cobra.Command, args []string) {
+		service := readService()
+
+		// Values are as stored, as Terraform will manage them as they are.
+		out, err := terraform.Export(service, listAll(service), terraform.Options{Tags: fileConf.Tags, KMSKey: fileConf.KMSKey})
+		check(logger, err, "unable to export parameters", InvalidArgs)
+
+		_, err = os.Stdout.Write(out)
+		check(logger, err, "unable to write output", InternalError)
+	}
+	terraformCmd.AddCommand(terraformExportCmd)
+
+	composeCmd := &cobra.Command{
+		Use:   "compose",
+		Short: "Use a service's parameters in Docker Compose",
+	}
+
+	composeEnvCmd := &cobra.Command{
+		Use:   "env",
+		Short: "Write the service's parameters as a Docker Compose env_file, e.g. for 'env_file: .env.compose'",
+		Args:  cobra.NoArgs,
+	}
+	composeOut := composeEnvCmd.Flags().String("out", "", "File to write, e.g. '.env.compose' (defaults to stdout).")
+	composeMask := composeEnvCmd.Flags().Bool("mask-secrets", false, "Replace the values of secrets, e.g. for a variant that is committed.")
+	composeMaskWith := composeEnvCmd.Flags().String("mask-with", "", "With --mask-secrets, the value to replace secrets with.")
+	composeEnvCmd.Run = func(cmd *cobra.Command, args []string) {
+		service := readService()
+
+		params, err := transforms().Read(ctx, listAll(service))
+		check(logger, err, "unable to transform parameters", InternalError)
+
+		if *composeMask {
+			for i := range params {
+				if params[i].IsSecret {
+					params[i].Value = *composeMaskWith
+				}
+			}
+		}
+
+		err = store.Sort(params, store.SortByName, false)
+		check(logger, err, "unable to sort parameters", InternalError)
+
+		var buf bytes.Buffer
+		err = output.Write(&buf, output.Options{Format: output.Compose, Multiline: output.Quote}, params)
+		check(logger, err, "unable to write output", InternalError)
+
+		if *composeOut == "" {
+			_, err = os.Stdout.Write(buf.Bytes())
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		// Unmasked files hold secrets, so are only readable by their owner.
+		err = os.WriteFile(*composeOut, buf.Bytes(), 0600)
+		check(logger, err, fmt.Sprintf("unable to write '%s'", *composeOut), InternalError)
+
+		logger.Infof("Wrote %d parameters to '%s'.", len(params), *composeOut)
+	}
+	composeCmd.AddCommand(composeEnvCmd)
+
+	systemdCmd := &cobra.Command{
+		Use:   "systemd",
+		Short: "Use a service's parameters in systemd units",
+	}
+
+	systemdSyncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Write the service's parameters to a systemd EnvironmentFile, reloading the unit if they've changed (e.g. from a timer on EC2)",
+		Args:  cobra.NoArgs,
+	}
+	systemdOut := systemdSyncCmd.Flags().String("out", "", "EnvironmentFile to write, e.g. '/etc/my-app/env'.")
+	systemdUnit := systemdSyncCmd.Flags().String("unit", "", "Unit to reload (or restart) with 'systemctl try-reload-or-restart' if the file changes, e.g. 'my-app.service'.")
+	systemdOwner := systemdSyncCmd.Flags().String("owner", "", "Owner of the file, as 'user' or 'user:group' (defaults to the current user).")
+	_ = systemdSyncCmd.MarkFlagRequired("out")
+	systemdSyncCmd.Run = func(cmd *cobra.Command, args []string) {
+		service := readService()
+
+		params, err := transforms().Read(ctx, listAll(service))
+		check(logger, err, "unable to transform parameters", InternalError)
+
+		data, err := systemd.EnvironmentFile(service, params)
+		check(logger, err, "unable to generate environment file", InvalidArgs)
+
+		changed, err := systemd.WriteFile(*systemdOut, data, *systemdOwner)
+		check(logger, err, "unable to write environment file", InternalError)
+
+		if !changed {
+			logger.Infof("'%s' is up to date.", *systemdOut)
+			return
+		}
+		logger.Infof("Wrote %d parameters to '%s'.", len(params), *systemdOut)
+
+		if *systemdUnit != "" {
+			err = systemd.Reload(*systemdUnit)
+			check(logger, err, "unable to reload unit", InternalError)
+
+			logger.Infof("Reloaded '%s'.", *systemdUnit)
+		}
+	}
+	systemdCmd.AddCommand(systemdSyncCmd)
+
+	kmsCmd := &cobra.Command{
+		Use:   "kms",
+		Short: "Manage the service's KMS key",
+	}
+
+	kmsInitCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create a customer managed KMS key with alias 'alias/devx/[stack]/[app]' (if there isn't one), and make it the local config's kmsKey",
+		Args:  cobra.NoArgs,
+	}
+	kmsServiceRole := kmsInitCmd.Flags().String("service-role-arn", "", "ARN of the service's role, to grant decrypt with the key.")
+	kmsInitCmd.Run = func(cmd *cobra.Command, args []string) {
+		service := readService()
+		alias := fmt.Sprintf("alias/devx/%s/%s", service.Stack, service.App)
+
+		cfg, err := loadAWSConfig(ctx, "ssm", "")
+		check(logger, err, "unable to load AWS config", InternalError)
+
+		keyARN, exists, err := awsclient.KeyForAlias(ctx, cfg, alias)
+		check(logger, err, "unable to look up KMS key", InternalError)
+
+		if exists {
+			logger.Infof("Using existing KMS key '%s' (%s).", alias, keyARN)
+		} else {
+			// Customer managed keys are charged for.
+			if !*yes {
+				ok, err := newPrompter(*nonInteractive).YesNo(fmt.Sprintf("Create a customer managed KMS key (which is charged for) with alias '%s'?", alias))
+				check(logger, err, "unable to confirm key creation (use --yes to skip confirmation)", InvalidArgs)
+
+				if !ok {
+					logger.Infof("KMS key has NOT been created.")
+					return
+				}
+			}
+
+			keyARN, err = awsclient.CreateKey(ctx, cfg, fmt.Sprintf("devx-config key for %s/%s", service.Stack, service.App), map[string]string{"App": service.App, "Stack": service.Stack})
+			check(logger, err, "unable to create KMS key", InternalError)
+
+			err = awsclient.CreateAlias(ctx, cfg, alias, keyARN)
+			check(logger, err, "unable to create KMS alias", InternalError)
+
+			logger.Infof("Created KMS key '%s' (%s).", alias, keyARN)
+		}
+
+		if *kmsServiceRole != "" {
+			err = awsclient.GrantDecrypt(ctx, cfg, keyARN, *kmsServiceRole)
+			check(logger, err, "unable to grant decrypt", InternalError)
+			logger.Infof("Granted '%s' decrypt with the key.", *kmsServiceRole)
+		} else {
+			logger.Warnf("No --service-role-arn given, so your service can only decrypt with the key if its IAM policy allows kms:Decrypt on it.")
+		}
+
+		path, err := config.WriteKMSKey(alias)
+		check(logger, err, "unable to write local config", InternalError)
+		logger.Infof("New SecureString parameters and secrets will be encrypted with '%s' (set in %s).", alias, path)
+	}
+	kmsCmd.AddCommand(kmsInitCmd)
+
+	driftCmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Compare a service's parameters against a manifest",
+	}
+
+	driftReportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show parameters that were added, removed or changed since a manifest (the JSON output of 'list') was saved",
+		Args:  cobra.NoArgs,
+	}
+	driftManifest := driftReportCmd.Flags().String("manifest", "", "Path of the manifest, e.g. from 'list --output=json > manifest.json' ('-' for stdin).")
+	driftPublish := driftReportCmd.Flags().Bool("publish-metric", false, "Publish the number of drifted parameters to CloudWatch, with App, Stack and Stage dimensions.")
+	driftNamespace := driftReportCmd.Flags().String("namespace", "devx-config", "CloudWatch namespace for --publish-metric.")
+	_ = driftReportCmd.MarkFlagRequired("manifest")
+	driftReportCmd.Run = func(cmd *cobra.Command, args []string) {
+		in := os.Stdin
+		if *driftManifest != "-" {
+			f, err := os.Open(*driftManifest)
+			check(logger, err, "unable to read manifest", InvalidArgs)
+			defer f.Close()
+			in = f
+		}
+
+		manifest, err := drift.ReadManifest(in)
+		check(logger, err, fmt.Sprintf("invalid manifest '%s'", *driftManifest), InvalidArgs)
+
+		service := readService()
+		changes := drift.Compare(manifest, listAll(service))
+
+		opts := outputOpts(logger, firstNonEmpty(*outputFormat, fileConf.Output), output.Table, *multiline, "")
+		err = output.WriteDrift(os.Stdout, opts, changes)
+		check(logger, err, "unable to write output", InternalError)
+
+		if *driftPublish {
+			cfg, err := loadAWSConfig(ctx, "ssm", "")
+			check(logger, err, "unable to load AWS config", InternalError)
+
+			dimensions := map[string]string{"App": service.App, "Stack": service.Stack, "Stage": service.Stage}
+			err = awsclient.PutMetric(ctx, cfg, *driftNamespace, "DriftedParameters", dimensions, float64(len(changes)))
+			check(logger, err, "unable to publish drift metric", InternalError)
+		}
+
+		if len(changes) == 0 {
+			logger.Infof("No drift from '%s'.", *driftManifest)
+		}
+	}
+	driftCmd.AddCommand(driftReportCmd)
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Snapshot a service's parameters (as JSON, like 'list --output=json'), to stdout or a KMS-encrypted object in S3",
+		Args:  cobra.NoArgs,
+	}
+	backupS3 := backupCmd.PersistentFlags().String("s3", "", "Where to keep snapshots, as s3://bucket/prefix. Each is written to [prefix]/[stage]/[stack]/[app]/[time].json.")
+	backupKMSKey := backupCmd.Flags().String("kms-key-id", "", "KMS key (ID, ARN or alias) to encrypt snapshots with. Defaults to the account's AWS managed key for S3.")
+	backupKeep := backupCmd.PersistentFlags().Int("keep", 0, "Number of the newest snapshots to keep, deleting older ones (when backing up, 0 keeps everything).")
+	backupSchedule := backupCmd.Flags().Bool("schedule", false, "Run unattended (e.g. from cron or an EventBridge rule): never prompt, and write a one-line JSON summary to stdout.")
+	// Prunes the service's snapshots, returning the number deleted.
+	pruneBackups := func(ctx context.Context, cfg aws.Config, bucket string, prefix string, service store.Service, keep int) int {
+		keys, err := awsclient.ListObjects(ctx, cfg, bucket, backup.Dir(prefix, service))
+		check(logger, err, "unable to list snapshots", InternalError)
+
+		expired := backup.Expired(keys, keep)
+		for _, key := range expired {
+			logger.Debugf("deleting snapshot s3://%s/%s", bucket, key)
+			err := awsclient.DeleteObject(ctx, cfg, bucket, key)
+			check(logger, err, "unable to delete snapshot", InternalError)
+		}
+
+		return len(expired)
+	}
+	backupCmd.Run = func(cmd *cobra.Command, args []string) {
+		if *backupSchedule {
+			*nonInteractive = true
+		}
+		if *backupKeep < 0 {
+			check(logger, errors.New("--keep must not be negative"), "invalid arguments", InvalidArgs)
+		}
+		if *backupS3 == "" && (*backupSchedule || *backupKeep > 0) {
+			check(logger, errors.New("--schedule and --keep need --s3"), "invalid arguments", InvalidArgs)
+		}
+
+		service := readService()
+		params := listAll(service)
+
+		var buf bytes.Buffer
+		err := output.Write(&buf, output.Options{Format: output.JSON}, params)
+		check(logger, err, "unable to write snapshot", InternalError)
+
+		if *backupS3 == "" {
+			_, err = os.Stdout.Write(buf.Bytes())
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		bucket, prefix, err := backup.ParseS3URL(*backupS3)
+		check(logger, err, "invalid --s3", InvalidArgs)
+
+		cfg, err := loadAWSConfig(ctx, "ssm", "")
+		check(logger, err, "unable to load AWS config", InternalError)
+
+		key := backup.Key(prefix, service, time.Now())
+		err = awsclient.PutObject(ctx, cfg, bucket, key, buf.Bytes(), *backupKMSKey)
+		check(logger, err, "unable to write snapshot", InternalError)
+
+		pruned := 0
+		if *backupKeep > 0 {
+			pruned = pruneBackups(ctx, cfg, bucket, prefix, service, *backupKeep)
+		}
+
+		if *backupSchedule {
+			summary := map[string]any{"bucket": bucket, "key": key, "parameters": len(params), "pruned": pruned}
+			err = json.NewEncoder(os.Stdout).Encode(summary)
+			check(logger, err, "unable to write output", InternalError)
+			return
+		}
+
+		logger.Infof("Backed up %d parameters to s3://%s/%s.", len(params), bucket, key)
+		if pruned > 0 {
+			logger.Infof("Deleted %d old snapshots.", pruned)
+		}
+	}
+
+	backupPruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete all but the newest --keep snapshots of a service in S3",
+		Args:  cobra.NoArgs,
+	}
+	backupPruneCmd.Run = func(cmd *cobra.Command, args []string) {
+		if *backupS3 == "" || *backupKeep <= 0 {
+			check(logger, errors.New("--s3 and a positive --keep are required"), "invalid arguments", InvalidArgs)
+		}
+
+		bucket, prefix, err := backup.ParseS3URL(*backupS3)
+		check(logger, err, "invalid --s3", InvalidArgs)
+
+		service := readService()
+
+		cfg, err := loadAWSConfig(ctx, "ssm", "")
+		check(logger, err, "unable to load AWS config", InternalError)
+
+		pruned := pruneBackups(ctx, cfg, bucket, prefix, service, *backupKeep)
+		logger.Infof("Deleted %d old snapshots of '%s'.", pruned, service.Prefix())
+	}
+	backupCmd.AddCommand(backupPruneCmd)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve parameters over a REST API with bearer token auth, e.g. as a sidecar",
+		Args:  cobra.NoArgs,
+	}
+	serveHTTP := serveCmd.Flags().String("http", "127.0.0.1:8080", "Address to serve the API on (only loopback addresses unless --listen-remote is given).")
+	serveListenRemote := serveCmd.Flags().Bool("listen-remote", false, "Allow --http to listen beyond the loopback interface (e.g. ':8080'), so other hosts or pods can read parameters over plain HTTP.")
+	serveTokenFile := serveCmd.Flags().String("token-file", "", "File containing the bearer token clients must send (defaults to DEVX_CONFIG_SERVE_TOKEN).")
+	serveCmd.Run = func(cmd *cobra.Command, args []string) {
+		if !isLoopback(*serveHTTP) && !*serveListenRemote {
+			check(logger, fmt.Errorf("'%s' isn't a loopback address, so secrets would be served over plain HTTP to the network", *serveHTTP), "invalid --http (pass --listen-remote to allow it)", InvalidArgs)
+		}
+
+		token := os.Getenv(serveTokenEnv)
+		if *serveTokenFile != "" {
+			data, err := os.ReadFile(*serveTokenFile)
+			check(logger, err, "unable to read --token-file", InvalidArgs)
+			token = strings.TrimSpace(string(data))
+		}
+
+		storeNames := fileConf.Stores()
+		if *storeName != "" {
+			storeNames = []string{*storeName}
+		}
+
+		stores := map[string]store.Store{}
+		for _, name := range storeNames {
+			stores[name] = newStore(name)
+		}
+
+		cfg, err := loadAWSConfig(ctx, "ssm", "")
+		check(logger, err, "unable to load AWS config", InternalError)
+
+		opts := server.Options{
+			Token:    token,
+			Stores:   stores,
+			StoreFor: func(name string) string { return firstNonEmpty(*storeName, fileConf.StoreFor(name)) },
+			Account:  fileConf.Account,
+			Ready: func(ctx context.Context) error {
+				_, err := cfg.Credentials.Retrieve(ctx)
+				return err
+			},
+			Logger: logger,
+		}
+		if fileConf.PrefixTemplate != "" {
+			opts.PrefixTemplate, err = store.ParsePrefixTemplate(fileConf.PrefixTemplate)
+			check(logger, err, "invalid prefix template", InvalidArgs)
+		}
+
+		handler, err := server.New(opts)
+		check(logger, err, fmt.Sprintf("unable to serve (set %s or --token-file)", serveTokenEnv), InvalidArgs)
+
+		// Stops gracefully on SIGINT or SIGTERM (e.g. when the pod is stopped).
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		srv := &http.Server{Addr: *serveHTTP, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+
+		logger.Infof("Serving on %s", *serveHTTP)
+		err = srv.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			check(logger, err, "unable to serve", InternalError)
+		}
+	}
+
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage config files",
+	}
+
+	lintCmd := &cobra.Command{
+		Use:         "lint [path...]",
+		Short:       "Check config files (by default, the user and local ones) for mistakes",
+		Annotations: map[string]string{"checksConfig": "true"},
 		Run: func(cmd *cobra.Command, args []string) {
-			cmd.ParseFlags(args)
+			paths := args
+			if len(paths) == 0 {
+				paths = config.FilePaths()
+			}
 
-			argConf := config.Config{App: *app, Stack: *stack, Stage: *stage}
-			conf, err := config.Read(argConf) // note, don't check existing files
+			if len(paths) == 0 {
+				logger.Infof("No config files found.")
+				return
+			}
 
-			if err != nil {
-				app := ask("App: ")
-				stack := ask("Stack: ")
-				stage := ask("Stage: ")
+			count := 0
+			for _, path := range paths {
+				problems, err := config.Lint(path)
+				check(logger, err, "unable to read config file", InvalidArgs)
+
+				for _, problem := range problems {
+					fmt.Fprintf(os.Stdout, "%s: %s\n", path, problem)
+				}
+				count += len(problems)
+			}
+
+			if count > 0 {
+				check(logger, fmt.Errorf("found %d problem(s)", count), "config lint failed", InvalidArgs)
+			}
+
+			logger.Infof("No problems found in %s.", strings.Join(paths, ", "))
+		},
+	}
 
-				conf = config.Config{App: app, Stack: stack, Stage: stage}
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [path]",
+		Short: "Move a config file (by default, the one in the working directory) to the project root",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := config.LocalPath()
+			if len(args) > 0 {
+				path = args[0]
 			}
 
-			config.Write(conf)
+			_, err := os.Stat(path)
+			check(logger, err, "unable to find config file", InvalidArgs)
+
+			to, err := config.Migrate(path)
+			check(logger, err, "unable to migrate config file", InternalError)
+
+			fmt.Fprintln(os.Stdout, to)
 		},
 	}
 
-	rootCmd.AddCommand(getCmd, listCmd, setCmd, deleteCmd, setConfig)
-	rootCmd.Execute()
+	configCmd.AddCommand(lintCmd, migrateCmd)
 
+	rootCmd.AddCommand(getCmd, listCmd, setCmd, deleteCmd, historyCmd, labelCmd, unlabelCmd, replicasCmd, promoteReplicaCmd, rotationCmd, kmsCmd, driftCmd, backupCmd, serveCmd, k8sCmd, ecsCmd, cdkCmd, terraformCmd, composeCmd, systemdCmd, backfillTagsCmd, setConfig, configCmd)
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(InvalidArgs)
+	}
 }
 
-func ask(question string) string {
-	fmt.Print(question)
+// Where serve reads its bearer token from, unless given --token-file. (Not a
+// flag, so it doesn't show up in process listings.)
+const serveTokenEnv = "DEVX_CONFIG_SERVE_TOKEN"
+
+// How many times, and how far apart, set --verify reads a value back. Secrets
+// Manager can take a moment to return a new value.
+const (
+	verifyAttempts = 5
+	verifyDelay    = time.Second
+)
 
-	got := ""
-	fmt.Scanln(&got)
+// Returns the name from the first positional arg or, failing that, --name.
+func nameArg(logger log.Logger, args []string, flag string) string {
+	if len(args) > 0 && flag != "" {
+		check(logger, errors.New("name given both as an argument and with --name"), "invalid arguments", InvalidArgs)
+	}
+
+	if len(args) > 0 {
+		return args[0]
+	}
 
-	return got
+	if flag == "" {
+		check(logger, errors.New("name is required (as an argument or with --name)"), "invalid arguments", InvalidArgs)
+	}
+
+	return flag
 }
 
-func askYesNo(question string) bool {
-	got := ask(question + "(y/n) ")
+// Returns the value from whichever of the second positional arg, --value,
+// --value-file or --value-stdin was used. File contents are used as-is; a
+// single trailing newline is trimmed from stdin as that is almost always an
+// artefact of echo or typing the value.
+func readValue(cmd *cobra.Command, args []string, value string, file string, stdin bool) (string, error) {
+	if len(args) > 1 && (cmd.Flags().Changed("value") || file != "" || stdin) {
+		return "", errors.New("value given both as an argument and with a flag")
+	}
 
-	switch got {
-	case "y":
-		return true
-	case "n":
-		return false
+	switch {
+	case len(args) > 1:
+		return args[1], nil
+	case cmd.Flags().Changed("value"):
+		return value, nil
+	case file != "":
+		data, err := os.ReadFile(file)
+		return string(data), err
+	case stdin:
+		data, err := io.ReadAll(os.Stdin)
+		return strings.TrimSuffix(string(data), "\n"), err
 	default:
-		fmt.Println("Response must be one of 'y', 'n'.")
-		return askYesNo(question)
+		return "", errors.New("a value is required (as an argument, or with one of --value, --value-file or --value-stdin)")
+	}
+}
+
+// --endpoint-url applies to all services. Otherwise, config file endpoints
+// for each store take precedence over the general one.
+func endpoints(flag string, conf config.Config) map[string]string {
+	if flag != "" {
+		return map[string]string{"": flag}
+	}
+
+	return map[string]string{
+		"":                       conf.EndpointURL,
+		ssm.ServiceID:            conf.StoreEndpoints["ssm"],
+		secretsmanager.ServiceID: conf.StoreEndpoints["secretsmanager"],
+	}
+}
+
+// Colour is only used when writing to a terminal, and never when NO_COLOR is
+// set (see https://no-color.org).
+func outputOpts(logger log.Logger, format string, defaultFormat output.Format, multiline string, tmpl string) output.Options {
+	opts := output.Options{
+		Format:    output.Format(format),
+		Multiline: output.Multiline(multiline),
+		Colour:    prompt.IsTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+	}
+
+	if format == "" {
+		opts.Format = defaultFormat
 	}
+
+	if tmpl != "" {
+		t, err := template.New("format").Parse(tmpl)
+		check(logger, err, "invalid --format template", InvalidArgs)
+		opts.Template = t
+	}
+
+	check(logger, opts.Validate(), "invalid output options", InvalidArgs)
+	return opts
+}
+
+// Destructive operations against PROD require typing confirmation back rather
+// than a bare y/n, as the latter is too easy to fat-finger.
+func confirmDestructive(p prompt.Prompter, stage string, question string, confirmation string) (bool, error) {
+	if strings.EqualFold(stage, "PROD") {
+		return p.TypeToConfirm(question, confirmation)
+	}
+
+	return p.YesNo(question)
 }
 
-func ssmClient(ctx context.Context, logger log.Logger, profile string) *ssm.Client {
-	cfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithSharedConfigProfile(profile), awsConfig.WithRegion("eu-west-1"))
-	check(logger, err, "unable to load default config", 1)
-	return ssm.NewFromConfig(cfg)
+// App, stack and stage each form a segment of parameter paths.
+func validatePathSegment(s string) error {
+	if s == "" {
+		return errors.New("must not be empty")
+	}
+
+	if strings.ContainsAny(s, "/ ") {
+		return errors.New("must not contain '/' or spaces")
+	}
+
+	return nil
 }
 
-func readBoolFlag(args []string, name string, usage string) bool {
-	fs := flag.NewFlagSet("", flag.ContinueOnError)
-	fs.Usage = func() {} // silence errors
-	got := fs.Bool(name, false, usage)
-	fs.Parse(args)
-	return *got
+// Prompts are only allowed when stdin is a terminal and the user hasn't opted
+// out with --non-interactive. They are written to stderr, as with logs.
+func newPrompter(nonInteractive bool) prompt.Prompter {
+	return prompt.New(os.Stdin, os.Stderr, !nonInteractive && prompt.IsTerminal(os.Stdin))
 }
 
+// Sets any flags not given on the command line from DEVX_CONFIG_* environment
+// variables, e.g. --log-level from DEVX_CONFIG_LOG_LEVEL.
+func bindEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		name := "DEVX_CONFIG_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		value, ok := os.LookupEnv(name)
+		if err != nil || f.Changed || !ok {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", name, setErr)
+		}
+	})
+
+	return err
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func firstNonZero(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+
+	return 0
+}
+
+// Set by --output=json (or output: json in a config file) once the config is
+// loaded, so that errors are also machine-readable.
+var jsonErrors bool
+
+// Logs and exits if err is non-nil. Errors that map to a specific exit code
+// (see exitCodeFor) use that in preference to exitCode. With --output=json the
+// error is written to stderr as a JSON object instead of being logged.
 func check(logger log.Logger, err error, msg string, exitCode int) {
-	if err != nil {
-		logger.Infof("%s; %v", msg, err)
-		os.Exit(exitCode)
+	if err == nil {
+		return
 	}
+
+	code := exitCodeFor(err, exitCode)
+	if !jsonErrors || writeJSONError(os.Stderr, err, msg, code) != nil {
+		logger.Errorf("%s; %v", msg, err)
+	}
+
+	os.Exit(code)
 }
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func writeCSV(w io.Writer, opts Options, params []store.Parameter) error {
+	out := csv.NewWriter(w)
+	regional := hasRegions(params)
+	deleted := hasDeleted(params)
+	shared := hasSourceAccounts(params)
+
+	if !opts.NoHeader {
+		header := []string{"key", "name", "value", "secret", "store", "type", "last_modified"}
+		if regional {
+			header = append(header, "region")
+		}
+		if deleted {
+			header = append(header, "deleted_date")
+		}
+		if shared {
+			header = append(header, "source_account")
+		}
+		out.Write(header)
+	}
+
+	for _, p := range params {
+		modified := ""
+		if !p.LastModified.IsZero() {
+			modified = p.LastModified.UTC().Format(time.RFC3339)
+		}
+
+		row := []string{p.Key(), p.Name, p.Value, strconv.FormatBool(p.IsSecret), p.Store, p.Type, modified}
+		if regional {
+			row = append(row, p.Region)
+		}
+		if deleted {
+			deletedDate := ""
+			if !p.DeletedDate.IsZero() {
+				deletedDate = p.DeletedDate.UTC().Format(time.RFC3339)
+			}
+			row = append(row, deletedDate)
+		}
+		if shared {
+			row = append(row, p.SourceAccount)
+		}
+		out.Write(row)
+	}
+
+	out.Flush()
+	return out.Error()
+}
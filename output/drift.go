@@ -0,0 +1,44 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/guardian/devx-config/drift"
+)
+
+type jsonChange struct {
+	Name   string `json:"name"`
+	Store  string `json:"store,omitempty"`
+	Status string `json:"status"`
+}
+
+// WriteDrift writes the parameters that have drifted from a manifest: a JSON
+// array for JSON output, otherwise an aligned table (without values).
+func WriteDrift(w io.Writer, opts Options, changes []drift.Change) error {
+	if opts.Format == JSON {
+		out := []jsonChange{}
+		for _, c := range changes {
+			out = append(out, jsonChange{Name: c.Name, Store: c.Store, Status: string(c.Status)})
+		}
+
+		return writeJSON(w, out)
+	}
+
+	rows := [][]string{}
+	if !opts.NoHeader {
+		rows = append(rows, []string{"NAME", "STORE", "STATUS"})
+	}
+
+	for _, c := range changes {
+		rows = append(rows, []string{c.Name, orDash(c.Store), string(c.Status)})
+	}
+
+	for _, line := range align(rows) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
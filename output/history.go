@@ -0,0 +1,91 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type jsonVersion struct {
+	Version      int64      `json:"version"`
+	Labels       []string   `json:"labels"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	ModifiedBy   string     `json:"modifiedBy,omitempty"`
+	Principal    string     `json:"principal,omitempty"`
+	SourceIP     string     `json:"sourceIp,omitempty"`
+}
+
+// WriteHistory writes the versions of a parameter: a JSON array for JSON
+// output, otherwise an aligned table. The table has principal and source IP
+// columns if any version has CloudTrail details.
+func WriteHistory(w io.Writer, opts Options, versions []store.Version) error {
+	if opts.Format == JSON {
+		out := []jsonVersion{}
+		for _, v := range versions {
+			j := jsonVersion{Version: v.Version, Labels: v.Labels, ModifiedBy: v.ModifiedBy, Principal: v.Principal, SourceIP: v.SourceIP}
+			if j.Labels == nil {
+				j.Labels = []string{}
+			}
+			if !v.LastModified.IsZero() {
+				j.LastModified = &v.LastModified
+			}
+			out = append(out, j)
+		}
+
+		return writeJSON(w, out)
+	}
+
+	trail := false
+	for _, v := range versions {
+		if v.Principal != "" || v.SourceIP != "" {
+			trail = true
+		}
+	}
+
+	rows := [][]string{}
+	if !opts.NoHeader {
+		header := []string{"VERSION", "LABELS", "LAST MODIFIED", "MODIFIED BY"}
+		if trail {
+			header = append(header, "PRINCIPAL", "SOURCE IP")
+		}
+		rows = append(rows, header)
+	}
+
+	for _, v := range versions {
+		labels := "-"
+		if len(v.Labels) > 0 {
+			labels = strings.Join(v.Labels, ",")
+		}
+
+		modified := "-"
+		if !v.LastModified.IsZero() {
+			modified = v.LastModified.Local().Format(time.RFC3339)
+		}
+
+		row := []string{strconv.FormatInt(v.Version, 10), labels, modified, v.ModifiedBy}
+		if trail {
+			row = append(row, orDash(v.Principal), orDash(v.SourceIP))
+		}
+		rows = append(rows, row)
+	}
+
+	for _, line := range align(rows) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,339 @@
+// Package output writes parameters in the formats supported by the CLI.
+package output
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type Format string
+
+const (
+	Env        Format = "env"        // k=v lines
+	JSON       Format = "json"       // values passed through as-is
+	Raw        Format = "raw"        // values only, exactly as stored
+	Table      Format = "table"      // aligned columns, without values
+	Export     Format = "export"     // POSIX shell 'export k='v'' lines, safe to eval
+	PowerShell Format = "powershell" // PowerShell '$env:k = 'v'' lines, safe to Invoke-Expression
+	CSV        Format = "csv"        // for spreadsheets, with a header row
+	Compose    Format = "compose"    // Docker Compose env_file lines, with values quoted literally
+)
+
+// How multi-line values are encoded in Env output.
+type Multiline string
+
+const (
+	Quote  Multiline = "quote"  // double-quoted, with newlines escaped
+	Base64 Multiline = "base64" // base64 encoded
+)
+
+type Options struct {
+	Format    Format
+	Multiline Multiline
+	NoHeader  bool // omit the header row from table and CSV output
+	Colour    bool // use ANSI colours in table output
+
+	NamesOnly bool // write only names (relative to the service), instead of Format
+	Null      bool // terminate names-only and raw output with NUL rather than newline, for 'xargs -0'
+
+	// If set, used instead of Format. Executed once per parameter (a
+	// store.Parameter, so methods such as Key are also available), with a
+	// newline appended.
+	Template *template.Template
+}
+
+func (o Options) Validate() error {
+	switch o.Format {
+	case Env, JSON, Raw, Table, Export, PowerShell, CSV, Compose:
+	default:
+		return fmt.Errorf("unsupported output format '%s' (must be one of 'env', 'export', 'powershell', 'compose', 'json', 'csv', 'raw', 'table')", o.Format)
+	}
+
+	switch o.Multiline {
+	case Quote, Base64:
+	default:
+		return fmt.Errorf("unsupported multi-line encoding '%s' (must be one of 'quote', 'base64')", o.Multiline)
+	}
+
+	return nil
+}
+
+// Streamable reports whether the format is line-based, so that parameters can
+// be written in batches as they arrive rather than all at once.
+func (o Options) Streamable() bool {
+	if o.NamesOnly || o.Template != nil {
+		return true
+	}
+
+	switch o.Format {
+	case Env, Export, PowerShell, Compose, Raw:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o Options) terminator() string {
+	if o.Null {
+		return "\x00"
+	}
+
+	return "\n"
+}
+
+type jsonParameter struct {
+	Key          string     `json:"key"`
+	Name         string     `json:"name"`
+	Value        string     `json:"value"`
+	Secret       bool       `json:"secret"`
+	Store        string     `json:"store,omitempty"`
+	Region       string     `json:"region,omitempty"`
+	Type         string     `json:"type,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	DeletedDate  *time.Time `json:"deletedDate,omitempty"`
+
+	SourceAccount string `json:"sourceAccount,omitempty"`
+}
+
+func asJSON(p store.Parameter) jsonParameter {
+	out := jsonParameter{Key: p.Key(), Name: p.Name, Value: p.Value, Secret: p.IsSecret, Store: p.Store, Region: p.Region, Type: p.Type, SourceAccount: p.SourceAccount}
+	if !p.LastModified.IsZero() {
+		out.LastModified = &p.LastModified
+	}
+	if !p.DeletedDate.IsZero() {
+		out.DeletedDate = &p.DeletedDate
+	}
+
+	return out
+}
+
+// Write writes params to w. JSON output is an array.
+func Write(w io.Writer, opts Options, params []store.Parameter) error {
+	if opts.NamesOnly {
+		for _, p := range params {
+			if _, err := io.WriteString(w, p.RelativeName()+opts.terminator()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if opts.Template != nil {
+		for _, p := range params {
+			if err := opts.Template.Execute(w, p); err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	switch opts.Format {
+	case JSON:
+		out := []jsonParameter{}
+		for _, p := range params {
+			out = append(out, asJSON(p))
+		}
+
+		return writeJSON(w, out)
+	case Table:
+		return writeTable(w, opts, params)
+	case CSV:
+		return writeCSV(w, opts, params)
+	case Export:
+		if err := checkVariableNames(params, opts.Format); err != nil {
+			return err
+		}
+		for i, p := range params {
+			if err := writeRegionComment(w, params, i); err != nil {
+				return err
+			}
+			if done, err := writeDeletedComment(w, p); done || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "export %s=%s\n", p.Key(), quotePOSIX(p.Value)); err != nil {
+				return err
+			}
+		}
+	case PowerShell:
+		if err := checkVariableNames(params, opts.Format); err != nil {
+			return err
+		}
+		for i, p := range params {
+			if err := writeRegionComment(w, params, i); err != nil {
+				return err
+			}
+			if done, err := writeDeletedComment(w, p); done || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "$env:%s = %s\n", p.Key(), quotePowerShell(p.Value)); err != nil {
+				return err
+			}
+		}
+	case Compose:
+		for i, p := range params {
+			if err := writeRegionComment(w, params, i); err != nil {
+				return err
+			}
+			if done, err := writeDeletedComment(w, p); done || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintln(w, composeEnv(p, opts.Multiline)); err != nil {
+				return err
+			}
+		}
+	case Raw:
+		for _, p := range params {
+			if !p.DeletedDate.IsZero() {
+				continue // no value
+			}
+			if _, err := io.WriteString(w, p.Value+opts.terminator()); err != nil {
+				return err
+			}
+		}
+	default:
+		for i, p := range params {
+			if err := writeRegionComment(w, params, i); err != nil {
+				return err
+			}
+			if done, err := writeDeletedComment(w, p); done || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintln(w, env(p, opts.Multiline)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteOne writes a single parameter to w. Unlike Write, JSON output is a
+// single object, and raw output has no trailing newline added, so that
+// values round-trip exactly (e.g. 'get --output=raw > cert.pem').
+func WriteOne(w io.Writer, opts Options, param store.Parameter) error {
+	if opts.Template != nil {
+		return Write(w, opts, []store.Parameter{param})
+	}
+
+	switch opts.Format {
+	case JSON:
+		return writeJSON(w, asJSON(param))
+	case Raw:
+		_, err := io.WriteString(w, param.Value)
+		return err
+	default:
+		return Write(w, opts, []store.Parameter{param})
+	}
+}
+
+// Parameters from several regions are labelled with a '# region' comment
+// (valid in dotenv files and both shells) before the first from each.
+func writeRegionComment(w io.Writer, params []store.Parameter, i int) error {
+	region := params[i].Region
+	if region == "" || (i > 0 && params[i-1].Region == region) {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "# %s\n", region)
+	return err
+}
+
+// Secrets scheduled for deletion have no value, so are written as a comment
+// rather than an (empty) assignment. Returns whether p was one.
+func writeDeletedComment(w io.Writer, p store.Parameter) (bool, error) {
+	if p.DeletedDate.IsZero() {
+		return false, nil
+	}
+
+	_, err := fmt.Fprintf(w, "# %s is scheduled for deletion (since %s)\n", p.Key(), p.DeletedDate.UTC().Format(time.RFC3339))
+	return true, err
+}
+
+func hasDeleted(params []store.Parameter) bool {
+	for _, p := range params {
+		if !p.DeletedDate.IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasSourceAccounts(params []store.Parameter) bool {
+	for _, p := range params {
+		if p.SourceAccount != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRegions(params []store.Parameter) bool {
+	for _, p := range params {
+		if p.Region != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func env(p store.Parameter, multiline Multiline) string {
+	if multiline == Base64 && strings.ContainsAny(p.Value, "\r\n") {
+		return p.Key() + "=" + base64.StdEncoding.EncodeToString([]byte(p.Value))
+	}
+
+	return p.String()
+}
+
+func composeEnv(p store.Parameter, multiline Multiline) string {
+	if multiline == Base64 && strings.ContainsAny(p.Value, "\r\n") {
+		return p.Key() + "=" + base64.StdEncoding.EncodeToString([]byte(p.Value))
+	}
+
+	return p.Key() + "=" + quoteCompose(p.Value)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Checks, before anything is written, that every key can be assigned as a
+// variable, so that the output can't half-evaluate.
+func checkVariableNames(params []store.Parameter, format Format) error {
+	for _, p := range params {
+		if !variableName.MatchString(p.Key()) {
+			return fmt.Errorf("unable to write '%s' as %s output: '%s' isn't a valid variable name", p.RelativeName(), format, p.Key())
+		}
+	}
+
+	return nil
+}
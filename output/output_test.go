@@ -0,0 +1,265 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/guardian/devx-config/drift"
+	"github.com/guardian/devx-config/store"
+)
+
+func TestWriteTable(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/db.password", Value: "secret", IsSecret: true, Store: "ssm", Type: "SecureString"},
+		{Service: service, Name: "/PROD/deploy/example/port", Value: "8080", Store: "ssm", Type: "String"},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, Options{Format: Table, Multiline: Quote}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `NAME         STORE  TYPE          SECRET  LAST MODIFIED
+db_password  ssm    SecureString  yes     -
+port         ssm    String        no      -
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/key", Value: "it's\n$multi line"},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, Options{Format: Export, Multiline: Quote}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "export key='it'\\''s\n$multi line'\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %q; want %q", got, want)
+	}
+}
+
+func TestWriteInvalidVariableName(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/port", Value: "8080"},
+		{Service: service, Name: "/PROD/deploy/example/api-key", Value: "secret"},
+	}
+
+	for _, format := range []Format{Export, PowerShell} {
+		var buf bytes.Buffer
+		if err := Write(&buf, Options{Format: format, Multiline: Quote}, params); err == nil {
+			t.Errorf("%s: expected an error for 'api-key'", format)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("%s: got %q; want nothing written", format, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Format: Env, Multiline: Quote}, params); err != nil {
+		t.Errorf("env: unexpected error: %v", err)
+	}
+}
+
+func TestWriteCompose(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/url", Value: "https://example.com/a,b=c"},
+		{Service: service, Name: "/PROD/deploy/example/greeting", Value: "hello $USER\nbye"},
+		{Service: service, Name: "/PROD/deploy/example/quoted", Value: `it's "$HOME"`},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, Options{Format: Compose, Multiline: Quote}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "url=https://example.com/a,b=c\n" +
+		"greeting='hello $USER\nbye'\n" +
+		`quoted="it's \"\$HOME\""` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %q; want %q", got, want)
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/port", Value: "8080", Type: "String"},
+	}
+
+	tmpl := template.Must(template.New("format").Parse("{{.Key}} ({{.Type}}): {{.Value}}"))
+
+	var buf bytes.Buffer
+	err := Write(&buf, Options{Template: tmpl}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "port (String): 8080\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %q; want %q", got, want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/greeting", Value: "hello, \"world\"", Store: "ssm", Type: "String"},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, Options{Format: CSV, NoHeader: true}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `greeting,/PROD/deploy/example/greeting,"hello, ""world""",false,ssm,String,` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %q; want %q", got, want)
+	}
+}
+
+func TestWriteEnvRegions(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/a", Value: "1", Region: "eu-west-1"},
+		{Service: service, Name: "/PROD/deploy/example/b", Value: "2", Region: "eu-west-1"},
+		{Service: service, Name: "/PROD/deploy/example/a", Value: "3", Region: "us-east-1"},
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, Options{Format: Env, Multiline: Quote}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# eu-west-1\na=1\nb=2\n# us-east-1\na=3\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got: %q; want %q", got, want)
+	}
+}
+
+func TestWriteReplicas(t *testing.T) {
+	replicas := []store.Replica{
+		{Region: "eu-west-1", Primary: true},
+		{Region: "us-east-1", Status: "Failed", Message: "Secret with this name already exists in this region"},
+	}
+
+	var buf bytes.Buffer
+	err := WriteReplicas(&buf, Options{Format: Table}, replicas)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `REGION     STATUS   LAST ACCESSED  MESSAGE
+eu-west-1  Primary  -
+us-east-1  Failed   -              Secret with this name already exists in this region
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	versions := []store.Version{
+		{Version: 1, ModifiedBy: "arn:aws:iam::123456789012:user/alice"},
+		{Version: 2, Labels: []string{"pinned", "stable"}},
+	}
+
+	var buf bytes.Buffer
+	err := WriteHistory(&buf, Options{Format: Table}, versions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `VERSION  LABELS         LAST MODIFIED  MODIFIED BY
+1        -              -              arn:aws:iam::123456789012:user/alice
+2        pinned,stable  -
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteHistoryWithCloudTrail(t *testing.T) {
+	versions := []store.Version{
+		{Version: 1},
+		{Version: 2, Principal: "arn:aws:sts::123456789012:assumed-role/developer/alice", SourceIP: "203.0.113.7"},
+	}
+
+	var buf bytes.Buffer
+	err := WriteHistory(&buf, Options{Format: Table}, versions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `VERSION  LABELS  LAST MODIFIED  MODIFIED BY  PRINCIPAL                                               SOURCE IP
+1        -       -                           -                                                       -
+2        -       -                           arn:aws:sts::123456789012:assumed-role/developer/alice  203.0.113.7
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteDrift(t *testing.T) {
+	changes := []drift.Change{
+		{Name: "/PROD/deploy/example/A", Store: "ssm", Status: drift.Added},
+		{Name: "/PROD/deploy/example/LONGER", Status: drift.Removed},
+	}
+
+	var buf bytes.Buffer
+	err := WriteDrift(&buf, Options{Format: Table}, changes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `NAME                         STORE  STATUS
+/PROD/deploy/example/A       ssm    added
+/PROD/deploy/example/LONGER  -      removed
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteDeleted(t *testing.T) {
+	service := store.Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	deleted := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/example/kept", Value: "v"},
+		{Service: service, Name: "/PROD/deploy/example/old", IsSecret: true, DeletedDate: deleted},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{Format: Env}, params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "kept=v\n# old is scheduled for deletion (since 2024-03-01T12:00:00Z)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, Options{Format: Raw}, params); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); got != "v\n" {
+		t.Errorf("raw: got %q; want only the value of the secret not scheduled for deletion", got)
+	}
+}
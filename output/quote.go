@@ -0,0 +1,41 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Quotes s for a POSIX shell. Single quotes disable all interpretation, so the
+// only character needing special treatment is the single quote itself, which
+// is written as a closing quote, an escaped quote (\') and an opening quote.
+// Newlines are preserved as-is.
+func quotePOSIX(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Quotes s for PowerShell. As with POSIX shells, single-quoted strings are
+// literal; an embedded single quote is escaped by doubling it.
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Shell and PowerShell variable names that can be assigned without quoting.
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var composeSafe = regexp.MustCompile(`^[-a-zA-Z0-9_./:@%+,=]*$`)
+
+// Quotes s for a Docker Compose env_file. Single-quoted values are literal
+// (including newlines), so are used unless s contains a single quote, in
+// which case it is double-quoted, with '\', '"', '$' and newlines escaped so
+// that Compose doesn't interpolate it.
+func quoteCompose(s string) string {
+	switch {
+	case composeSafe.MatchString(s):
+		return s
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'"
+	default:
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+		return `"` + r.Replace(s) + `"`
+	}
+}
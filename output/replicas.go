@@ -0,0 +1,61 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type jsonReplica struct {
+	Region       string     `json:"region"`
+	Primary      bool       `json:"primary"`
+	Status       string     `json:"status,omitempty"`
+	Message      string     `json:"message,omitempty"`
+	LastAccessed *time.Time `json:"lastAccessed,omitempty"`
+}
+
+// WriteReplicas writes the replication status of a secret: a JSON array for
+// JSON output, otherwise an aligned table.
+func WriteReplicas(w io.Writer, opts Options, replicas []store.Replica) error {
+	if opts.Format == JSON {
+		out := []jsonReplica{}
+		for _, r := range replicas {
+			j := jsonReplica{Region: r.Region, Primary: r.Primary, Status: r.Status, Message: r.Message}
+			if !r.LastAccessed.IsZero() {
+				j.LastAccessed = &r.LastAccessed
+			}
+			out = append(out, j)
+		}
+
+		return writeJSON(w, out)
+	}
+
+	rows := [][]string{}
+	if !opts.NoHeader {
+		rows = append(rows, []string{"REGION", "STATUS", "LAST ACCESSED", "MESSAGE"})
+	}
+
+	for _, r := range replicas {
+		status := r.Status
+		if r.Primary {
+			status = "Primary"
+		}
+
+		accessed := "-"
+		if !r.LastAccessed.IsZero() {
+			accessed = r.LastAccessed.Local().Format("2006-01-02")
+		}
+
+		rows = append(rows, []string{r.Region, status, accessed, r.Message})
+	}
+
+	for _, line := range align(rows) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
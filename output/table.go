@@ -0,0 +1,115 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+const (
+	colourReset  = "\033[0m"
+	colourYellow = "\033[33m"
+	colourBold   = "\033[1m"
+)
+
+// Writes params as a column-aligned table. Values are deliberately omitted so
+// that listing a service is safe to do on a shared screen.
+func writeTable(w io.Writer, opts Options, params []store.Parameter) error {
+	regional := hasRegions(params)
+	deleted := hasDeleted(params)
+	shared := hasSourceAccounts(params)
+
+	rows := [][]string{}
+	if !opts.NoHeader {
+		header := []string{"NAME", "STORE", "TYPE", "SECRET", "LAST MODIFIED"}
+		if regional {
+			header = append(header, "REGION")
+		}
+		if deleted {
+			header = append(header, "DELETION SCHEDULED")
+		}
+		if shared {
+			header = append(header, "SOURCE ACCOUNT")
+		}
+		rows = append(rows, header)
+	}
+
+	for _, p := range params {
+		modified := "-"
+		if !p.LastModified.IsZero() {
+			modified = p.LastModified.Local().Format(time.RFC3339)
+		}
+
+		secret := "no"
+		if p.IsSecret {
+			secret = "yes"
+		}
+
+		row := []string{p.Key(), p.Store, p.Type, secret, modified}
+		if regional {
+			row = append(row, p.Region)
+		}
+		if deleted {
+			deletedDate := "-"
+			if !p.DeletedDate.IsZero() {
+				deletedDate = p.DeletedDate.Local().Format(time.RFC3339)
+			}
+			row = append(row, deletedDate)
+		}
+		if shared {
+			row = append(row, orDash(p.SourceAccount))
+		}
+		rows = append(rows, row)
+	}
+
+	for i, line := range align(rows) {
+		colour := ""
+		switch {
+		case !opts.Colour:
+		case i == 0 && !opts.NoHeader:
+			colour = colourBold
+		case rows[i][3] == "yes":
+			colour = colourYellow
+		}
+
+		if colour != "" {
+			line = colour + line + colourReset
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pads each cell to the width of its column, returning one line per row.
+func align(rows [][]string) []string {
+	widths := []int{}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = cell + strings.Repeat(" ", widths[j]-len(cell))
+		}
+
+		lines[i] = strings.TrimRight(strings.Join(cells, "  "), " ")
+	}
+
+	return lines
+}
@@ -0,0 +1,408 @@
+// Package devxconfig lets Go services read (and write) their config the same
+// way the devx-config CLI does: the service is resolved from config files,
+// riff-raff.yaml or cdk.json, or the Lambda, ECS or EC2 environment, and
+// parameters are read from Parameter Store or Secrets Manager according to the
+// config file's routing. For example, at startup:
+//
+//	client, err := devxconfig.New(ctx, devxconfig.Options{})
+//	if err != nil {
+//		return err
+//	}
+//
+//	dbPassword, err := client.Get(ctx, "db/password")
+//
+// A Client is safe for concurrent use.
+package devxconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/config"
+	"github.com/guardian/devx-config/events"
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/store"
+	"github.com/guardian/devx-config/transform"
+)
+
+// Options are all optional, and correspond to the CLI's global flags.
+// Anything not given is taken from config files (or inferred) as for the CLI.
+type Options struct {
+	App, Stack, Stage string
+
+	Service       string // workspace (or registry) service, as --service
+	ConfigProfile string // as --config-profile
+	Store         string // 'ssm' or 'secretsmanager', overriding the config file's routing
+
+	AWSProfile string
+	Region     string
+
+	// Defaults to warnings and errors on stderr.
+	Logger log.Logger
+
+	// Transformations for every parameter, after the config file's (see
+	// package transform), as --transform.
+	Transforms []string
+
+	// Where to publish an event for each change made by Set, as well as the
+	// config file's sinks (see package events), as --event-sink. Publisher,
+	// if given, is published to as well.
+	EventSinks []string
+	Publisher  events.Publisher
+
+	// How long to cache parameters read (see store.Cached), e.g. for
+	// services that read them on every request. No caching if zero.
+	CacheTTL time.Duration
+
+	// How often Watch re-reads parameters. Defaults to a minute.
+	WatchInterval time.Duration
+
+	// Called around every store operation (see store.WithHooks), e.g. for
+	// metrics or audit logging.
+	Before store.BeforeHook
+	After  store.AfterHook
+}
+
+type Client struct {
+	conf       config.Config
+	opts       Options
+	service    store.Service
+	logger     log.Logger
+	transforms transform.Pipeline
+	publisher  events.Multi
+
+	mu     sync.Mutex
+	stores map[string]store.Store
+}
+
+// New reads config files and resolves the service. AWS config is loaded when
+// each store is first used.
+func New(ctx context.Context, opts Options) (*Client, error) {
+	c := &Client{opts: opts, logger: opts.Logger, stores: map[string]store.Store{}}
+	if c.logger == (log.Logger{}) {
+		c.logger, _ = log.New(os.Stderr, "warn", log.FormatPlain)
+	}
+
+	args := config.Config{App: opts.App, Stack: opts.Stack, Stage: opts.Stage}
+	conf, err := config.Load(ctx, opts.ConfigProfile, opts.Service, args, c.getRegistryParameter)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+	c.conf = conf
+
+	c.service, err = ServiceFor(config.Infer(ctx, c.logger, config.Merge(conf, args)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+	c.logger = c.logger.With("service", c.service.Prefix())
+
+	rules := append([]config.Transform{}, conf.Transforms...)
+	if len(opts.Transforms) > 0 {
+		rules = append(rules, config.Transform{Apply: opts.Transforms})
+	}
+	c.transforms, err = transform.New(rules, transform.Env{AWS: func(ctx context.Context) (aws.Config, error) {
+		return awsclient.LoadConfig(ctx, c.logger, awsOptions(c.conf, c.opts, ""))
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+
+	sinks := append(append([]string{}, conf.EventSinks...), opts.EventSinks...)
+	c.publisher, err = events.New(sinks, events.Env{Stdout: os.Stdout, AWS: func(ctx context.Context) (aws.Config, error) {
+		return awsclient.LoadConfig(ctx, c.logger, awsOptions(c.conf, c.opts, ""))
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %w", err)
+	}
+	if opts.Publisher != nil {
+		c.publisher = append(c.publisher, opts.Publisher)
+	}
+
+	return c, nil
+}
+
+// ServiceFor returns the service described by conf, with its stage normalised
+// and any prefix template parsed.
+func ServiceFor(conf config.Config) (store.Service, error) {
+	if err := conf.Validate(); err != nil {
+		return store.Service{}, err
+	}
+
+	stage, err := conf.NormaliseStage(conf.Stage)
+	if err != nil {
+		return store.Service{}, fmt.Errorf("invalid stage: %w", err)
+	}
+
+	service := store.Service{App: conf.App, Stack: conf.Stack, Stage: stage, Account: conf.Account}
+	if conf.PrefixTemplate != "" {
+		service.PrefixTemplate, err = store.ParsePrefixTemplate(conf.PrefixTemplate)
+		if err != nil {
+			return store.Service{}, fmt.Errorf("invalid prefix template: %w", err)
+		}
+	}
+
+	return service, nil
+}
+
+// Service is the resolved service, e.g. for its Prefix.
+func (c *Client) Service() store.Service {
+	return c.service
+}
+
+// Get returns the named parameter, relative to the service (e.g.
+// 'db/password'), from the config file's overrides if it is there, or else
+// from the store it is routed to. It fails if a transform turns the parameter
+// into several (e.g. json-flatten); use List instead.
+func (c *Client) Get(ctx context.Context, name string) (store.Parameter, error) {
+	st, err := c.store(ctx, c.storeFor(name))
+	if err != nil {
+		return store.Parameter{}, err
+	}
+	st = c.withOverrides(st)
+
+	p, err := st.Get(ctx, c.service, name)
+	if err != nil || p.Store == overrides {
+		return p, err
+	}
+
+	params, err := c.transforms.Read(ctx, []store.Parameter{p})
+	if err != nil {
+		return store.Parameter{}, err
+	}
+	if len(params) != 1 {
+		return store.Parameter{}, fmt.Errorf("'%s' is transformed into %d parameters", name, len(params))
+	}
+
+	return params[0], nil
+}
+
+// Set creates or updates the named parameter in the store it is routed to,
+// then publishes the change to any event sinks. Failing to publish is only
+// logged, as the change has been made.
+func (c *Client) Set(ctx context.Context, name string, value string, isSecret bool) error {
+	storeName := c.storeFor(name)
+	st, err := c.store(ctx, storeName)
+	if err != nil {
+		return err
+	}
+
+	value, err = c.transforms.Write(ctx, c.service, name, value)
+	if err != nil {
+		return err
+	}
+
+	if err := st.Set(ctx, c.service, name, value, isSecret, store.SetOptions{}); err != nil {
+		return err
+	}
+
+	if len(c.publisher) > 0 {
+		if err := c.publisher.Publish(ctx, events.NewChangeEvent(events.Set, c.service, storeName, name)); err != nil {
+			c.logger.Warnf("Unable to publish change to '%s': %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// List returns every parameter of the service, from each of the configured
+// stores (or just Options.Store), sorted by name.
+func (c *Client) List(ctx context.Context) ([]store.Parameter, error) {
+	var params []store.Parameter
+	err := c.Each(ctx, func(p store.Parameter) error {
+		params = append(params, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return params, store.Sort(params, store.SortByName, false)
+}
+
+// Each calls fn with each parameter of the service as it is listed (see
+// store.Each), in constant memory: first the config file's overrides, then
+// those of each store in turn, skipping any already listed. Return store.Stop
+// from fn to stop early.
+func (c *Client) Each(ctx context.Context, fn func(p store.Parameter) error) error {
+	storeNames := c.conf.Stores()
+	if c.opts.Store != "" {
+		storeNames = []string{c.opts.Store}
+	}
+
+	stores := make([]store.Store, 0, len(storeNames))
+	for _, name := range storeNames {
+		st, err := c.store(ctx, name)
+		if err != nil {
+			return err
+		}
+		stores = append(stores, st)
+	}
+
+	err := store.Each(ctx, c.withOverrides(store.Fallback(stores[0], stores[1:]...)), c.service, store.Filter{}, func(p store.Parameter) error {
+		if p.Store == overrides {
+			return fn(p)
+		}
+
+		params, err := c.transforms.Read(ctx, []store.Parameter{p})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range params {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list parameters for service '%s': %w", c.service.Prefix(), err)
+	}
+
+	return nil
+}
+
+// The store name of the config file's overrides, which are used as they are
+// (without transforms).
+const overrides = "overrides"
+
+// Puts the config file's overrides, if any, in front of st.
+func (c *Client) withOverrides(st store.Store) store.Store {
+	if len(c.conf.Overrides) == 0 {
+		return st
+	}
+
+	return store.Fallback(store.Values(overrides, c.conf.Overrides), st)
+}
+
+// Env returns every parameter of the service as 'KEY=value' (see
+// store.Parameter.Key), as for os.Environ.
+func (c *Client) Env(ctx context.Context) ([]string, error) {
+	params, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make([]string, 0, len(params))
+	for _, p := range params {
+		env = append(env, p.Key()+"="+p.Value)
+	}
+
+	return env, nil
+}
+
+// Exec runs the command with the service's parameters added to its
+// environment (taking precedence over variables already set), and with the
+// current process's stdin, stdout and stderr.
+func (c *Client) Exec(ctx context.Context, name string, args ...string) error {
+	env, err := c.Env(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func (c *Client) storeFor(name string) string {
+	if c.opts.Store != "" {
+		return c.opts.Store
+	}
+
+	return c.conf.StoreFor(name)
+}
+
+// Stores are created on demand, as each needs its own AWS client.
+func (c *Client) store(ctx context.Context, name string) (store.Store, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if st, ok := c.stores[name]; ok {
+		return st, nil
+	}
+
+	factory, err := store.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := awsclient.LoadConfig(ctx, c.logger, awsOptions(c.conf, c.opts, name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	st, err := factory(store.Config{Logger: c.logger, AWS: cfg, KMSKey: c.conf.KMSKey, Tags: c.conf.Tags})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s store: %w", name, err)
+	}
+
+	if c.opts.Before != nil || c.opts.After != nil {
+		st = store.WithHooks(st, c.opts.Before, c.opts.After)
+	}
+	if c.opts.CacheTTL > 0 {
+		st = store.Cached(st, c.opts.CacheTTL)
+	}
+
+	c.stores[name] = st
+	return st, nil
+}
+
+// Registry entries in SSM are read using the region and profile from options
+// and config files, rather than those of the service being looked up.
+func (c *Client) getRegistryParameter(ctx context.Context, conf config.Config, name string) (string, error) {
+	cfg, err := awsclient.LoadConfig(ctx, c.logger, awsOptions(conf, c.opts, "ssm"))
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{Name: &name, WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(out.Parameter.Value), nil
+}
+
+// As the CLI's, but without MFA or SSO login prompts.
+func awsOptions(conf config.Config, opts Options, storeName string) awsclient.Options {
+	return awsclient.Options{
+		Profile:       firstNonEmpty(opts.AWSProfile, conf.AWSProfile),
+		Region:        firstNonEmpty(opts.Region, conf.RegionFor(storeName)),
+		DefaultRegion: conf.DefaultRegion,
+		Account:       conf.Account,
+		RoleChain:     conf.RoleChain,
+		RoleARN:       conf.RoleARN,
+		ExternalID:    conf.ExternalID,
+		SessionName:   conf.SessionName,
+		Proxy:         conf.Proxy,
+		CABundle:      conf.CABundle,
+		MaxRetries:    conf.MaxRetries,
+		RetryMode:     conf.RetryMode,
+		Endpoints: map[string]string{
+			"":                       conf.EndpointURL,
+			ssm.ServiceID:            conf.StoreEndpoints["ssm"],
+			secretsmanager.ServiceID: conf.StoreEndpoints["secretsmanager"],
+		},
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,279 @@
+package devxconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/guardian/devx-config/config"
+	"github.com/guardian/devx-config/events"
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/store"
+)
+
+func TestServiceFor(t *testing.T) {
+	service, err := ServiceFor(config.Config{App: "api", Stack: "deploy", Stage: "code"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := service.Prefix(), "/CODE/deploy/api"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+
+	if _, err := ServiceFor(config.Config{App: "api", Stack: "deploy", Stage: "PORD", Stages: []string{"CODE", "PROD"}}); err == nil {
+		t.Errorf("invalid stage: expected an error")
+	}
+
+	if _, err := ServiceFor(config.Config{App: "api", Stack: "deploy", Stage: "CODE", PrefixTemplate: "no-slash"}); err == nil {
+		t.Errorf("invalid prefix template: expected an error")
+	}
+}
+
+// Reads the service from a config file, and a parameter from (fake) SSM.
+func TestClientGet(t *testing.T) {
+	var requested string
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Name string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		requested = in.Name
+		requests++
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Parameter": {"Name": "/PROD/deploy/api/db/password", "Value": "hunter2", "Type": "SecureString"}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".devx-config")
+	conf := `{"App": "api", "Stack": "deploy", "Stage": "CODE", "Region": "eu-west-1", "EndpointURL": "` + server.URL + `", "Overrides": {"db/host": "localhost"}}`
+	if err := os.WriteFile(path, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(config.FileEnv, path)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "aws-config"))
+
+	ctx := context.Background()
+	client, err := New(ctx, Options{Stage: "PROD"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := client.Service().Prefix(), "/PROD/deploy/api"; got != want {
+		t.Errorf("got service %s; want %s", got, want)
+	}
+
+	param, err := client.Get(ctx, "db/password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if requested != "/PROD/deploy/api/db/password" {
+		t.Errorf("requested %s; want /PROD/deploy/api/db/password", requested)
+	}
+	if param.Key() != "db_password" || param.Value != "hunter2" {
+		t.Errorf("got %s; want db_password=hunter2", param)
+	}
+
+	requested = ""
+	param, err = client.Get(ctx, "db/host")
+	if err != nil || param.Value != "localhost" || requested != "" {
+		t.Errorf("got %s, %v (requested %q); want the override, without calling SSM", param, err, requested)
+	}
+
+	cachedClient, err := New(ctx, Options{Stage: "PROD", CacheTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requests = 0
+	for i := 0; i < 3; i++ {
+		if param, err := cachedClient.Get(ctx, "db/password"); err != nil || param.Value != "hunter2" {
+			t.Errorf("got %s, %v; want db_password=hunter2", param, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests; want 1, with the rest cached", requests)
+	}
+}
+
+func TestClientSetPublishes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Version": 1, "Tier": "Standard"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".devx-config")
+	conf := `{"App": "api", "Stack": "deploy", "Stage": "PROD", "Region": "eu-west-1", "EndpointURL": "` + server.URL + `"}`
+	if err := os.WriteFile(path, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(config.FileEnv, path)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "aws-config"))
+
+	var published []events.ChangeEvent
+	publisher := events.PublisherFunc(func(ctx context.Context, e events.ChangeEvent) error {
+		published = append(published, e)
+		return nil
+	})
+
+	ctx := context.Background()
+	client, err := New(ctx, Options{Publisher: publisher})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.Set(ctx, "db/user", "admin", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(published) != 1 {
+		t.Fatalf("got %d events; want 1", len(published))
+	}
+	if e := published[0]; e.Action != events.Set || e.Name != "db/user" || e.Prefix != "/PROD/deploy/api" || e.Store != "ssm" {
+		t.Errorf("got %+v; want a set of db/user in ssm", e)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	reads := [][]store.Parameter{
+		{{Name: "/PROD/deploy/api/a", Value: "1"}, {Name: "/PROD/deploy/api/b", Value: "2"}},
+		{{Name: "/PROD/deploy/api/a", Value: "1"}, {Name: "/PROD/deploy/api/b", Value: "2"}},
+		{{Name: "/PROD/deploy/api/a", Value: "3"}, {Name: "/PROD/deploy/api/c", Value: "4"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := func(ctx context.Context) ([]store.Parameter, error) {
+		if len(reads) == 0 {
+			cancel()
+			return nil, ctx.Err()
+		}
+		params := reads[0]
+		reads = reads[1:]
+		return params, nil
+	}
+
+	var calls [][]store.Parameter
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	err := watch(ctx, logger, time.Millisecond, list, func(changed []store.Parameter) {
+		calls = append(calls, changed)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v; want context.Canceled", err)
+	}
+
+	// Only the last read differs: a changed, c added and b removed.
+	if len(calls) != 1 {
+		t.Fatalf("got %d calls; want 1", len(calls))
+	}
+
+	var got []string
+	for _, p := range calls[0] {
+		got = append(got, p.Name+"="+p.Value)
+	}
+	if want := "/PROD/deploy/api/a=3 /PROD/deploy/api/c=4 /PROD/deploy/api/b="; strings.Join(got, " ") != want {
+		t.Errorf("got %v; want %s", got, want)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	param := func(name, value string) store.Parameter {
+		return store.Parameter{Service: service, Name: service.Prefix() + "/" + name, Value: value}
+	}
+	params := []store.Parameter{
+		param("db/url", "postgres://db"),
+		param("port", "8080"),
+		param("debug", "true"),
+		param("hosts", "a, b,c"),
+	}
+
+	var conf struct {
+		DBURL   string        `devx:"db/url,required"`
+		Port    int           `devx:"port,default=9000"`
+		Debug   bool          `devx:"debug"`
+		Timeout time.Duration `devx:"timeout,default=30s"`
+		Hosts   []string      `devx:"hosts"`
+		Other   string
+	}
+	if err := Unmarshal(params, &conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.DBURL != "postgres://db" || conf.Port != 8080 || !conf.Debug || conf.Timeout != 30*time.Second || strings.Join(conf.Hosts, "|") != "a|b|c" {
+		t.Errorf("got %+v", conf)
+	}
+
+	var required struct {
+		A string `devx:"a,required"`
+		B string `devx:"b,required"`
+	}
+	if err := Unmarshal(params, &required); !errors.Is(err, store.ErrNotFound) || !strings.Contains(err.Error(), "a, b") {
+		t.Errorf("missing required: got %v; want ErrNotFound naming a and b", err)
+	}
+
+	var invalid struct {
+		Port bool `devx:"port"`
+	}
+	if err := Unmarshal(params, &invalid); err == nil {
+		t.Errorf("invalid bool: expected an error")
+	}
+
+	if err := Unmarshal(params, conf); err == nil {
+		t.Errorf("not a pointer: expected an error")
+	}
+}
+
+func TestFS(t *testing.T) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	param := func(name, value string, isSecret bool) store.Parameter {
+		return store.Parameter{Service: service, Name: service.Prefix() + "/" + name, Value: value, IsSecret: isSecret}
+	}
+
+	fsys := FS([]store.Parameter{
+		param("tls/cert.pem", "CERT", false),
+		param("tls/key.pem", "KEY", true),
+		param("port", "8080", false),
+	})
+
+	if err := fstest.TestFS(fsys, "tls/cert.pem", "tls/key.pem", "port"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "tls/key.pem")
+	if err != nil || string(data) != "KEY" {
+		t.Errorf("got %s, %v; want KEY", data, err)
+	}
+
+	if info, err := fs.Stat(fsys, "tls/key.pem"); err != nil || info.Mode() != 0o400 {
+		t.Errorf("secret: got %v, %v; want mode 0400", info, err)
+	}
+
+	if _, err := fs.ReadFile(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing: got %v; want fs.ErrNotExist", err)
+	}
+}
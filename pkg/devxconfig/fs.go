@@ -0,0 +1,215 @@
+package devxconfig
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// FS reads every parameter of the service and returns them as a file system
+// (see FS).
+func (c *Client) FS(ctx context.Context) (fs.FS, error) {
+	params, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return FS(params), nil
+}
+
+// FS returns params as a read-only, in-memory file system, with each parameter
+// a file at its name relative to the service (e.g. 'tls/cert.pem'), for use
+// with code that reads config from files (e.g. tls.X509KeyPair via
+// fs.ReadFile, or template.ParseFS). Secrets have mode 0400, and other
+// parameters 0444. A parameter whose name is also another's directory (e.g.
+// 'db' and 'db/url') is left out.
+func FS(params []store.Parameter) fs.FS {
+	root := &fsNode{name: ".", dir: true, children: map[string]*fsNode{}}
+
+	for _, p := range params {
+		name := p.RelativeName()
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+
+		dir := root
+		parts := strings.Split(name, "/")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := dir.children[part]
+			if !ok {
+				child = &fsNode{name: part, dir: true, children: map[string]*fsNode{}}
+				dir.children[part] = child
+			}
+			if !child.dir {
+				child.dir, child.data, child.children = true, nil, map[string]*fsNode{}
+			}
+			dir = child
+		}
+
+		base := parts[len(parts)-1]
+		if existing, ok := dir.children[base]; ok && existing.dir {
+			continue
+		}
+
+		mode := fs.FileMode(0o444)
+		if p.IsSecret {
+			mode = 0o400
+		}
+		dir.children[base] = &fsNode{name: base, data: []byte(p.Value), mode: mode, modTime: p.LastModified}
+	}
+
+	return parameterFS{root}
+}
+
+type parameterFS struct {
+	root *fsNode
+}
+
+type fsNode struct {
+	name     string
+	dir      bool
+	data     []byte
+	mode     fs.FileMode
+	modTime  time.Time
+	children map[string]*fsNode
+}
+
+func (fsys parameterFS) lookup(op string, name string) (*fsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	node := fsys.root
+	if name == "." {
+		return node, nil
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		child, ok := node.children[part]
+		if !node.dir || !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		node = child
+	}
+
+	return node, nil
+}
+
+func (fsys parameterFS) Open(name string) (fs.File, error) {
+	node, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.dir {
+		return &fsDir{node: node, path: name}, nil
+	}
+
+	return &fsFile{node: node, Reader: strings.NewReader(string(node.data))}, nil
+}
+
+func (fsys parameterFS) ReadFile(name string) ([]byte, error) {
+	node, err := fsys.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return append([]byte(nil), node.data...), nil
+}
+
+func (fsys parameterFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return node.entries(), nil
+}
+
+// Entries sorted by name, as fs.ReadDir requires.
+func (n *fsNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info()))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries
+}
+
+func (n *fsNode) info() fs.FileInfo {
+	return fsInfo{n}
+}
+
+type fsInfo struct {
+	node *fsNode
+}
+
+func (i fsInfo) Name() string       { return i.node.name }
+func (i fsInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i fsInfo) ModTime() time.Time { return i.node.modTime }
+func (i fsInfo) IsDir() bool        { return i.node.dir }
+func (i fsInfo) Sys() any           { return nil }
+
+func (i fsInfo) Mode() fs.FileMode {
+	if i.node.dir {
+		return fs.ModeDir | 0o555
+	}
+	return i.node.mode
+}
+
+type fsFile struct {
+	node *fsNode
+	*strings.Reader
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.node.info(), nil }
+func (f *fsFile) Close() error               { return nil }
+
+type fsDir struct {
+	node    *fsNode
+	path    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.node.info(), nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: fs.ErrInvalid}
+}
+
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = d.node.entries()
+	}
+
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+var _ fs.ReadDirFile = (*fsDir)(nil)
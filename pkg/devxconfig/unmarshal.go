@@ -0,0 +1,184 @@
+package devxconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// Unmarshal reads every parameter of the service and sets the fields of the
+// struct v points to from them (see Unmarshal).
+func (c *Client) Unmarshal(ctx context.Context, v any) error {
+	params, err := c.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	return Unmarshal(params, v)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Unmarshal sets the fields of the struct v points to from params, by the
+// parameter name (relative to the service) in each field's 'devx' tag:
+//
+//	type Config struct {
+//		DBURL   string        `devx:"db/url,required"`
+//		Port    int           `devx:"port,default=9000"`
+//		Debug   bool          `devx:"debug"`
+//		Timeout time.Duration `devx:"timeout,default=30s"`
+//		Hosts   []string      `devx:"hosts"` // comma-separated, as SSM's StringList
+//	}
+//
+// Fields may be strings, ints, uints, bools, floats, time.Durations or string
+// slices. Fields without a tag, or whose parameter doesn't exist and that have
+// no default, are left as they are. If any required parameters are missing the
+// error wraps store.ErrNotFound, and names them all.
+func Unmarshal(params []store.Parameter, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unable to unmarshal into %T: must be a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+
+	values := make(map[string]string, len(params))
+	for _, p := range params {
+		values[p.RelativeName()] = p.Value
+	}
+
+	var missing []string
+	var errs []error
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		tag, ok := field.Tag.Lookup("devx")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		name, required, def, hasDefault, err := parseTag(tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %s: %w", field.Name, err))
+			continue
+		}
+
+		value, ok := values[name]
+		if !ok {
+			switch {
+			case required:
+				missing = append(missing, name)
+				continue
+			case !hasDefault:
+				continue
+			}
+			value = def
+		}
+
+		if !field.IsExported() {
+			errs = append(errs, fmt.Errorf("field %s: must be exported", field.Name))
+			continue
+		}
+
+		if err := setField(rv.Field(i), value); err != nil {
+			errs = append(errs, fmt.Errorf("field %s ('%s'): %w", field.Name, name, err))
+		}
+	}
+
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("required parameters not set: %s (%w)", strings.Join(missing, ", "), store.ErrNotFound))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Parses 'name[,required][,default=value]'. The default must come last, so
+// that it may contain commas.
+func parseTag(tag string) (name string, required bool, def string, hasDefault bool, err error) {
+	name, rest, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return "", false, "", false, errors.New("devx tag has no parameter name")
+	}
+
+	for rest != "" {
+		if d, ok := strings.CutPrefix(rest, "default="); ok {
+			def, hasDefault = d, true
+			break
+		}
+
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+		switch opt {
+		case "required":
+			required = true
+		default:
+			return "", false, "", false, fmt.Errorf("unknown devx tag option '%s'", opt)
+		}
+	}
+
+	if required && hasDefault {
+		return "", false, "", false, errors.New("devx tag can't be both required and have a default")
+	}
+
+	return name, required, def, hasDefault, nil
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
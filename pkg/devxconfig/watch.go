@@ -0,0 +1,85 @@
+package devxconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/store"
+)
+
+const defaultWatchInterval = time.Minute
+
+// Watch re-reads the service's parameters every Options.WatchInterval, calling
+// fn with those that were added, changed or removed since the last read, so
+// that long-running services can reload their config without restarting.
+// Removed parameters are passed with an empty Value. fn isn't called for the
+// initial read, which the caller will usually have done with List.
+//
+// Watch blocks until ctx is done, returning ctx.Err(), or until the initial
+// read fails. Later failures are logged and retried at the next interval.
+func (c *Client) Watch(ctx context.Context, fn func(changed []store.Parameter)) error {
+	interval := c.opts.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	return watch(ctx, c.logger, interval, c.List, fn)
+}
+
+func watch(ctx context.Context, logger log.Logger, interval time.Duration, list func(ctx context.Context) ([]store.Parameter, error), fn func(changed []store.Parameter)) error {
+	last, err := list(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		params, err := list(ctx)
+		if err != nil {
+			logger.Warnf("unable to re-read parameters: %v", err)
+			continue
+		}
+
+		if changed := diff(last, params); len(changed) > 0 {
+			logger.Debugf("%d parameters changed", len(changed))
+			fn(changed)
+		}
+		last = params
+	}
+}
+
+// Returns the parameters of next that are new or have a different value
+// from prev, followed by those only in prev (with an empty Value).
+func diff(prev, next []store.Parameter) []store.Parameter {
+	previous := make(map[string]store.Parameter, len(prev))
+	for _, p := range prev {
+		previous[p.Name] = p
+	}
+
+	var changed []store.Parameter
+	for _, p := range next {
+		old, ok := previous[p.Name]
+		if !ok || old.Value != p.Value {
+			changed = append(changed, p)
+		}
+		delete(previous, p.Name)
+	}
+
+	for _, p := range prev {
+		if _, ok := previous[p.Name]; ok {
+			p.Value = ""
+			changed = append(changed, p)
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,107 @@
+// Package progress shows a spinner (with counts and, where the total is known,
+// an ETA) for long-running operations. It writes to stderr so that it never
+// interferes with output, and does nothing unless stderr is a terminal.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var frames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+type Spinner struct {
+	w       io.Writer
+	label   string
+	total   int
+	enabled bool
+
+	mu    sync.Mutex
+	count int
+	start time.Time
+	done  chan struct{}
+	stop  sync.Once
+	wg    sync.WaitGroup
+}
+
+// New returns a spinner, started immediately. Pass a total of 0 if it isn't
+// known in advance.
+func New(w io.Writer, enabled bool, label string, total int) *Spinner {
+	s := &Spinner{w: w, label: label, total: total, enabled: enabled, start: time.Now(), done: make(chan struct{})}
+	if enabled {
+		s.wg.Add(1)
+		go s.run()
+	}
+
+	return s
+}
+
+// Add records n more items as complete.
+func (s *Spinner) Add(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += n
+}
+
+// Stop stops the spinner and removes it from the terminal. It is safe to call
+// more than once.
+func (s *Spinner) Stop() {
+	if !s.enabled {
+		return
+	}
+
+	s.stop.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		fmt.Fprint(s.w, "\r\033[K")
+	})
+}
+
+// Pause temporarily removes the spinner (until the next tick) so that other
+// output can be written cleanly.
+func (s *Spinner) Pause(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.enabled {
+		fmt.Fprint(s.w, "\r\033[K")
+	}
+
+	fn()
+}
+
+func (s *Spinner) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 0; ; i++ {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			fmt.Fprintf(s.w, "\r\033[K%s %s %s", frames[i%len(frames)], s.label, s.status())
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Spinner) status() string {
+	elapsed := time.Since(s.start)
+
+	if s.total <= 0 {
+		return fmt.Sprintf("(%d, %s)", s.count, elapsed.Round(time.Second))
+	}
+
+	eta := "?"
+	if s.count > 0 {
+		remaining := time.Duration(float64(elapsed) / float64(s.count) * float64(s.total-s.count))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("(%d/%d, ETA %s)", s.count, s.total, eta)
+}
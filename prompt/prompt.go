@@ -0,0 +1,125 @@
+// Package prompt asks the user questions on the terminal. When running
+// non-interactively (in CI, say) any attempt to prompt fails fast with
+// ErrNonInteractive rather than blocking on input that will never arrive.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var ErrNonInteractive = errors.New("input required but running non-interactively (stdin is not a terminal or --non-interactive is set)")
+
+type Prompter struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+	fd          int // of in, if it is a terminal (for arrow-key selection), otherwise -1
+}
+
+func New(in io.Reader, out io.Writer, interactive bool) Prompter {
+	fd := -1
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fd = int(f.Fd())
+	}
+
+	return Prompter{bufio.NewReader(in), out, interactive, fd}
+}
+
+// IsTerminal reports whether f is a character device (i.e. a TTY) rather than
+// a pipe or regular file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p Prompter) Interactive() bool {
+	return p.interactive
+}
+
+// Ask prints question and returns the (trimmed) line entered in response.
+func (p Prompter) Ask(question string) (string, error) {
+	if !p.interactive {
+		return "", fmt.Errorf("%w: %s", ErrNonInteractive, strings.TrimSpace(question))
+	}
+
+	fmt.Fprint(p.out, question)
+
+	got, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && got != "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(got), nil
+}
+
+// Input is like Ask, but returns def if the response is empty, and re-prompts
+// until validate (if non-nil) accepts the response.
+func (p Prompter) Input(question string, def string, validate func(string) error) (string, error) {
+	label := question + ": "
+	if def != "" {
+		label = fmt.Sprintf("%s [%s]: ", question, def)
+	}
+
+	for {
+		got, err := p.Ask(label)
+		if err != nil {
+			return "", err
+		}
+
+		if got == "" {
+			got = def
+		}
+
+		if validate == nil {
+			return got, nil
+		}
+
+		if err := validate(got); err != nil {
+			fmt.Fprintf(p.out, "Invalid response: %v.\n", err)
+			continue
+		}
+
+		return got, nil
+	}
+}
+
+// YesNo asks question until the response is one of 'y' or 'n'.
+func (p Prompter) YesNo(question string) (bool, error) {
+	for {
+		got, err := p.Ask(question + " (y/n) ")
+		if err != nil {
+			return false, err
+		}
+
+		switch got {
+		case "y":
+			return true, nil
+		case "n":
+			return false, nil
+		default:
+			fmt.Fprintln(p.out, "Response must be one of 'y', 'n'.")
+		}
+	}
+}
+
+// TypeToConfirm asks the user to type expected back (as when deleting a GitHub
+// repository), which is much harder to do by accident than answering 'y'.
+func (p Prompter) TypeToConfirm(question string, expected string) (bool, error) {
+	got, err := p.Ask(fmt.Sprintf("%s Type '%s' to confirm: ", question, expected))
+	if err != nil {
+		return false, err
+	}
+
+	return got == expected, nil
+}
@@ -0,0 +1,75 @@
+package prompt
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestYesNo(t *testing.T) {
+	p := New(strings.NewReader("maybe\ny\n"), io.Discard, true)
+
+	got, err := p.YesNo("Continue?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !got {
+		t.Fatalf("got: %v; want true", got)
+	}
+}
+
+func TestAskNonInteractive(t *testing.T) {
+	p := New(strings.NewReader("y\n"), io.Discard, false)
+
+	_, err := p.Ask("App: ")
+	if !errors.Is(err, ErrNonInteractive) {
+		t.Fatalf("got: %v; want %v", err, ErrNonInteractive)
+	}
+}
+
+func TestTypeToConfirm(t *testing.T) {
+	p := New(strings.NewReader("db.passwrd\n"), io.Discard, true)
+
+	got, err := p.TypeToConfirm("Delete?", "db.password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got {
+		t.Fatalf("got: %v; want false for mistyped confirmation", got)
+	}
+}
+
+func TestInput(t *testing.T) {
+	p := New(strings.NewReader("bad/stage\n\n"), io.Discard, true)
+	validate := func(s string) error {
+		if strings.Contains(s, "/") {
+			return errors.New("must not contain '/'")
+		}
+		return nil
+	}
+
+	got, err := p.Input("Stage", "CODE", validate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "CODE" {
+		t.Fatalf("got: %s; want default of CODE", got)
+	}
+}
+
+func TestSelectByNumber(t *testing.T) {
+	p := New(strings.NewReader("3\n2\n"), io.Discard, true)
+
+	got, err := p.Select("Is this parameter a secret?", []string{"Yes", "No"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != 1 {
+		t.Fatalf("got: %d; want 1", got)
+	}
+}
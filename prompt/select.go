@@ -0,0 +1,102 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// Select asks the user to choose one of options, returning its index. In a
+// terminal the choice is made with the arrow keys (or j/k) and enter;
+// otherwise, options are numbered and the number is read instead.
+func (p Prompter) Select(question string, options []string, def int) (int, error) {
+	if !p.interactive {
+		return 0, fmt.Errorf("%w: %s", ErrNonInteractive, question)
+	}
+
+	if p.fd < 0 {
+		return p.selectByNumber(question, options, def)
+	}
+
+	state, err := term.MakeRaw(p.fd)
+	if err != nil {
+		return p.selectByNumber(question, options, def)
+	}
+	defer term.Restore(p.fd, state)
+
+	// Note, in raw mode newlines don't imply a carriage return.
+	fmt.Fprintf(p.out, "%s (use arrow keys)\r\n", question)
+	selected := def
+	render := func() {
+		for i, option := range options {
+			marker := "  "
+			if i == selected {
+				marker = "> "
+			}
+			fmt.Fprintf(p.out, "\r\033[K%s%s\r\n", marker, option)
+		}
+	}
+
+	render()
+	for {
+		key, err := p.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch key {
+		case '\r', '\n':
+			return selected, nil
+		case 3, 4: // ctrl-c, ctrl-d
+			return 0, errInterrupted
+		case 'k':
+			selected = (selected + len(options) - 1) % len(options)
+		case 'j':
+			selected = (selected + 1) % len(options)
+		case 27: // escape sequence, e.g. '\033[A' for up
+			if next, _ := p.in.ReadByte(); next != '[' {
+				continue
+			}
+
+			switch arrow, _ := p.in.ReadByte(); arrow {
+			case 'A':
+				selected = (selected + len(options) - 1) % len(options)
+			case 'B':
+				selected = (selected + 1) % len(options)
+			}
+		default:
+			continue
+		}
+
+		fmt.Fprintf(p.out, "\033[%dA", len(options))
+		render()
+	}
+}
+
+func (p Prompter) selectByNumber(question string, options []string, def int) (int, error) {
+	fmt.Fprintln(p.out, question)
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+
+	validate := func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > len(options) {
+			return fmt.Errorf("must be a number between 1 and %d", len(options))
+		}
+
+		return nil
+	}
+
+	got, err := p.Input("Choice", strconv.Itoa(def+1), validate)
+	if err != nil {
+		return 0, err
+	}
+
+	n, _ := strconv.Atoi(got)
+	return n - 1, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// Runs fn for each region concurrently, returning the parameters from all of
+// them (grouped in the order the regions are given), labelled with their
+// region.
+func fanOutRegions(regions []string, fn func(region string) ([]store.Parameter, error)) ([]store.Parameter, error) {
+	results := make([][]store.Parameter, len(regions))
+	errs := make([]error, len(regions))
+
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			results[i], errs[i] = fn(region)
+		}(i, region)
+	}
+	wg.Wait()
+
+	var out []store.Parameter
+	for i, region := range regions {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("%s: %w", region, errs[i])
+		}
+
+		for _, p := range results[i] {
+			p.Region = region
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestFanOutRegions(t *testing.T) {
+	got, err := fanOutRegions([]string{"eu-west-1", "us-east-1"}, func(region string) ([]store.Parameter, error) {
+		return []store.Parameter{{Name: "/PROD/deploy/example/a", Value: region}}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || got[0].Region != "eu-west-1" || got[1].Region != "us-east-1" || got[1].Value != "us-east-1" {
+		t.Errorf("got %+v; want one parameter per region, in order", got)
+	}
+
+	_, err = fanOutRegions([]string{"eu-west-1", "us-east-1"}, func(region string) ([]store.Parameter, error) {
+		if region == "us-east-1" {
+			return nil, errors.New("boom")
+		}
+		return nil, nil
+	})
+	if err == nil || err.Error() != "us-east-1: boom" {
+		t.Errorf("got %v; want the error labelled by region", err)
+	}
+}
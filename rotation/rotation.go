@@ -0,0 +1,205 @@
+// Package rotation generates CloudFormation templates that set up rotation for
+// Secrets Manager secrets, using AWS's standard rotation functions.
+package rotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Type string
+
+const (
+	RDSPostgres Type = "rds-postgres" // AWS's hosted single-user PostgreSQL rotation function
+	Generic     Type = "generic"      // replaces the value with a random password, for secrets with nothing else to update
+)
+
+func (t Type) Validate() error {
+	switch t {
+	case RDSPostgres, Generic:
+		return nil
+	default:
+		return fmt.Errorf("unsupported rotation type '%s' (must be one of 'rds-postgres', 'generic')", t)
+	}
+}
+
+type Options struct {
+	Type     Type
+	SecretID string // name or ARN
+	Schedule string // e.g. 'rate(30 days)' or 'cron(0 4 ? * SUN *)'
+
+	// For RDS, the VPC the rotation function needs to reach the database.
+	SubnetIDs        []string
+	SecurityGroupIDs []string
+}
+
+// StackName returns a CloudFormation stack name for the secret's rotation.
+func StackName(secretID string) string {
+	name := "devx-config-rotation-" + strings.Trim(invalidStackChars.ReplaceAllString(secretID, "-"), "-")
+	if len(name) > 128 {
+		name = name[:128]
+	}
+
+	return name
+}
+
+var invalidStackChars = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+// Template returns the CloudFormation template (as JSON) for the options.
+func Template(opts Options) ([]byte, error) {
+	if err := opts.Type.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.SecretID == "" {
+		return nil, fmt.Errorf("a secret is required")
+	}
+
+	rules := map[string]any{"ScheduleExpression": opts.Schedule}
+
+	var tmpl map[string]any
+	switch opts.Type {
+	case RDSPostgres:
+		hosted := map[string]any{"RotationType": "PostgreSQLSingleUser"}
+		if len(opts.SubnetIDs) > 0 {
+			hosted["VpcSubnetIds"] = strings.Join(opts.SubnetIDs, ",")
+		}
+		if len(opts.SecurityGroupIDs) > 0 {
+			hosted["VpcSecurityGroupIds"] = strings.Join(opts.SecurityGroupIDs, ",")
+		}
+
+		tmpl = map[string]any{
+			"AWSTemplateFormatVersion": "2010-09-09",
+			"Transform":                "AWS::SecretsManager-2020-07-23",
+			"Resources": map[string]any{
+				"RotationSchedule": map[string]any{
+					"Type": "AWS::SecretsManager::RotationSchedule",
+					"Properties": map[string]any{
+						"SecretId":             opts.SecretID,
+						"HostedRotationLambda": hosted,
+						"RotationRules":        rules,
+					},
+				},
+			},
+		}
+	case Generic:
+		tmpl = genericTemplate(opts.SecretID, rules)
+	}
+
+	return json.MarshalIndent(tmpl, "", "  ")
+}
+
+// The secret's ARN, as the rotation function's permissions need one (a name is
+// also accepted, but then matches any suffix Secrets Manager added).
+func secretARN(secretID string) any {
+	if strings.HasPrefix(secretID, "arn:") {
+		return secretID
+	}
+
+	return map[string]any{"Fn::Sub": "arn:${AWS::Partition}:secretsmanager:${AWS::Region}:${AWS::AccountId}:secret:" + secretID + "-*"}
+}
+
+func genericTemplate(secretID string, rules map[string]any) map[string]any {
+	return map[string]any{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Resources": map[string]any{
+			"RotationRole": map[string]any{
+				"Type": "AWS::IAM::Role",
+				"Properties": map[string]any{
+					"AssumeRolePolicyDocument": map[string]any{
+						"Version": "2012-10-17",
+						"Statement": []any{map[string]any{
+							"Effect":    "Allow",
+							"Principal": map[string]any{"Service": "lambda.amazonaws.com"},
+							"Action":    "sts:AssumeRole",
+						}},
+					},
+					"ManagedPolicyArns": []any{
+						map[string]any{"Fn::Sub": "arn:${AWS::Partition}:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+					},
+					"Policies": []any{map[string]any{
+						"PolicyName": "rotate-secret",
+						"PolicyDocument": map[string]any{
+							"Version": "2012-10-17",
+							"Statement": []any{
+								map[string]any{
+									"Effect": "Allow",
+									"Action": []any{
+										"secretsmanager:DescribeSecret",
+										"secretsmanager:GetSecretValue",
+										"secretsmanager:PutSecretValue",
+										"secretsmanager:UpdateSecretVersionStage",
+									},
+									"Resource": secretARN(secretID),
+								},
+								map[string]any{
+									"Effect":   "Allow",
+									"Action":   "secretsmanager:GetRandomPassword",
+									"Resource": "*",
+								},
+								map[string]any{
+									"Effect":    "Allow",
+									"Action":    []any{"kms:Decrypt", "kms:GenerateDataKey"},
+									"Resource":  "*",
+									"Condition": map[string]any{"StringEquals": map[string]any{"kms:ViaService": map[string]any{"Fn::Sub": "secretsmanager.${AWS::Region}.${AWS::URLSuffix}"}}},
+								},
+							},
+						},
+					}},
+				},
+			},
+			"RotationFunction": map[string]any{
+				"Type": "AWS::Lambda::Function",
+				"Properties": map[string]any{
+					"Runtime": "python3.12",
+					"Handler": "index.handler",
+					"Timeout": 30,
+					"Role":    map[string]any{"Fn::GetAtt": []any{"RotationRole", "Arn"}},
+					"Code":    map[string]any{"ZipFile": genericFunction},
+				},
+			},
+			"RotationPermission": map[string]any{
+				"Type": "AWS::Lambda::Permission",
+				"Properties": map[string]any{
+					"Action":       "lambda:InvokeFunction",
+					"FunctionName": map[string]any{"Ref": "RotationFunction"},
+					"Principal":    "secretsmanager.amazonaws.com",
+				},
+			},
+			"RotationSchedule": map[string]any{
+				"Type":      "AWS::SecretsManager::RotationSchedule",
+				"DependsOn": "RotationPermission",
+				"Properties": map[string]any{
+					"SecretId":          secretID,
+					"RotationLambdaARN": map[string]any{"Fn::GetAtt": []any{"RotationFunction", "Arn"}},
+					"RotationRules":     rules,
+				},
+			},
+		},
+	}
+}
+
+// Follows AWS's generic rotation template, with nothing to do for setSecret and
+// testSecret as there's no other system to update.
+const genericFunction = `import boto3
+
+client = boto3.client("secretsmanager")
+
+
+def handler(event, context):
+    arn, token, step = event["SecretId"], event["ClientRequestToken"], event["Step"]
+
+    if step == "createSecret":
+        try:
+            client.get_secret_value(SecretId=arn, VersionId=token, VersionStage="AWSPENDING")
+        except client.exceptions.ResourceNotFoundException:
+            password = client.get_random_password(ExcludePunctuation=True)["RandomPassword"]
+            client.put_secret_value(SecretId=arn, ClientRequestToken=token, SecretString=password, VersionStages=["AWSPENDING"])
+    elif step == "finishSecret":
+        versions = client.describe_secret(SecretId=arn)["VersionIdsToStages"]
+        current = next(v for v, stages in versions.items() if "AWSCURRENT" in stages)
+        if current != token:
+            client.update_secret_version_stage(SecretId=arn, VersionStage="AWSCURRENT", MoveToVersionId=token, RemoveFromVersionId=current)
+`
@@ -0,0 +1,40 @@
+package rotation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	for _, typ := range []Type{RDSPostgres, Generic} {
+		data, err := Template(Options{Type: typ, SecretID: "/PROD/deploy/example/db-password", Schedule: "rate(30 days)"})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", typ, err)
+		}
+
+		var tmpl struct {
+			Resources map[string]struct {
+				Type       string
+				Properties map[string]any
+			}
+		}
+		if err := json.Unmarshal(data, &tmpl); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", typ, err)
+		}
+
+		schedule := tmpl.Resources["RotationSchedule"]
+		if schedule.Type != "AWS::SecretsManager::RotationSchedule" || schedule.Properties["SecretId"] != "/PROD/deploy/example/db-password" {
+			t.Errorf("%s: got rotation schedule %+v", typ, schedule)
+		}
+	}
+
+	if _, err := Template(Options{Type: "mysql", SecretID: "foo"}); err == nil {
+		t.Error("expected an error for an unsupported type")
+	}
+}
+
+func TestStackName(t *testing.T) {
+	if got, want := StackName("/PROD/deploy/example/db_password"), "devx-config-rotation-PROD-deploy-example-db-password"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "net"
+
+// Whether addr (as for http.Server) only listens on a loopback interface, so
+// nothing else on the network can reach it. An empty host (e.g. ':8080')
+// listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
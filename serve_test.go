@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.1:8080":  false,
+		"example.com:80": false,
+		"invalid":        false,
+	}
+
+	for addr, want := range tests {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("%s: got %t; want %t", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package server serves parameters over a small REST API, for running
+// devx-config as an internal sidecar (or daemonset) that apps can read their
+// config from without AWS credentials of their own.
+//
+//	GET /healthz                                       200 while the process is up
+//	GET /readyz                                        200 once AWS credentials are usable
+//	GET /v1/services/{stage}/{stack}/{app}/params      every parameter of the service
+//	GET /v1/services/{stage}/{stack}/{app}/params/{name...}
+//
+// The /v1 endpoints require an 'Authorization: Bearer <token>' header.
+// Responses are JSON, as with '--output=json'; errors are
+// '{"error": {"code": ..., "message": ...}}'.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/output"
+	"github.com/guardian/devx-config/store"
+)
+
+type Options struct {
+	Token string // required for /v1 requests
+
+	// Stores by name, and the name of the store each parameter is routed to.
+	Stores   map[string]store.Store
+	StoreFor func(name string) string
+
+	// Used for every service, as with the config file's 'account' and
+	// 'prefixTemplate'. Optional.
+	Account        string
+	PrefixTemplate *template.Template
+
+	// Checks readiness, e.g. that AWS credentials can be retrieved. Optional.
+	Ready func(ctx context.Context) error
+
+	Logger log.Logger
+}
+
+// New returns the API's handler. It fails if there's no token, so the API is
+// never served unauthenticated by mistake.
+func New(opts Options) (http.Handler, error) {
+	if opts.Token == "" {
+		return nil, errors.New("a token is required")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if opts.Ready != nil {
+			if err := opts.Ready(r.Context()); err != nil {
+				writeError(w, http.StatusServiceUnavailable, "not_ready", err.Error())
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+	mux.Handle("/v1/", authenticated(opts.Token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveParams(w, r, opts)
+	})))
+
+	return mux, nil
+}
+
+func authenticated(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func serveParams(w http.ResponseWriter, r *http.Request, opts Options) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET is supported")
+		return
+	}
+
+	service, name, ok := parsePath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "expected /v1/services/{stage}/{stack}/{app}/params[/{name}]")
+		return
+	}
+	service.Account, service.PrefixTemplate = opts.Account, opts.PrefixTemplate
+
+	logger := opts.Logger.With("service", service.Prefix())
+	w.Header().Set("Content-Type", "application/json")
+
+	if name != "" {
+		st, ok := opts.Stores[opts.StoreFor(name)]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("store '%s' isn't available", opts.StoreFor(name)))
+			return
+		}
+
+		param, err := st.Get(r.Context(), service, name)
+		if err != nil {
+			logger.Debugf("unable to get '%s': %v", name, err)
+			writeStoreError(w, err)
+			return
+		}
+
+		logger.Debugf("served '%s'", name)
+		_ = output.WriteOne(w, output.Options{Format: output.JSON}, param)
+		return
+	}
+
+	var params []store.Parameter
+	for storeName, st := range opts.Stores {
+		page, err := st.List(r.Context(), service, store.Filter{})
+		if err != nil {
+			logger.Debugf("unable to list %s: %v", storeName, err)
+			writeStoreError(w, err)
+			return
+		}
+		params = append(params, page...)
+	}
+
+	if err := store.Sort(params, store.SortByName, false); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	logger.Debugf("served %d parameters", len(params))
+	_ = output.Write(w, output.Options{Format: output.JSON}, params)
+}
+
+// Parses '/v1/services/{stage}/{stack}/{app}/params[/{name...}]'.
+func parsePath(path string) (store.Service, string, bool) {
+	rest, ok := strings.CutPrefix(path, "/v1/services/")
+	if !ok {
+		return store.Service{}, "", false
+	}
+
+	parts := strings.SplitN(rest, "/", 5)
+	if len(parts) < 4 || parts[3] != "params" || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return store.Service{}, "", false
+	}
+
+	service := store.Service{Stage: parts[0], Stack: parts[1], App: parts[2]}
+	if len(parts) == 5 {
+		return service, parts[4], true
+	}
+
+	return service, "", true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, store.ErrAccessDenied):
+		writeError(w, http.StatusForbidden, "access_denied", err.Error())
+	case errors.Is(err, store.ErrThrottled):
+		writeError(w, http.StatusTooManyRequests, "throttled", err.Error())
+	default:
+		writeError(w, http.StatusBadGateway, "internal_error", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]map[string]string{"error": {"code": code, "message": message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/store"
+)
+
+// Has a single parameter, 'db/password'.
+type fakeStore struct {
+	store.Store
+}
+
+func (fakeStore) Get(ctx context.Context, service store.Service, name string) (store.Parameter, error) {
+	if name != "db/password" {
+		return store.Parameter{}, errors.Join(store.ErrNotFound, errors.New("parameter not found"))
+	}
+
+	return store.Parameter{Service: service, Name: service.Prefix() + "/" + name, Value: "hunter2", IsSecret: true, Store: "ssm"}, nil
+}
+
+func (s fakeStore) List(ctx context.Context, service store.Service, f store.Filter) ([]store.Parameter, error) {
+	p, err := s.Get(ctx, service, "db/password")
+	return []store.Parameter{p}, err
+}
+
+func TestServer(t *testing.T) {
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	handler, err := New(Options{
+		Token:    "secret-token",
+		Stores:   map[string]store.Store{"ssm": fakeStore{}},
+		StoreFor: func(name string) string { return "ssm" },
+		Ready:    func(ctx context.Context) error { return errors.New("no credentials") },
+		Logger:   logger,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string, token string) (int, []byte) {
+		t.Helper()
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, data
+	}
+
+	tests := []struct {
+		path, token string
+		want        int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/readyz", "", http.StatusServiceUnavailable},
+		{"/v1/services/PROD/deploy/api/params", "", http.StatusUnauthorized},
+		{"/v1/services/PROD/deploy/api/params", "wrong", http.StatusUnauthorized},
+		{"/v1/services/PROD/deploy/api/params", "secret-token", http.StatusOK},
+		{"/v1/services/PROD/deploy/api/params/db/password", "secret-token", http.StatusOK},
+		{"/v1/services/PROD/deploy/api/params/missing", "secret-token", http.StatusNotFound},
+		{"/v1/services/PROD/deploy/params", "secret-token", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		if got, body := get(tc.path, tc.token); got != tc.want {
+			t.Errorf("%s: got %d (%s); want %d", tc.path, got, body, tc.want)
+		}
+	}
+
+	var param struct{ Name, Value string }
+	_, body := get("/v1/services/PROD/deploy/api/params/db/password", "secret-token")
+	if err := json.Unmarshal(body, &param); err != nil || param.Name != "/PROD/deploy/api/db/password" || param.Value != "hunter2" {
+		t.Errorf("got %s; want db/password=hunter2", body)
+	}
+
+	var params []struct{ Name, Value string }
+	_, body = get("/v1/services/PROD/deploy/api/params", "secret-token")
+	if err := json.Unmarshal(body, &params); err != nil || len(params) != 1 || params[0].Value != "hunter2" {
+		t.Errorf("got %s; want a list of just db/password", body)
+	}
+
+	if _, err := New(Options{}); err == nil {
+		t.Errorf("no token: expected an error")
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	service, name, ok := parsePath("/v1/services/CODE/deploy/api/params/db/password")
+	if !ok || service.Prefix() != "/CODE/deploy/api" || name != "db/password" {
+		t.Errorf("got %s, %s, %t; want /CODE/deploy/api, db/password", service.Prefix(), name, ok)
+	}
+
+	if _, _, ok := parsePath("/v1/services/CODE//api/params"); ok {
+		t.Errorf("empty stack: expected no match")
+	}
+}
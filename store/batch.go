@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Item is a parameter to set with SetMany.
+type Item struct {
+	Name     string
+	Value    string
+	IsSecret bool
+}
+
+// BatchError reports the parameters of a batch operation that failed, with
+// their errors, keyed by name. The others succeeded. errors.Is matches any of
+// the errors, e.g. ErrNotFound if any parameter didn't exist.
+type BatchError map[string]error
+
+func (e BatchError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("'%s': %v", name, e[name]))
+	}
+
+	return fmt.Sprintf("%d parameter(s) failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// The most single calls made at once by SetConcurrently and
+// DeleteConcurrently, to stay well within AWS rate limits.
+const batchConcurrency = 5
+
+// SetConcurrently implements SetMany with concurrent calls to st.Set, for
+// stores without a batch API.
+func SetConcurrently(ctx context.Context, st Store, service Service, items []Item, opts SetOptions) error {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+
+	return concurrently(names, func(i int) error {
+		return st.Set(ctx, service, items[i].Name, items[i].Value, items[i].IsSecret, opts)
+	})
+}
+
+// DeleteConcurrently implements DeleteMany with concurrent calls to
+// st.Delete, for stores without a batch API.
+func DeleteConcurrently(ctx context.Context, st Store, service Service, names []string) error {
+	return concurrently(names, func(i int) error {
+		return st.Delete(ctx, service, names[i])
+	})
+}
+
+// Calls fn with the index of each name, batchConcurrency at a time, returning
+// a BatchError of any that fail.
+func concurrently(names []string, fn func(i int) error) error {
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, batchConcurrency)
+
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return batchError(names, errs)
+}
+
+// Returns a BatchError of the names with errors, or nil if there are none.
+func batchError(names []string, errs []error) error {
+	failed := BatchError{}
+	for i, err := range errs {
+		if err != nil {
+			failed[names[i]] = err
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cached returns inner with parameters read through an in-memory cache, kept
+// for ttl, so that hot-path reads (e.g. on every request) don't each call AWS.
+// Errors are never cached. Set and Delete drop the parameter, and every
+// cached List of its service, so a process sees its own writes at once, but
+// writes by others may take up to ttl to be seen. Versions are read from inner
+// as they are.
+func Cached(inner Store, ttl time.Duration) Store {
+	return &cached{inner: inner, ttl: ttl, now: time.Now, params: map[string]cacheEntry{}, lists: map[string]cacheEntry{}}
+}
+
+type cached struct {
+	inner Store
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu     sync.Mutex
+	params map[string]cacheEntry // by full name
+	lists  map[string]cacheEntry // by service prefix and filter (see listKey)
+}
+
+type cacheEntry struct {
+	params  []Parameter
+	expires time.Time
+}
+
+// Returns the entry, if it hasn't expired.
+func (c *cached) lookup(entries map[string]cacheEntry, key string) ([]Parameter, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return nil, false
+	}
+
+	return e.params, true
+}
+
+func (c *cached) store(entries map[string]cacheEntry, key string, params []Parameter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries[key] = cacheEntry{params, c.now().Add(c.ttl)}
+}
+
+// Drops the named parameter and the service's lists.
+func (c *cached) invalidate(service Service, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.params, service.Prefix()+"/"+name)
+	for key := range c.lists {
+		if strings.HasPrefix(key, service.Prefix()+"\x00") {
+			delete(c.lists, key)
+		}
+	}
+}
+
+// Filters are cached separately, as stores may filter while listing.
+func listKey(service Service, f Filter) string {
+	regex := ""
+	if f.Regex != nil {
+		regex = f.Regex.String()
+	}
+
+	return strings.Join([]string{service.Prefix(), f.Prefix, f.Contains, regex, fmt.Sprint(f.IncludeUntagged, f.Deleted)}, "\x00")
+}
+
+func (c *cached) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	key := service.Prefix() + "/" + name
+	if params, ok := c.lookup(c.params, key); ok {
+		return params[0], nil
+	}
+
+	p, err := c.inner.Get(ctx, service, name)
+	if err != nil {
+		return Parameter{}, err
+	}
+
+	c.store(c.params, key, []Parameter{p})
+	return p, nil
+}
+
+// Only the parameters that aren't cached are read from inner.
+func (c *cached) GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error) {
+	found := map[string]Parameter{}
+	var missing []string
+	for _, name := range names {
+		if params, ok := c.lookup(c.params, service.Prefix()+"/"+name); ok {
+			found[name] = params[0]
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	var err error
+	if len(missing) > 0 {
+		var params []Parameter
+		params, err = c.inner.GetMany(ctx, service, missing)
+		for _, p := range params {
+			c.store(c.params, p.Name, []Parameter{p})
+			found[p.RelativeName()] = p
+		}
+	}
+
+	params := []Parameter{}
+	for _, name := range names {
+		if p, ok := found[name]; ok {
+			params = append(params, p)
+		}
+	}
+
+	return params, err
+}
+
+func (c *cached) GetVersion(ctx context.Context, service Service, name string, version string) (Parameter, error) {
+	return c.inner.GetVersion(ctx, service, name, version)
+}
+
+func (c *cached) ListVersions(ctx context.Context, service Service, name string) ([]Version, error) {
+	return c.inner.ListVersions(ctx, service, name)
+}
+
+func (c *cached) List(ctx context.Context, service Service, f Filter) ([]Parameter, error) {
+	params := []Parameter{}
+	err := c.ListPages(ctx, service, f, func(page []Parameter) error {
+		params = append(params, page...)
+		return nil
+	})
+
+	return params, err
+}
+
+// Cached lists are passed to fn as a single page. Lists are only cached once
+// every page has been read, so stopping early caches nothing.
+func (c *cached) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	key := listKey(service, f)
+	if params, ok := c.lookup(c.lists, key); ok {
+		if len(params) == 0 {
+			return nil
+		}
+		return fn(params)
+	}
+
+	params := []Parameter{}
+	err := c.inner.ListPages(ctx, service, f, func(page []Parameter) error {
+		params = append(params, page...)
+		return fn(page)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.store(c.lists, key, params)
+	for _, p := range params {
+		if p.DeletedDate.IsZero() { // deleted secrets are listed without values
+			c.store(c.params, p.Name, []Parameter{p})
+		}
+	}
+
+	return nil
+}
+
+func (c *cached) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
+	defer c.invalidate(service, name)
+	return c.inner.Set(ctx, service, name, value, isSecret, opts)
+}
+
+func (c *cached) Delete(ctx context.Context, service Service, name string) error {
+	defer c.invalidate(service, name)
+	return c.inner.Delete(ctx, service, name)
+}
+
+func (c *cached) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	defer func() {
+		for _, item := range items {
+			c.invalidate(service, item.Name)
+		}
+	}()
+	return c.inner.SetMany(ctx, service, items, opts)
+}
+
+func (c *cached) DeleteMany(ctx context.Context, service Service, names []string) error {
+	defer func() {
+		for _, name := range names {
+			c.invalidate(service, name)
+		}
+	}()
+	return c.inner.DeleteMany(ctx, service, names)
+}
+
+func (c *cached) Tag(ctx context.Context, service Service, name string) error {
+	return c.inner.Tag(ctx, service, name)
+}
@@ -0,0 +1,109 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guardian/devx-config/log"
+	"github.com/guardian/devx-config/store"
+	"github.com/guardian/devx-config/store/storetest"
+)
+
+func TestSSMConformance(t *testing.T) {
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+
+	storetest.TestStore(t, func(t *testing.T) store.Store {
+		return store.NewSSM(logger, storetest.NewSSMServer(t).Client())
+	})
+}
+
+func TestSSMTags(t *testing.T) {
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	server := storetest.NewSSMServer(t)
+	st := store.NewSSM(logger, server.Client()).WithTags(map[string]string{"Owner": "devx"})
+
+	if err := st.Set(context.Background(), storetest.Service, "key", "value", false, store.SetOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tags := server.Tags(storetest.Service.Prefix() + "/key")
+	if tags["App"] != "storetest" || tags["Owner"] != "devx" {
+		t.Errorf("got tags %v; want App and Owner", tags)
+	}
+
+	// Overwriting still succeeds if tagging is denied.
+	server.DenyTagging()
+	if err := st.Set(context.Background(), storetest.Service, "key", "new", false, store.SetOptions{}); err != nil {
+		t.Errorf("overwriting without permission to tag: unexpected error: %v", err)
+	}
+	if p, err := st.Get(context.Background(), storetest.Service, "key"); err != nil || p.Value != "new" {
+		t.Errorf("got %+v, %v; want the new value", p, err)
+	}
+}
+
+func TestFallbackConformance(t *testing.T) {
+	storetest.TestStore(t, func(t *testing.T) store.Store {
+		return store.Fallback(storetest.NewMemory(), storetest.NewMemory())
+	})
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	service := storetest.Service
+
+	ssm, secrets := storetest.NewMemory(), storetest.NewMemory()
+	for _, st := range []store.Store{ssm, secrets} {
+		for _, name := range []string{"db/host", "db/password"} {
+			if err := st.Set(ctx, service, name, "remote", false, store.SetOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := secrets.Set(ctx, service, "api-key", "secret", true, store.SetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	st := store.Fallback(store.Values("overrides", map[string]string{"db/host": "localhost"}), ssm, secrets)
+
+	if p, err := st.Get(ctx, service, "db/host"); err != nil || p.Value != "localhost" || p.Store != "overrides" {
+		t.Errorf("got %+v, %v; want the override", p, err)
+	}
+	if p, err := st.Get(ctx, service, "api-key"); err != nil || p.Value != "secret" {
+		t.Errorf("got %+v, %v; want the value from the last store", p, err)
+	}
+
+	params, err := st.GetMany(ctx, service, []string{"api-key", "db/host", "missing"})
+	if !errors.Is(err, store.ErrNotFound) || len(params) != 2 || params[0].Value != "secret" || params[1].Value != "localhost" {
+		t.Errorf("got %+v, %v; want api-key and db/host, with store.ErrNotFound", params, err)
+	}
+
+	params, err = st.List(ctx, service, store.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, p := range params {
+		got = append(got, p.RelativeName()+"="+p.Value)
+	}
+	if want := "db/host=localhost db/password=remote api-key=secret"; strings.Join(got, " ") != want {
+		t.Errorf("got %v; want %s", got, want)
+	}
+
+	// Writes go to the first store only.
+	if err := st.Set(ctx, service, "db/host", "new", false, store.SetOptions{}); !errors.Is(err, store.ErrReadOnly) {
+		t.Errorf("got %v; want store.ErrReadOnly", err)
+	}
+	if err := store.Fallback(ssm, secrets).Delete(ctx, service, "api-key"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("got %v; want store.ErrNotFound, as api-key isn't in the first store", err)
+	}
+}
+
+func TestCachedConformance(t *testing.T) {
+	storetest.TestStore(t, func(t *testing.T) store.Store {
+		return store.Cached(storetest.NewMemory(), time.Hour)
+	})
+}
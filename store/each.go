@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// Stop can be returned by the function given to Each to stop listing early,
+// without an error.
+var Stop = errors.New("stop listing")
+
+// Each calls fn with each of the service's parameters (as List) as their page
+// arrives, so that very large namespaces can be processed in constant memory
+// and without waiting for every page. Listing stops at the first error from
+// fn, which is returned unless it is Stop.
+func Each(ctx context.Context, st Store, service Service, f Filter, fn func(p Parameter) error) error {
+	err := st.ListPages(ctx, service, f, func(page []Parameter) error {
+		for _, p := range page {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, Stop) {
+		return nil
+	}
+
+	return err
+}
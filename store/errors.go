@@ -0,0 +1,72 @@
+package store
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// Errors from stores wrap one of these where the AWS error is recognised, so
+// callers can use errors.Is rather than checking AWS error codes (which
+// differ between SSM and Secrets Manager). The AWS error is still available
+// with errors.As.
+var (
+	ErrNotFound      = errors.New("not found")      // the parameter (or version) does not exist
+	ErrAccessDenied  = errors.New("access denied")  // credentials are invalid, expired, or lack permission
+	ErrThrottled     = errors.New("throttled")      // AWS rate limits were exceeded
+	ErrAlreadyExists = errors.New("already exists") // e.g. creating a parameter without overwriting
+	ErrReadOnly      = errors.New("read-only")      // the store can't be changed (e.g. one created by Values)
+)
+
+// Wraps an AWS error with its sentinel, keeping its message.
+type storeError struct {
+	sentinel error
+	err      error
+}
+
+func (e *storeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *storeError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
+
+// WrapError wraps err with the matching sentinel error, if any, for errors
+// from AWS calls made outside the stores (e.g. the registry lookup).
+func WrapError(err error) error {
+	return wrapError(err)
+}
+
+func wrapError(err error) error {
+	var apiErr smithy.APIError
+	if err == nil || !errors.As(err, &apiErr) {
+		return err
+	}
+
+	var existing *storeError
+	if errors.As(err, &existing) {
+		return err
+	}
+
+	switch apiErr.ErrorCode() {
+	case "ParameterNotFound", "ParameterVersionNotFound", "ResourceNotFoundException":
+		return &storeError{ErrNotFound, err}
+	case "AccessDeniedException", "AccessDenied", "UnrecognizedClientException", "ExpiredTokenException":
+		return &storeError{ErrAccessDenied, err}
+	case "ThrottlingException", "Throttling", "TooManyRequestsException", "TooManyUpdates":
+		return &storeError{ErrThrottled, err}
+	case "ParameterAlreadyExists", "ResourceExistsException":
+		return &storeError{ErrAlreadyExists, err}
+	case "InvalidRequestException":
+		// Secrets scheduled for deletion can't be read (or changed) until
+		// they are restored.
+		if strings.Contains(apiErr.ErrorMessage(), "marked for deletion") {
+			return &storeError{ErrNotFound, err}
+		}
+		return err
+	default:
+		return err
+	}
+}
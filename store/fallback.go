@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Fallback returns a store that reads from each of the stores in priority
+// order, e.g. local overrides, then SSM, then Secrets Manager. Get returns the
+// parameter from the first store that has it, and List returns every store's
+// parameters, with those in earlier stores shadowing any of the same name in
+// later ones. Set, Delete and Tag only act on primary.
+func Fallback(primary Store, fallbacks ...Store) Store {
+	return fallback(append([]Store{primary}, fallbacks...))
+}
+
+type fallback []Store
+
+func (f fallback) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	for _, st := range f {
+		p, err := st.Get(ctx, service, name)
+		if !errors.Is(err, ErrNotFound) {
+			return p, err
+		}
+	}
+
+	return Parameter{}, fmt.Errorf("'%s' not found in %s: %w", name, service.Prefix(), ErrNotFound)
+}
+
+func (f fallback) GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error) {
+	found := map[string]Parameter{}
+	missing := names
+	for _, st := range f {
+		if len(missing) == 0 {
+			break
+		}
+
+		params, err := st.GetMany(ctx, service, missing)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		for _, p := range params {
+			found[p.RelativeName()] = p
+		}
+
+		var still []string
+		for _, name := range missing {
+			if _, ok := found[name]; !ok {
+				still = append(still, name)
+			}
+		}
+		missing = still
+	}
+
+	params := []Parameter{}
+	for _, name := range names {
+		if p, ok := found[name]; ok {
+			params = append(params, p)
+		}
+	}
+
+	if len(missing) > 0 {
+		return params, fmt.Errorf("%v not found in %s: %w", missing, service.Prefix(), ErrNotFound)
+	}
+
+	return params, nil
+}
+
+func (f fallback) GetVersion(ctx context.Context, service Service, name string, version string) (Parameter, error) {
+	for _, st := range f {
+		if _, err := st.Get(ctx, service, name); errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		return st.GetVersion(ctx, service, name, version)
+	}
+
+	return Parameter{}, fmt.Errorf("'%s' not found in %s: %w", name, service.Prefix(), ErrNotFound)
+}
+
+func (f fallback) ListVersions(ctx context.Context, service Service, name string) ([]Version, error) {
+	for _, st := range f {
+		if _, err := st.Get(ctx, service, name); errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		return st.ListVersions(ctx, service, name)
+	}
+
+	return nil, fmt.Errorf("'%s' not found in %s: %w", name, service.Prefix(), ErrNotFound)
+}
+
+func (f fallback) List(ctx context.Context, service Service, filter Filter) ([]Parameter, error) {
+	params := []Parameter{}
+	err := f.ListPages(ctx, service, filter, func(page []Parameter) error {
+		params = append(params, page...)
+		return nil
+	})
+
+	return params, err
+}
+
+// Pages are passed on as each store lists them, less any parameters already
+// listed by an earlier store.
+func (f fallback) ListPages(ctx context.Context, service Service, filter Filter, fn func(page []Parameter) error) error {
+	seen := map[string]bool{}
+	for _, st := range f {
+		err := st.ListPages(ctx, service, filter, func(page []Parameter) error {
+			var unseen []Parameter
+			for _, p := range page {
+				if !seen[p.RelativeName()] {
+					seen[p.RelativeName()] = true
+					unseen = append(unseen, p)
+				}
+			}
+
+			if len(unseen) == 0 {
+				return nil
+			}
+			return fn(unseen)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f fallback) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
+	return f[0].Set(ctx, service, name, value, isSecret, opts)
+}
+
+func (f fallback) Delete(ctx context.Context, service Service, name string) error {
+	return f[0].Delete(ctx, service, name)
+}
+
+func (f fallback) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	return f[0].SetMany(ctx, service, items, opts)
+}
+
+func (f fallback) DeleteMany(ctx context.Context, service Service, names []string) error {
+	return f[0].DeleteMany(ctx, service, names)
+}
+
+func (f fallback) Tag(ctx context.Context, service Service, name string) error {
+	return f[0].Tag(ctx, service, name)
+}
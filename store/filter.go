@@ -0,0 +1,51 @@
+package store
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Filter narrows the parameters returned by List. Names are matched relative
+// to the service (e.g. 'db/password' rather than '/PROD/stack/app/db/password').
+// The zero value matches everything.
+type Filter struct {
+	Prefix   string
+	Contains string
+	Regex    *regexp.Regexp
+
+	// Secrets Manager only: also list secrets under the service's prefix that
+	// lack App, Stack and Stage tags (e.g. created before tagging).
+	IncludeUntagged bool
+
+	// Secrets Manager only: how to treat secrets scheduled for deletion.
+	Deleted DeletedMode
+}
+
+// DeletedMode says how secrets scheduled for deletion are listed. Their values
+// can't be read, so they are never listed by accident.
+type DeletedMode int
+
+const (
+	WarnDeleted    DeletedMode = iota // skipped, with a warning saying how many
+	IncludeDeleted                    // listed, with their deletion date but no value
+	ExcludeDeleted                    // skipped silently
+)
+
+func (f Filter) Match(p Parameter) bool {
+	name := p.RelativeName()
+
+	return strings.HasPrefix(name, f.Prefix) &&
+		strings.Contains(name, f.Contains) &&
+		(f.Regex == nil || f.Regex.MatchString(name))
+}
+
+func filter(params []Parameter, f Filter) []Parameter {
+	out := []Parameter{}
+	for _, p := range params {
+		if f.Match(p) {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Version is one version of a parameter. Values are deliberately left out, as
+// with list's table output.
+type Version struct {
+	ID           string   // for GetVersion: the SSM version number, or Secrets Manager version ID
+	Version      int64    // SSM only
+	Labels       []string // SSM labels, or Secrets Manager staging labels
+	LastModified time.Time
+	ModifiedBy   string // ARN of the IAM user or role
+
+	// From CloudTrail, if looked up (see history's --cloudtrail).
+	Principal string // ARN of the caller, e.g. including the role session name
+	SourceIP  string
+}
+
+// ListVersions returns every version of the parameter, oldest first.
+func (s SSM) ListVersions(ctx context.Context, service Service, name string) ([]Version, error) {
+	s.logger.Debugf("getting history of parameter '%s'", name)
+	pages := ssm.NewGetParameterHistoryPaginator(s.client, &ssm.GetParameterHistoryInput{
+		Name: aws.String(service.Prefix() + "/" + name),
+	})
+
+	var versions []Version
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		for _, p := range page.Parameters {
+			v := Version{ID: strconv.FormatInt(p.Version, 10), Version: p.Version, Labels: p.Labels, ModifiedBy: aws.StringValue(p.LastModifiedUser)}
+			if p.LastModifiedDate != nil {
+				v.LastModified = *p.LastModifiedDate
+			}
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+// Label attaches labels to a version of the parameter (the latest if version
+// is 0), moving them from any other version they were on.
+func (s SSM) Label(ctx context.Context, service Service, name string, version int64, labels []string) error {
+	input := &ssm.LabelParameterVersionInput{
+		Name:   aws.String(service.Prefix() + "/" + name),
+		Labels: labels,
+	}
+	if version > 0 {
+		input.ParameterVersion = aws.Int64(version)
+	}
+
+	s.logger.Debugf("labelling parameter '%s'", name)
+	output, err := s.client.LabelParameterVersion(ctx, input)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if len(output.InvalidLabels) > 0 {
+		return fmt.Errorf("invalid labels: %s", strings.Join(output.InvalidLabels, ", "))
+	}
+
+	return nil
+}
+
+// Unlabel removes labels from a version of the parameter.
+func (s SSM) Unlabel(ctx context.Context, service Service, name string, version int64, labels []string) error {
+	s.logger.Debugf("unlabelling parameter '%s'", name)
+	output, err := s.client.UnlabelParameterVersion(ctx, &ssm.UnlabelParameterVersionInput{
+		Name:             aws.String(service.Prefix() + "/" + name),
+		ParameterVersion: aws.Int64(version),
+		Labels:           labels,
+	})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if len(output.InvalidLabels) > 0 {
+		return fmt.Errorf("labels not on version %d: %s", version, strings.Join(output.InvalidLabels, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Call describes a store operation, for hooks.
+type Call struct {
+	Op      Operation
+	Service Service
+	Names   []string // the parameters' names, relative to the service (none for list)
+	Version string   // for get, if a specific version was asked for
+}
+
+// BeforeHook is called before each operation. Returning an error stops the
+// operation, which then fails with that error (e.g. for a dry run).
+type BeforeHook func(ctx context.Context, call Call) error
+
+// AfterHook is called after each operation (including those stopped by a
+// BeforeHook) with its error, if any, and how long it took.
+type AfterHook func(ctx context.Context, call Call, err error, took time.Duration)
+
+// WithHooks returns st with before and after (either of which may be nil)
+// called around each of its operations, so that cross-cutting concerns such as
+// audit logging, metrics or dry runs can be added to any store.
+func WithHooks(st Store, before BeforeHook, after AfterHook) Store {
+	return hooked{st, before, after}
+}
+
+type hooked struct {
+	st     Store
+	before BeforeHook
+	after  AfterHook
+}
+
+// Runs fn between the hooks.
+func (h hooked) run(ctx context.Context, call Call, fn func() error) error {
+	start := time.Now()
+
+	var err error
+	if h.before != nil {
+		err = h.before(ctx, call)
+	}
+	if err == nil {
+		err = fn()
+	}
+
+	if h.after != nil {
+		h.after(ctx, call, err, time.Since(start))
+	}
+
+	return err
+}
+
+func (h hooked) Get(ctx context.Context, service Service, name string) (p Parameter, err error) {
+	err = h.run(ctx, Call{Op: OpGet, Service: service, Names: []string{name}}, func() error {
+		p, err = h.st.Get(ctx, service, name)
+		return err
+	})
+
+	return p, err
+}
+
+func (h hooked) GetMany(ctx context.Context, service Service, names []string) (params []Parameter, err error) {
+	err = h.run(ctx, Call{Op: OpGet, Service: service, Names: names}, func() error {
+		params, err = h.st.GetMany(ctx, service, names)
+		return err
+	})
+
+	return params, err
+}
+
+func (h hooked) GetVersion(ctx context.Context, service Service, name string, version string) (p Parameter, err error) {
+	err = h.run(ctx, Call{Op: OpGet, Service: service, Names: []string{name}, Version: version}, func() error {
+		p, err = h.st.GetVersion(ctx, service, name, version)
+		return err
+	})
+
+	return p, err
+}
+
+func (h hooked) List(ctx context.Context, service Service, f Filter) (params []Parameter, err error) {
+	err = h.run(ctx, Call{Op: OpList, Service: service}, func() error {
+		params, err = h.st.List(ctx, service, f)
+		return err
+	})
+
+	return params, err
+}
+
+func (h hooked) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	return h.run(ctx, Call{Op: OpList, Service: service}, func() error {
+		return h.st.ListPages(ctx, service, f, fn)
+	})
+}
+
+func (h hooked) ListVersions(ctx context.Context, service Service, name string) (versions []Version, err error) {
+	err = h.run(ctx, Call{Op: OpVersions, Service: service, Names: []string{name}}, func() error {
+		versions, err = h.st.ListVersions(ctx, service, name)
+		return err
+	})
+
+	return versions, err
+}
+
+func (h hooked) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
+	return h.run(ctx, Call{Op: OpSet, Service: service, Names: []string{name}}, func() error {
+		return h.st.Set(ctx, service, name, value, isSecret, opts)
+	})
+}
+
+func (h hooked) Delete(ctx context.Context, service Service, name string) error {
+	return h.run(ctx, Call{Op: OpDelete, Service: service, Names: []string{name}}, func() error {
+		return h.st.Delete(ctx, service, name)
+	})
+}
+
+func (h hooked) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+
+	return h.run(ctx, Call{Op: OpSet, Service: service, Names: names}, func() error {
+		return h.st.SetMany(ctx, service, items, opts)
+	})
+}
+
+func (h hooked) DeleteMany(ctx context.Context, service Service, names []string) error {
+	return h.run(ctx, Call{Op: OpDelete, Service: service, Names: names}, func() error {
+		return h.st.DeleteMany(ctx, service, names)
+	})
+}
+
+func (h hooked) Tag(ctx context.Context, service Service, name string) error {
+	return h.run(ctx, Call{Op: OpTag, Service: service, Names: []string{name}}, func() error {
+		return h.st.Tag(ctx, service, name)
+	})
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONField returns a copy of the parameter with its value replaced by the
+// named field of its (JSON object) value. String fields are returned as-is,
+// anything else as JSON.
+func (c Parameter) JSONField(key string) (Parameter, error) {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(c.Value), &doc); err != nil {
+		return c, fmt.Errorf("value of '%s' is not a JSON object: %w", c.Name, err)
+	}
+
+	raw, ok := doc[key]
+	if !ok {
+		return c, fmt.Errorf("key '%s' not found in '%s'", key, c.Name)
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		c.Value = s
+	} else {
+		c.Value = string(raw)
+	}
+
+	return c, nil
+}
+
+// SetJSONField sets the named field of a JSON object (as a string), leaving all
+// other fields intact.
+func SetJSONField(value string, key string, fieldValue string) (string, error) {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		return "", fmt.Errorf("value is not a JSON object: %w", err)
+	}
+
+	raw, err := json.Marshal(fieldValue)
+	if err != nil {
+		return "", err
+	}
+
+	doc[key] = raw
+
+	out, err := json.Marshal(doc)
+	return string(out), err
+}
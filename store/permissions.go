@@ -0,0 +1,61 @@
+package store
+
+import "fmt"
+
+// Operation is a kind of store operation, for checking permissions before
+// making changes, and for hooks (see WithHooks).
+type Operation string
+
+const (
+	OpSet            Operation = "set"
+	OpDelete         Operation = "delete"
+	OpLabel          Operation = "label"
+	OpUnlabel        Operation = "unlabel"
+	OpPromoteReplica Operation = "promote-replica"
+
+	OpGet      Operation = "get" // including GetMany and GetVersion
+	OpList     Operation = "list"
+	OpTag      Operation = "tag"
+	OpVersions Operation = "versions"
+)
+
+// Actions returns the IAM actions a store needs for the operation. Set also
+// tags (for Secrets Manager, reconciling existing tags), and for Secrets
+// Manager may need secretsmanager:CreateSecret too (for new secrets), which
+// isn't included.
+func Actions(storeName string, op Operation) []string {
+	switch storeName + ":" + string(op) {
+	case "ssm:set":
+		return []string{"ssm:PutParameter", "ssm:AddTagsToResource"}
+	case "ssm:delete":
+		return []string{"ssm:DeleteParameter"}
+	case "ssm:tag":
+		return []string{"ssm:AddTagsToResource"}
+	case "ssm:label":
+		return []string{"ssm:LabelParameterVersion"}
+	case "ssm:unlabel":
+		return []string{"ssm:UnlabelParameterVersion"}
+	case "secretsmanager:set":
+		return []string{"secretsmanager:PutSecretValue", "secretsmanager:DescribeSecret", "secretsmanager:TagResource", "secretsmanager:UntagResource"}
+	case "secretsmanager:delete":
+		return []string{"secretsmanager:DeleteSecret"}
+	case "secretsmanager:tag":
+		return []string{"secretsmanager:DescribeSecret", "secretsmanager:TagResource", "secretsmanager:UntagResource"}
+	case "secretsmanager:promote-replica":
+		return []string{"secretsmanager:StopReplicationToReplica"}
+	default:
+		return nil
+	}
+}
+
+// ResourceARN returns the ARN of the parameter or secret with the given (full)
+// name. Secret ARNs end with a random suffix, so a placeholder is used, which
+// matches policies granting access to 'name-*' or 'name-??????'.
+func ResourceARN(storeName string, partition string, region string, account string, name string) string {
+	switch storeName {
+	case "secretsmanager":
+		return fmt.Sprintf("arn:%s:secretsmanager:%s:%s:secret:%s-XXXXXX", partition, region, account, name)
+	default:
+		return fmt.Sprintf("arn:%s:ssm:%s:%s:parameter%s", partition, region, account, name)
+	}
+}
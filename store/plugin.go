@@ -0,0 +1,286 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/log"
+)
+
+// PluginProtocol is the version of the plugin protocol, sent with every
+// request so that plugins can reject ones they don't understand.
+const PluginProtocol = 1
+
+// Plugin is a store provided by an external executable (see PluginPrefix),
+// so teams can add bespoke backends without forking devx-config. The
+// executable is run once per operation, with a JSON request on stdin, e.g.
+//
+//	{"protocol": 1, "op": "get", "service": {"app": "api", "stack": "deploy", "stage": "PROD", "prefix": "/PROD/deploy/api"}, "names": ["db/password"]}
+//
+// and must write a JSON response to stdout, e.g.
+//
+//	{"parameters": [{"name": "db/password", "value": "hunter2", "isSecret": true}]}
+//
+// Ops are 'get' (of each of names, leaving out any that don't exist, and of
+// version if given), 'list', 'set' (of names[0] to value, with isSecret and
+// tags), 'delete', 'tag' and 'versions' (of names[0]). Names are relative to
+// the service's prefix. Failures are reported as e.g.
+//
+//	{"error": {"code": "not_found", "message": "no such secret"}}
+//
+// where the code is 'not_found', 'access_denied', 'throttled',
+// 'already_exists' or anything else (for other errors).
+type Plugin struct {
+	logger log.Logger
+	name   string
+	path   string
+	tags   map[string]string
+}
+
+func NewPlugin(logger log.Logger, name string, path string) Plugin {
+	return Plugin{logger: logger, name: name, path: path}
+}
+
+// WithTags sets extra tags to send with set and tag requests.
+func (p Plugin) WithTags(tags map[string]string) Plugin {
+	p.tags = tags
+	return p
+}
+
+type pluginService struct {
+	App     string `json:"app"`
+	Stack   string `json:"stack"`
+	Stage   string `json:"stage"`
+	Account string `json:"account,omitempty"`
+	Prefix  string `json:"prefix"`
+}
+
+type pluginRequest struct {
+	Protocol int               `json:"protocol"`
+	Op       Operation         `json:"op"`
+	Service  pluginService     `json:"service"`
+	Names    []string          `json:"names,omitempty"`
+	Version  string            `json:"version,omitempty"`
+	Value    string            `json:"value,omitempty"`
+	IsSecret bool              `json:"isSecret,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+type pluginParameter struct {
+	Name         string    `json:"name"`
+	Value        string    `json:"value"`
+	IsSecret     bool      `json:"isSecret"`
+	Type         string    `json:"type"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+type pluginVersion struct {
+	ID           string    `json:"id"`
+	Labels       []string  `json:"labels"`
+	LastModified time.Time `json:"lastModified"`
+	ModifiedBy   string    `json:"modifiedBy"`
+}
+
+type pluginResponse struct {
+	Parameters []pluginParameter `json:"parameters"`
+	Versions   []pluginVersion   `json:"versions"`
+	Error      *PluginError      `json:"error"`
+}
+
+// PluginError is an error reported by a plugin.
+type PluginError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var pluginErrors = map[string]error{
+	"not_found":      ErrNotFound,
+	"access_denied":  ErrAccessDenied,
+	"throttled":      ErrThrottled,
+	"already_exists": ErrAlreadyExists,
+}
+
+// Runs the plugin with the request.
+func (p Plugin) call(ctx context.Context, req pluginRequest) (pluginResponse, error) {
+	req.Protocol = PluginProtocol
+
+	in, err := json.Marshal(req)
+	if err != nil {
+		return pluginResponse{}, err
+	}
+
+	p.logger.Debugf("running %s store plugin (%s) for %s", p.name, p.path, req.Op)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(in), &stdout, &stderr
+	runErr := cmd.Run()
+
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return resp, fmt.Errorf("%s store plugin failed: %w (%s)", p.name, runErr, strings.TrimSpace(stderr.String()))
+		}
+		return resp, fmt.Errorf("invalid response from %s store plugin: %w", p.name, err)
+	}
+
+	if resp.Error != nil {
+		if sentinel, ok := pluginErrors[resp.Error.Code]; ok {
+			return resp, &storeError{sentinel, resp.Error}
+		}
+		return resp, resp.Error
+	}
+
+	if runErr != nil {
+		return resp, fmt.Errorf("%s store plugin failed: %w (%s)", p.name, runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	return resp, nil
+}
+
+func (p Plugin) request(op Operation, service Service, names ...string) pluginRequest {
+	return pluginRequest{
+		Op:      op,
+		Service: pluginService{App: service.App, Stack: service.Stack, Stage: service.Stage, Account: service.Account, Prefix: service.Prefix()},
+		Names:   names,
+	}
+}
+
+func (p Plugin) parameters(service Service, params []pluginParameter) []Parameter {
+	out := make([]Parameter, 0, len(params))
+	for _, param := range params {
+		out = append(out, Parameter{
+			Service:      service,
+			Name:         service.Prefix() + "/" + param.Name,
+			Value:        param.Value,
+			IsSecret:     param.IsSecret,
+			Store:        p.name,
+			Type:         param.Type,
+			LastModified: param.LastModified,
+		})
+	}
+
+	return out
+}
+
+func (p Plugin) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	return p.GetVersion(ctx, service, name, "")
+}
+
+func (p Plugin) GetVersion(ctx context.Context, service Service, name string, version string) (Parameter, error) {
+	req := p.request(OpGet, service, name)
+	req.Version = version
+
+	resp, err := p.call(ctx, req)
+	if err != nil {
+		return Parameter{}, err
+	}
+
+	params := p.parameters(service, resp.Parameters)
+	if len(params) == 0 {
+		return Parameter{}, &storeError{ErrNotFound, fmt.Errorf("parameter '%s' not found", name)}
+	}
+
+	return params[0], nil
+}
+
+func (p Plugin) GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error) {
+	resp, err := p.call(ctx, p.request(OpGet, service, names...))
+	if err != nil {
+		return nil, err
+	}
+
+	params := p.parameters(service, resp.Parameters)
+	SortByNames(params, names)
+
+	found := map[string]bool{}
+	for _, param := range params {
+		found[param.RelativeName()] = true
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return params, &storeError{ErrNotFound, errors.New("parameters not found: " + strings.Join(missing, ", "))}
+	}
+
+	return params, nil
+}
+
+// List filters client-side, as plugins aren't sent the filter.
+func (p Plugin) List(ctx context.Context, service Service, f Filter) ([]Parameter, error) {
+	resp, err := p.call(ctx, p.request(OpList, service))
+	if err != nil {
+		return nil, err
+	}
+
+	return filter(p.parameters(service, resp.Parameters), f), nil
+}
+
+// ListPages calls fn once, as plugins return everything at once.
+func (p Plugin) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	params, err := p.List(ctx, service, f)
+	if err != nil {
+		return err
+	}
+
+	return fn(params)
+}
+
+func (p Plugin) ListVersions(ctx context.Context, service Service, name string) ([]Version, error) {
+	resp, err := p.call(ctx, p.request(OpVersions, service, name))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(resp.Versions))
+	for _, v := range resp.Versions {
+		versions = append(versions, Version{ID: v.ID, Labels: v.Labels, LastModified: v.LastModified, ModifiedBy: v.ModifiedBy})
+	}
+
+	return versions, nil
+}
+
+// Set ignores opts, which are SSM-specific.
+func (p Plugin) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
+	req := p.request(OpSet, service, name)
+	req.Value, req.IsSecret, req.Tags = value, isSecret, service.Tags(p.tags)
+
+	_, err := p.call(ctx, req)
+	return err
+}
+
+func (p Plugin) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	return SetConcurrently(ctx, p, service, items, opts)
+}
+
+func (p Plugin) DeleteMany(ctx context.Context, service Service, names []string) error {
+	return DeleteConcurrently(ctx, p, service, names)
+}
+
+func (p Plugin) Delete(ctx context.Context, service Service, name string) error {
+	_, err := p.call(ctx, p.request(OpDelete, service, name))
+	return err
+}
+
+func (p Plugin) Tag(ctx context.Context, service Service, name string) error {
+	req := p.request(OpTag, service, name)
+	req.Tags = service.Tags(p.tags)
+
+	_, err := p.call(ctx, req)
+	return err
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policies are SSM parameter policies, for keeping temporary values (e.g.
+// credentials) in check. Zero durations are omitted. Note, policies require
+// the advanced (or intelligent) tier.
+type Policies struct {
+	ExpireAfter        time.Duration // delete the parameter this long after it is set
+	NotifyBeforeExpiry time.Duration // send an EventBridge event this long before it expires
+	NotifyNoChange     time.Duration // send an EventBridge event if it isn't changed for this long
+}
+
+func (p Policies) IsZero() bool {
+	return p == Policies{}
+}
+
+// JSON returns the policies in the form expected by PutParameter, given the
+// time the parameter is being set.
+func (p Policies) JSON(now time.Time) (string, error) {
+	policies := []map[string]any{}
+
+	if p.ExpireAfter > 0 {
+		policies = append(policies, policy("Expiration", map[string]string{
+			"Timestamp": now.Add(p.ExpireAfter).UTC().Format(time.RFC3339),
+		}))
+	}
+
+	if p.NotifyBeforeExpiry > 0 {
+		if p.ExpireAfter == 0 {
+			return "", fmt.Errorf("an expiry notification requires an expiry")
+		}
+
+		after, unit := inUnits(p.NotifyBeforeExpiry)
+		policies = append(policies, policy("ExpirationNotification", map[string]string{"Before": after, "Unit": unit}))
+	}
+
+	if p.NotifyNoChange > 0 {
+		after, unit := inUnits(p.NotifyNoChange)
+		policies = append(policies, policy("NoChangeNotification", map[string]string{"After": after, "Unit": unit}))
+	}
+
+	data, err := json.Marshal(policies)
+	return string(data), err
+}
+
+func policy(policyType string, attributes map[string]string) map[string]any {
+	return map[string]any{"Type": policyType, "Version": "1.0", "Attributes": attributes}
+}
+
+// SSM policies are specified in whole days or hours.
+func inUnits(d time.Duration) (string, string) {
+	if d%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(d / (24 * time.Hour))), "Days"
+	}
+
+	return strconv.Itoa(int((d + time.Hour - 1) / time.Hour)), "Hours"
+}
+
+// ParseAge parses a duration as for time.ParseDuration, but also accepting
+// days, e.g. '90d'.
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days '%s'", s)
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
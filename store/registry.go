@@ -0,0 +1,68 @@
+package store
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/guardian/devx-config/internal/registry"
+	"github.com/guardian/devx-config/log"
+)
+
+// Config is what a Factory is given to create a store.
+type Config struct {
+	Logger log.Logger
+	AWS    aws.Config
+	KMSKey string            // optional
+	Tags   map[string]string // extra tags (see Service.Tags)
+}
+
+// Factory creates a store.
+type Factory func(cfg Config) (Store, error)
+
+// PluginPrefix is the prefix of executables (on the PATH) providing stores
+// that aren't registered, e.g. 'devx-config-store-vault' for a 'vault' store.
+// See Plugin.
+const PluginPrefix = "devx-config-store-"
+
+var factories registry.Registry[Factory]
+
+func init() {
+	Register("ssm", func(cfg Config) (Store, error) {
+		return NewSSM(cfg.Logger, ssm.NewFromConfig(cfg.AWS)).WithKMSKey(cfg.KMSKey).WithTags(cfg.Tags), nil
+	})
+	Register("secretsmanager", func(cfg Config) (Store, error) {
+		return NewSecretsManager(cfg.Logger, secretsmanager.NewFromConfig(cfg.AWS)).WithKMSKey(cfg.KMSKey).WithTags(cfg.Tags), nil
+	})
+}
+
+// Register makes a store available by name, replacing any already registered
+// with that name. It is typically called from an init function.
+func Register(name string, factory Factory) {
+	factories.Register(name, factory)
+}
+
+// Names returns the names of the registered stores, sorted.
+func Names() []string {
+	return factories.Names()
+}
+
+// Lookup returns the factory for the named store: a registered one, or else a
+// plugin executable (see PluginPrefix) on the PATH.
+func Lookup(name string) (Factory, error) {
+	if factory, ok := factories.Lookup(name); ok {
+		return factory, nil
+	}
+
+	if path, err := exec.LookPath(PluginPrefix + name); err == nil && name != "" {
+		return func(cfg Config) (Store, error) {
+			return NewPlugin(cfg.Logger, name, path).WithTags(cfg.Tags), nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown store '%s' (must be one of '%s', or a '%s%s' plugin on the PATH)", name, strings.Join(Names(), "', '"), PluginPrefix, name)
+}
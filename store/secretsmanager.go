@@ -0,0 +1,613 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/guardian/devx-config/log"
+)
+
+// SecretsManager stores parameters as AWS Secrets Manager secrets, named in the
+// same way as SSM parameters (i.e. '/:stage/:stack/:app/:name'). All values are
+// secrets.
+type SecretsManager struct {
+	logger log.Logger
+	client *secretsmanager.Client
+	kmsKey string
+	tags   map[string]string
+
+	withoutRecovery bool
+}
+
+func NewSecretsManager(logger log.Logger, client *secretsmanager.Client) SecretsManager {
+	return SecretsManager{logger: logger.With("store", "secretsmanager"), client: client}
+}
+
+// WithKMSKey returns the store set to encrypt new secrets with the given KMS
+// key. Existing secrets keep the key they were created with.
+func (s SecretsManager) WithKMSKey(key string) SecretsManager {
+	s.kmsKey = key
+	return s
+}
+
+// WithTags returns the store set to add these tags to secrets, in addition to
+// the service's App, Stack and Stage.
+func (s SecretsManager) WithTags(tags map[string]string) SecretsManager {
+	s.tags = tags
+	return s
+}
+
+// WithoutRecovery returns the store set to delete secrets immediately, rather
+// than after a recovery window, so their names can be reused at once (e.g. in
+// tests).
+func (s SecretsManager) WithoutRecovery() SecretsManager {
+	s.withoutRecovery = true
+	return s
+}
+
+func (s SecretsManager) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	return s.GetVersion(ctx, service, name, "")
+}
+
+// GetVersion gets a specific version of the secret by ID, or the current
+// version if versionID is empty.
+func (s SecretsManager) GetVersion(ctx context.Context, service Service, name string, versionID string) (Parameter, error) {
+	s.logger.Debugf("getting secret '%s'", name)
+	return s.getSecret(ctx, service, service.Prefix()+"/"+name, versionID, "")
+}
+
+// GetVersionStage gets the version of the secret with the staging label, e.g.
+// 'AWSPREVIOUS' (during rotation).
+func (s SecretsManager) GetVersionStage(ctx context.Context, service Service, name string, versionStage string) (Parameter, error) {
+	s.logger.Debugf("getting secret '%s' (%s)", name, versionStage)
+	return s.getSecret(ctx, service, service.Prefix()+"/"+name, "", versionStage)
+}
+
+// ListVersions returns the secret's versions, oldest first, including those
+// without staging labels (which Secrets Manager deletes eventually).
+func (s SecretsManager) ListVersions(ctx context.Context, service Service, name string) ([]Version, error) {
+	s.logger.Debugf("getting versions of secret '%s'", name)
+	pages := secretsmanager.NewListSecretVersionIdsPaginator(s.client, &secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(service.Prefix() + "/" + name),
+		IncludeDeprecated: aws.Bool(true),
+	})
+
+	var versions []Version
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		for _, v := range page.Versions {
+			version := Version{ID: aws.StringValue(v.VersionId), Labels: v.VersionStages}
+			if v.CreatedDate != nil {
+				version.LastModified = *v.CreatedDate
+			}
+			versions = append(versions, version)
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].LastModified.Before(versions[j].LastModified)
+	})
+
+	return versions, nil
+}
+
+// Gets a secret by its full name or ARN.
+func (s SecretsManager) getSecret(ctx context.Context, service Service, id string, versionID string, versionStage string) (Parameter, error) {
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+	if versionStage != "" {
+		input.VersionStage = aws.String(versionStage)
+	}
+
+	output, err := s.client.GetSecretValue(ctx, input)
+
+	if err != nil {
+		return Parameter{}, wrapError(err)
+	}
+
+	item := Parameter{
+		Service:  service,
+		Name:     aws.StringValue(output.Name),
+		Value:    aws.StringValue(output.SecretString),
+		IsSecret: true,
+		Store:    "secretsmanager",
+		Type:     "SecretString",
+		ARN:      aws.StringValue(output.ARN),
+	}
+
+	if output.CreatedDate != nil {
+		item.LastModified = *output.CreatedDate // of this version
+	}
+
+	return item, nil
+}
+
+// The most secrets BatchGetSecretValue gets in one call.
+const batchGetLimit = 20
+
+// Gets the current values of secrets, by full name, batchGetLimit at a time.
+// Secrets that can't be read are returned in failed, by name, rather than
+// failing the rest.
+func (s SecretsManager) batchGet(ctx context.Context, service Service, ids []string) (items []Parameter, failed map[string]error, err error) {
+	failed = map[string]error{}
+	for start := 0; start < len(ids); start += batchGetLimit {
+		output, err := s.client.BatchGetSecretValue(ctx, &secretsmanager.BatchGetSecretValueInput{
+			SecretIdList: ids[start:min(start+batchGetLimit, len(ids))],
+		})
+		if err != nil {
+			return nil, nil, wrapError(err)
+		}
+
+		for _, v := range output.SecretValues {
+			item := Parameter{
+				Service:  service,
+				Name:     aws.StringValue(v.Name),
+				Value:    aws.StringValue(v.SecretString),
+				IsSecret: true,
+				Store:    "secretsmanager",
+				Type:     "SecretString",
+				ARN:      aws.StringValue(v.ARN),
+			}
+			if v.CreatedDate != nil {
+				item.LastModified = *v.CreatedDate
+			}
+			items = append(items, item)
+		}
+
+		for _, e := range output.Errors {
+			failed[aws.StringValue(e.SecretId)] = wrapError(&smithy.GenericAPIError{
+				Code:    aws.StringValue(e.ErrorCode),
+				Message: aws.StringValue(e.Message),
+			})
+		}
+	}
+
+	return items, failed, nil
+}
+
+// GetMany gets the secrets in batches (see batchGet), in the order given.
+func (s SecretsManager) GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error) {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		ids[i] = service.Prefix() + "/" + name
+	}
+
+	s.logger.Debugf("getting %d secrets", len(names))
+	found, failed, err := s.batchGet(ctx, service, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]Parameter{}
+	for _, item := range found {
+		byName[item.Name] = item
+	}
+
+	var items []Parameter
+	var missing []string
+
+	for i, name := range names {
+		if err := failed[ids[i]]; errors.Is(err, ErrNotFound) {
+			missing = append(missing, name)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to get secret '%s': %w", name, err)
+		}
+
+		if item, ok := byName[ids[i]]; ok {
+			items = append(items, item)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return items, wrapError(&types.ResourceNotFoundException{Message: aws.String("secrets not found: " + strings.Join(missing, ", "))})
+	}
+
+	return items, nil
+}
+
+func (s SecretsManager) List(ctx context.Context, service Service, f Filter) ([]Parameter, error) {
+	var items []Parameter
+	err := s.ListPages(ctx, service, f, func(page []Parameter) error {
+		items = append(items, page...)
+		return nil
+	})
+
+	return items, err
+}
+
+// ListPages lists secrets a page at a time. Secrets are found by their App,
+// Stack and Stage tags, whatever they are named (e.g. by CDK), plus, with
+// f.IncludeUntagged, any under the service's prefix without those tags. Values
+// are read in batches (see batchGet), a page at a time.
+func (s SecretsManager) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	tags := service.Tags(nil)
+
+	// Filters are ANDed, but each tag filter matches any tag, so tags are
+	// checked again once listed.
+	var filters []types.Filter
+	for _, key := range []string{"App", "Stack", "Stage"} {
+		filters = append(filters,
+			types.Filter{Key: types.FilterNameStringTypeTagKey, Values: []string{key}},
+			types.Filter{Key: types.FilterNameStringTypeTagValue, Values: []string{tags[key]}},
+		)
+	}
+
+	s.logger.Debugf("listing secrets tagged App=%s, Stack=%s, Stage=%s", service.App, service.Stack, service.Stage)
+	deleted, err := s.listPages(ctx, service, f, filters, func(entry types.SecretListEntry) bool {
+		return hasTags(entry.Tags, tags)
+	}, fn)
+	if err != nil {
+		return err
+	}
+
+	if f.IncludeUntagged {
+		prefix := service.Prefix() + "/" + f.Prefix
+		s.logger.Debugf("listing untagged secrets under '%s'", prefix)
+		filters = []types.Filter{{Key: types.FilterNameStringTypeName, Values: []string{prefix}}}
+
+		n, err := s.listPages(ctx, service, f, filters, func(entry types.SecretListEntry) bool {
+			return !hasTags(entry.Tags, tags)
+		}, fn)
+		if err != nil {
+			return err
+		}
+		deleted += n
+	}
+
+	if deleted > 0 && f.Deleted == WarnDeleted {
+		s.logger.Warnf("Skipped %d secrets scheduled for deletion (use --include-deleted to list them, or --exclude-deleted to hide this warning).", deleted)
+	}
+
+	return nil
+}
+
+// Whether the secret has all the given tags.
+func hasTags(tags []types.Tag, want map[string]string) bool {
+	found := 0
+	for _, tag := range tags {
+		if v, ok := want[aws.StringValue(tag.Key)]; ok && v == aws.StringValue(tag.Value) {
+			found++
+		}
+	}
+
+	return found == len(want)
+}
+
+// Returns the number of matching secrets skipped as they are scheduled for
+// deletion.
+func (s SecretsManager) listPages(ctx context.Context, service Service, f Filter, filters []types.Filter, include func(types.SecretListEntry) bool, fn func(page []Parameter) error) (int, error) {
+	// Secrets scheduled for deletion are always listed, so that list can say
+	// how many it skipped.
+	pages := secretsmanager.NewListSecretsPaginator(s.client, &secretsmanager.ListSecretsInput{
+		Filters:                filters,
+		IncludePlannedDeletion: aws.Bool(true),
+	})
+
+	deleted := 0
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("unable to list secrets: %w", wrapError(err))
+		}
+
+		// Secrets to read, by their index in items.
+		live := map[int]types.SecretListEntry{}
+		var ids []string
+		items := []Parameter{}
+		for _, entry := range page.SecretList {
+			item := Parameter{Service: service, Name: aws.StringValue(entry.Name)}
+			if !include(entry) || !f.Match(item) {
+				continue
+			}
+
+			// Values of secrets scheduled for deletion can't be read.
+			if entry.DeletedDate != nil {
+				if f.Deleted != IncludeDeleted {
+					deleted++
+					continue
+				}
+
+				item.IsSecret = true
+				item.Store = "secretsmanager"
+				item.Type = "SecretString"
+				item.DeletedDate = *entry.DeletedDate
+				item.ARN = aws.StringValue(entry.ARN)
+				if entry.LastChangedDate != nil {
+					item.LastModified = *entry.LastChangedDate
+				}
+
+				items = append(items, item)
+				continue
+			}
+
+			live[len(items)] = entry
+			ids = append(ids, aws.StringValue(entry.Name))
+			items = append(items, item)
+		}
+
+		found, failed, err := s.batchGet(ctx, service, ids)
+		if err != nil {
+			return 0, fmt.Errorf("unable to get secrets: %w", err)
+		}
+
+		byName := map[string]Parameter{}
+		for _, item := range found {
+			byName[item.Name] = item
+		}
+
+		for i, entry := range live {
+			name := aws.StringValue(entry.Name)
+			if err := failed[name]; err != nil {
+				return 0, fmt.Errorf("unable to get secret '%s': %w", name, err)
+			}
+
+			item, ok := byName[name]
+			if !ok {
+				return 0, fmt.Errorf("unable to get secret '%s': %w", name, ErrNotFound)
+			}
+
+			if entry.LastChangedDate != nil {
+				item.LastModified = *entry.LastChangedDate
+			}
+
+			items[i] = item
+		}
+
+		if err := fn(items); err != nil {
+			return 0, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// Set updates the secret if it exists, or creates it otherwise. Note, isSecret
+// is ignored, as everything in Secrets Manager is a secret.
+func (s SecretsManager) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
+	id := service.Prefix() + "/" + name
+
+	if !opts.Policies.IsZero() {
+		s.logger.Warnf("ignoring parameter policies for '%s', as they are only supported by SSM", name)
+	}
+
+	s.logger.Debugf("putting secret value '%s'", name)
+	_, err := s.client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(id),
+		SecretString: aws.String(value),
+	})
+
+	var notFound *types.ResourceNotFoundException
+	if err == nil {
+		// The value has changed regardless, so failing to tag (e.g. without
+		// secretsmanager:TagResource) isn't an error.
+		if err := s.Tag(ctx, service, name); err != nil {
+			s.logger.Warnf("set '%s', but %v", name, err)
+		}
+		return nil
+	} else if !errors.As(err, &notFound) {
+		return wrapError(err)
+	}
+
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(id),
+		SecretString: aws.String(value),
+		Tags:         awsTags(s.desiredTags(service)),
+	}
+
+	if s.kmsKey != "" {
+		input.KmsKeyId = aws.String(s.kmsKey)
+	}
+
+	s.logger.Debugf("creating secret '%s'", name)
+	_, err = s.client.CreateSecret(ctx, input)
+
+	return wrapError(err)
+}
+
+// Tag key whose value lists the other tag keys devx-config manages on a secret
+// (space-separated), so that tags dropped from config can be removed without
+// touching tags set by other tools.
+const managedTagsKey = "devx-config:managed-tags"
+
+// Tag reconciles the secret's tags with the service's: changed tags are
+// updated, and tags devx-config set previously but that are no longer
+// configured are removed. If the secret can't be described, tags are only
+// added.
+func (s SecretsManager) Tag(ctx context.Context, service Service, name string) error {
+	id := service.Prefix() + "/" + name
+	desired := s.desiredTags(service)
+
+	var toTag []types.Tag
+	var toRemove []string
+
+	s.logger.Debugf("describing secret '%s'", name)
+	output, err := s.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(id)})
+	if err != nil {
+		s.logger.Warnf("unable to describe secret '%s', so not removing stale tags: %v", name, err)
+		toTag = awsTags(desired)
+	} else {
+		toTag, toRemove = reconcileTags(output.Tags, desired)
+	}
+
+	if len(toRemove) > 0 {
+		s.logger.Debugf("removing stale tags %s from secret '%s'", strings.Join(toRemove, ", "), name)
+		_, err := s.client.UntagResource(ctx, &secretsmanager.UntagResourceInput{
+			SecretId: aws.String(id),
+			TagKeys:  toRemove,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to remove stale tags: %w", wrapError(err))
+		}
+	}
+
+	if len(toTag) == 0 {
+		return nil
+	}
+
+	s.logger.Debugf("tagging secret '%s'", name)
+	_, err = s.client.TagResource(ctx, &secretsmanager.TagResourceInput{
+		SecretId: aws.String(id),
+		Tags:     toTag,
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to tag secret: %w", wrapError(err))
+	}
+
+	return nil
+}
+
+// The service's tags, plus the list of keys managed.
+func (s SecretsManager) desiredTags(service Service) map[string]string {
+	tags := service.Tags(s.tags)
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags[managedTagsKey] = strings.Join(keys, " ")
+
+	return tags
+}
+
+// Returns the tags to set (those missing or with a different value) and the
+// keys to remove (those previously managed, but no longer desired).
+func reconcileTags(current []types.Tag, desired map[string]string) ([]types.Tag, []string) {
+	existing := map[string]string{}
+	for _, tag := range current {
+		existing[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	changed := map[string]string{}
+	for k, v := range desired {
+		if value, ok := existing[k]; !ok || value != v {
+			changed[k] = v
+		}
+	}
+
+	var remove []string
+	for _, k := range strings.Fields(existing[managedTagsKey]) {
+		if _, ok := desired[k]; !ok {
+			if _, ok := existing[k]; ok {
+				remove = append(remove, k)
+			}
+		}
+	}
+	sort.Strings(remove)
+
+	return awsTags(changed), remove
+}
+
+func awsTags(tags map[string]string) []types.Tag {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := []types.Tag{}
+	for _, k := range keys {
+		out = append(out, types.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+
+	return out
+}
+
+// Secrets Manager has no batch API for changing secrets, so they are changed
+// concurrently.
+func (s SecretsManager) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	return SetConcurrently(ctx, s, service, items, opts)
+}
+
+func (s SecretsManager) DeleteMany(ctx context.Context, service Service, names []string) error {
+	return DeleteConcurrently(ctx, s, service, names)
+}
+
+// Delete schedules the secret for deletion, after Secrets Manager's default
+// recovery window (30 days), unless the store is set WithoutRecovery.
+func (s SecretsManager) Delete(ctx context.Context, service Service, name string) error {
+	s.logger.Debugf("deleting secret '%s'", name)
+	_, err := s.client.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(service.Prefix() + "/" + name),
+		ForceDeleteWithoutRecovery: aws.Bool(s.withoutRecovery),
+	})
+
+	return wrapError(err)
+}
+
+// Replica is the replication status of a secret in one region.
+type Replica struct {
+	Region       string
+	Primary      bool
+	Status       string // 'InSync', 'InProgress' or 'Failed' (empty for the primary)
+	Message      string
+	LastAccessed time.Time
+}
+
+// Replicas returns the secret's primary region followed by any regions it is
+// replicated to.
+func (s SecretsManager) Replicas(ctx context.Context, service Service, name string) ([]Replica, error) {
+	s.logger.Debugf("describing secret '%s'", name)
+	output, err := s.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(service.Prefix() + "/" + name),
+	})
+
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	replicas := []Replica{}
+	if output.PrimaryRegion != nil {
+		primary := Replica{Region: *output.PrimaryRegion, Primary: true}
+		if output.LastAccessedDate != nil {
+			primary.LastAccessed = *output.LastAccessedDate
+		}
+		replicas = append(replicas, primary)
+	}
+
+	for _, status := range output.ReplicationStatus {
+		replica := Replica{
+			Region:  aws.StringValue(status.Region),
+			Status:  string(status.Status),
+			Message: aws.StringValue(status.StatusMessage),
+		}
+		if status.LastAccessedDate != nil {
+			replica.LastAccessed = *status.LastAccessedDate
+		}
+		replicas = append(replicas, replica)
+	}
+
+	return replicas, nil
+}
+
+// PromoteReplica stops replication to the replica of the secret in the store's
+// region, making it a standalone (primary) secret. The store must therefore be
+// for the replica's region, not the primary's.
+func (s SecretsManager) PromoteReplica(ctx context.Context, service Service, name string) error {
+	s.logger.Debugf("promoting replica of secret '%s'", name)
+	_, err := s.client.StopReplicationToReplica(ctx, &secretsmanager.StopReplicationToReplicaInput{
+		SecretId: aws.String(service.Prefix() + "/" + name),
+	})
+
+	return wrapError(err)
+}
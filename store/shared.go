@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ParameterARN is a parsed SSM parameter ARN, e.g. of a parameter shared into
+// the account with AWS RAM.
+type ParameterARN struct {
+	ARN     string
+	Region  string
+	Account string // owner of the parameter
+	Name    string
+}
+
+// ParseParameterARN parses an SSM parameter ARN.
+func ParseParameterARN(s string) (ParameterARN, error) {
+	parsed, err := awsarn.Parse(s)
+	if err != nil || parsed.Service != "ssm" || !strings.HasPrefix(parsed.Resource, "parameter/") {
+		return ParameterARN{}, fmt.Errorf("'%s' is not an SSM parameter ARN (arn:aws:ssm:[region]:[account]:parameter/[name])", s)
+	}
+
+	// Hierarchical names start with '/', which the ARN doesn't repeat.
+	name := strings.TrimPrefix(parsed.Resource, "parameter/")
+	if strings.Contains(name, "/") {
+		name = "/" + name
+	}
+
+	return ParameterARN{ARN: s, Region: parsed.Region, Account: parsed.AccountID, Name: name}, nil
+}
+
+// GetShared gets a parameter by ARN, which is how parameters shared from other
+// accounts with AWS RAM are read. Its key is its base name, and SourceAccount
+// is the account that owns it. The store must be in the parameter's region.
+func (s SSM) GetShared(ctx context.Context, arn ParameterARN) (Parameter, error) {
+	s.logger.Debugf("getting shared parameter '%s'", arn.ARN)
+	output, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(arn.ARN),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return Parameter{}, wrapError(err)
+	}
+
+	item := asConfigItem(sharedService(arn.Name), *output.Parameter)
+	item.Name = arn.Name // rather than the ARN
+	item.SourceAccount = arn.Account
+
+	return item, nil
+}
+
+// Returns a service whose prefix is the parameter's path, so that it is keyed
+// by its base name. Parameter names can't contain '{', so are safe to use as
+// a template.
+func sharedService(name string) Service {
+	dir := path.Dir(name)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+
+	return Service{PrefixTemplate: template.Must(template.New("shared").Parse(dir))}
+}
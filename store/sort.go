@@ -0,0 +1,71 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+)
+
+type SortKey string
+
+const (
+	SortByName     SortKey = "name"
+	SortByModified SortKey = "modified" // most recently modified first
+	SortBySize     SortKey = "size"     // largest first
+)
+
+func (k SortKey) Validate() error {
+	switch k {
+	case SortByName, SortByModified, SortBySize:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sort key '%s' (must be one of 'name', 'modified', 'size')", k)
+	}
+}
+
+// Sort sorts params in place. Ties are broken by name.
+func Sort(params []Parameter, key SortKey, reverse bool) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+
+	less := func(a, b Parameter) bool { return false }
+
+	switch key {
+	case SortByModified:
+		less = func(a, b Parameter) bool { return a.LastModified.After(b.LastModified) }
+	case SortBySize:
+		less = func(a, b Parameter) bool { return len(a.Value) > len(b.Value) }
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		a, b := params[i], params[j]
+		if reverse {
+			a, b = b, a
+		}
+
+		if less(a, b) {
+			return true
+		}
+
+		if less(b, a) {
+			return false
+		}
+
+		return a.Name < b.Name
+	})
+
+	return nil
+}
+
+// SortByNames sorts params in place to match the order of names (relative to
+// the service), e.g. as requested by the user.
+func SortByNames(params []Parameter, names []string) {
+	index := map[string]int{}
+	for i, name := range names {
+		index[name] = i
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return index[params[i].RelativeName()] < index[params[j].RelativeName()]
+	})
+}
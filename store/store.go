@@ -2,8 +2,12 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
@@ -13,99 +17,457 @@ import (
 
 type Service struct {
 	Stack, Stage, App string
+	Account           string // optional; only used by prefix templates
+
+	// Used by Prefix if set (see ParsePrefixTemplate).
+	PrefixTemplate *template.Template
+}
+
+// DefaultPrefixTemplate gives the standard '/:stage/:stack/:app' scheme.
+const DefaultPrefixTemplate = "/{{.Stage}}/{{.Stack}}/{{.App}}"
+
+// Tags returns the tags for the service's parameters: App, Stack and Stage,
+// plus any extra ones (e.g. Owner), which take precedence.
+func (s Service) Tags(extra map[string]string) map[string]string {
+	tags := map[string]string{"App": s.App, "Stack": s.Stack, "Stage": s.Stage}
+	for k, v := range extra {
+		tags[k] = v
+	}
+
+	return tags
 }
 
+// Prefix is the path beneath which the service's parameters are stored, by
+// default '/:stage/:stack/:app'.
 func (s Service) Prefix() string {
-	return fmt.Sprintf("/%s/%s/%s", s.Stage, s.Stack, s.App)
+	if s.PrefixTemplate == nil {
+		return fmt.Sprintf("/%s/%s/%s", s.Stage, s.Stack, s.App)
+	}
+
+	var b strings.Builder
+	_ = s.PrefixTemplate.Execute(&b, s) // checked by ParsePrefixTemplate
+	return strings.TrimSuffix(b.String(), "/")
+}
+
+// ParsePrefixTemplate parses a Go template for service prefixes, e.g.
+// '/{{.Account}}/{{.Stage}}/{{.App}}', which is executed with the Service.
+func ParsePrefixTemplate(text string) (*template.Template, error) {
+	if !strings.HasPrefix(text, "/") {
+		return nil, fmt.Errorf("prefix template must start with '/' (got '%s')", text)
+	}
+
+	t, err := template.New("prefix").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Execute(io.Discard, Service{}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
 }
 
 type Parameter struct {
-	Service  Service
-	Name     string
-	Value    string
-	IsSecret bool
+	Service       Service
+	Name          string
+	Value         string
+	IsSecret      bool
+	Store         string // name of the store the parameter came from, e.g. 'ssm'
+	Region        string // set only when reading from several regions
+	Type          string // store-specific type, e.g. 'SecureString'
+	ARN           string // where the store gives it (SSM and Secrets Manager)
+	LastModified  time.Time
+	DeletedDate   time.Time // Secrets Manager only: when the secret was scheduled for deletion
+	SourceAccount string    // SSM only: the owning account, for a parameter shared with AWS RAM
+}
+
+// RelativeName is the name of the parameter without its service prefix.
+func (c Parameter) RelativeName() string {
+	return strings.TrimPrefix(c.Name, c.Service.Prefix()+"/")
+}
+
+// Key is the name of the parameter relative to its service, in a form suitable
+// for use as an environment variable.
+func (c Parameter) Key() string {
+	r := strings.NewReplacer(".", "_", "/", "_")
+	return r.Replace(c.RelativeName())
 }
 
+// String returns the parameter in 'k=v' (dotenv) form. Values containing
+// anything other than a conservative set of characters are double-quoted, with
+// '\', '"', '$' and '`' backslash-escaped and newlines written as '\n'. Single
+// line values are therefore also safe to evaluate in a POSIX shell; for
+// multi-line values use output.Export instead.
 func (c Parameter) String() string {
-	clean := func(s, prefix string) string {
-		r := strings.NewReplacer(prefix+"/", "", ".", "_", "/", "_")
-		return r.Replace(s)
+	value := c.Value
+	if strings.IndexFunc(value, isUnsafe) >= 0 {
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+		value = `"` + r.Replace(value) + `"`
 	}
 
-	return fmt.Sprintf("%s=%s", clean(c.Name, c.Service.Prefix()), c.Value)
+	return fmt.Sprintf("%s=%s", c.Key(), value)
+}
+
+func isUnsafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	default:
+		return !strings.ContainsRune("-_./:@%+,=", r)
+	}
 }
 
 type Store interface {
-	Get(service Service, name string) (Parameter, error)
-	List(service Service) ([]Parameter, error)
-	Set(service Service, name string, value string, isSecret bool) error
-	Delete(service Service, name string) error
+	Get(ctx context.Context, service Service, name string) (Parameter, error)
+
+	// GetMany gets several parameters at once, in the order given. Any that
+	// don't exist are left out, and reported with a not found error alongside
+	// those that do.
+	GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error)
+
+	List(ctx context.Context, service Service, filter Filter) ([]Parameter, error)
+	ListPages(ctx context.Context, service Service, filter Filter, fn func(page []Parameter) error) error
+	Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error
+	Delete(ctx context.Context, service Service, name string) error
+
+	// SetMany and DeleteMany change several parameters at once, using batch
+	// APIs where the store has them (or else SetConcurrently and
+	// DeleteConcurrently). Failures are reported per parameter, with a
+	// BatchError, and don't stop the others being changed.
+	SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error
+	DeleteMany(ctx context.Context, service Service, names []string) error
+
+	// Tag adds the service's tags (see Service.Tags) to an existing parameter.
+	Tag(ctx context.Context, service Service, name string) error
+
+	// GetVersion gets a specific version of the parameter, as identified by
+	// Version.ID, or the latest if version is empty.
+	GetVersion(ctx context.Context, service Service, name string, version string) (Parameter, error)
+
+	// ListVersions returns every version of the parameter, oldest first.
+	ListVersions(ctx context.Context, service Service, name string) ([]Version, error)
 }
 
+// SetOptions are optional, store-specific settings for Set. Stores ignore any
+// they don't support.
+type SetOptions struct {
+	Tier     Tier     // SSM only
+	Policies Policies // SSM only
+}
+
+// Tier is an SSM parameter tier. Standard parameters are free but limited to
+// 4KB; advanced ones (up to 8KB) are charged for.
+type Tier string
+
+const (
+	TierDefault     Tier = ""         // standard, or advanced if the value needs it
+	TierStandard    Tier = "standard" // fails if the value is too large
+	TierAdvanced    Tier = "advanced"
+	TierIntelligent Tier = "intelligent" // SSM picks the tier based on the value and policies
+)
+
+func (t Tier) Validate() error {
+	switch t {
+	case TierDefault, TierStandard, TierAdvanced, TierIntelligent:
+		return nil
+	default:
+		return fmt.Errorf("unsupported tier '%s' (must be one of 'standard', 'advanced', 'intelligent')", t)
+	}
+}
+
+// MaxStandardSize is the largest value (in bytes) a standard tier SSM parameter
+// can hold.
+const MaxStandardSize = 4096
+
 type SSM struct {
 	logger log.Logger
 	client *ssm.Client
+	kmsKey string
+	tags   map[string]string
 }
 
 func NewSSM(logger log.Logger, client *ssm.Client) SSM {
-	return SSM{logger, client}
+	return SSM{logger: logger.With("store", "ssm"), client: client}
 }
 
-func (s SSM) Get(service Service, name string) (Parameter, error) {
+// WithKMSKey returns the store set to encrypt secrets with the given KMS key
+// (an ID, ARN or alias, e.g. 'alias/my-key'), rather than the AWS-managed
+// default.
+func (s SSM) WithKMSKey(key string) SSM {
+	s.kmsKey = key
+	return s
+}
+
+// WithTags returns the store set to add these tags to parameters, in addition
+// to the service's App, Stack and Stage.
+func (s SSM) WithTags(tags map[string]string) SSM {
+	s.tags = tags
+	return s
+}
+
+func (s SSM) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	return s.GetVersion(ctx, service, name, "")
+}
+
+// GetVersion gets a specific version of the parameter, by number or label
+// (using the 'name:version' selector), or the latest if version is empty.
+func (s SSM) GetVersion(ctx context.Context, service Service, name string, version string) (Parameter, error) {
 	var item Parameter
 
-	output, err := s.client.GetParameter(context.TODO(), &ssm.GetParameterInput{
-		Name:           aws.String(service.Prefix() + "/" + name),
-		WithDecryption: true,
+	id := service.Prefix() + "/" + name
+	if version != "" {
+		id = id + ":" + version
+	}
+
+	s.logger.Debugf("getting parameter '%s'", name)
+	output, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(id),
+		WithDecryption: aws.Bool(true),
 	})
 
 	if err != nil {
-		return item, err
+		return item, wrapError(err)
 	}
 
 	return asConfigItem(service, *output.Parameter), nil
 }
 
-func (s SSM) List(service Service) ([]Parameter, error) {
+// The most parameters GetParameters accepts per call.
+const maxGetParameters = 10
+
+func (s SSM) GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error) {
+	var items []Parameter
+	var missing []string
+
+	for start := 0; start < len(names); start += maxGetParameters {
+		batch := []string{}
+		for _, name := range names[start:min(start+maxGetParameters, len(names))] {
+			batch = append(batch, service.Prefix()+"/"+name)
+		}
+
+		s.logger.Debugf("getting %d parameters", len(batch))
+		output, err := s.client.GetParameters(ctx, &ssm.GetParametersInput{
+			Names:          batch,
+			WithDecryption: aws.Bool(true),
+		})
+
+		if err != nil {
+			return nil, wrapError(err)
+		}
+
+		items = append(items, asConfigItems(service, output.Parameters)...)
+		for _, name := range output.InvalidParameters {
+			missing = append(missing, strings.TrimPrefix(name, service.Prefix()+"/"))
+		}
+	}
+
+	SortByNames(items, names)
+
+	if len(missing) > 0 {
+		return items, wrapError(&types.ParameterNotFound{Message: aws.String("parameters not found: " + strings.Join(missing, ", "))})
+	}
+
+	return items, nil
+}
+
+// List returns all parameters for the service, including those nested beneath
+// it (e.g. 'db/password'). Where the filter prefix is a whole path segment
+// (e.g. 'db/') it is used to narrow the request; otherwise filtering happens
+// client-side.
+func (s SSM) List(ctx context.Context, service Service, f Filter) ([]Parameter, error) {
+	var items []Parameter
+	err := s.ListPages(ctx, service, f, func(page []Parameter) error {
+		items = append(items, page...)
+		return nil
+	})
+
+	return items, err
+}
+
+// ListPages is like List but calls fn with each page of results as it
+// arrives, so that callers can show progress or stream output.
+func (s SSM) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	path := service.Prefix()
+	if i := strings.LastIndex(f.Prefix, "/"); i > 0 {
+		path += "/" + f.Prefix[:i]
+	}
+
+	s.logger.Debugf("listing parameters under '%s'", path)
 	pages := ssm.NewGetParametersByPathPaginator(s.client, &ssm.GetParametersByPathInput{
-		Path:           aws.String(service.Prefix()),
-		WithDecryption: true,
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
 	})
 
-	var items []Parameter
 	for pages.HasMorePages() {
-		page, err := pages.NextPage(context.TODO())
+		page, err := pages.NextPage(ctx)
 		if err != nil {
-			return items, fmt.Errorf("unable to get parameters: %w", err)
+			return fmt.Errorf("unable to get parameters: %w", wrapError(err))
 		}
 
-		items = append(items, asConfigItems(service, page.Parameters)...)
+		if err := fn(filter(asConfigItems(service, page.Parameters), f)); err != nil {
+			return err
+		}
 	}
 
-	return items, nil
+	return nil
 }
 
-func (s SSM) Set(service Service, name string, value string, isSecret bool) error {
+// Set creates or updates the parameter. Values too large for the standard tier
+// are stored as advanced parameters (with a warning, as these cost money)
+// unless a tier is given.
+func (s SSM) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
 	paramType := types.ParameterTypeString
 	if isSecret {
 		paramType = types.ParameterTypeSecureString
 	}
 
-	_, err := s.client.PutParameter(context.TODO(), &ssm.PutParameterInput{
+	// Note, the tier is left unset by default so that updating an existing
+	// advanced parameter (which can't be downgraded) doesn't fail.
+	var tier types.ParameterTier
+	switch opts.Tier {
+	case TierDefault:
+		if len(value) > MaxStandardSize {
+			s.logger.Warnf("value of '%s' is %d bytes, over the %d byte limit for standard parameters, so storing it as an advanced parameter (which is charged for)", name, len(value), MaxStandardSize)
+			tier = types.ParameterTierAdvanced
+		} else if !opts.Policies.IsZero() {
+			s.logger.Warnf("storing '%s' as an advanced parameter (which is charged for), as policies require it", name)
+			tier = types.ParameterTierAdvanced
+		}
+	case TierStandard:
+		if len(value) > MaxStandardSize {
+			return fmt.Errorf("value is %d bytes, over the %d byte limit for standard parameters (use --tier=advanced)", len(value), MaxStandardSize)
+		}
+		if !opts.Policies.IsZero() {
+			return fmt.Errorf("parameter policies aren't supported by standard parameters (use --tier=advanced)")
+		}
+		tier = types.ParameterTierStandard
+	case TierAdvanced:
+		tier = types.ParameterTierAdvanced
+	case TierIntelligent:
+		tier = types.ParameterTierIntelligentTiering
+	default:
+		return opts.Tier.Validate()
+	}
+
+	input := &ssm.PutParameterInput{
 		Name:  aws.String(service.Prefix() + "/" + name),
 		Value: &value,
 		Type:  paramType,
+		Tier:  tier,
+		Tags:  s.awsTags(service),
+	}
+
+	if isSecret && s.kmsKey != "" {
+		input.KeyId = aws.String(s.kmsKey)
+	}
+
+	if !opts.Policies.IsZero() {
+		policies, err := opts.Policies.JSON(time.Now())
+		if err != nil {
+			return err
+		}
+		input.Policies = aws.String(policies)
+	}
+
+	// PutParameter only takes tags when creating a parameter, so it is
+	// created with them, or, if it exists, overwritten and then tagged.
+	s.logger.Debugf("putting parameter '%s' (type %s, tier '%s')", name, paramType, tier)
+	_, err := s.client.PutParameter(ctx, input)
+
+	var exists *types.ParameterAlreadyExists
+	if err == nil {
+		return nil
+	} else if !errors.As(err, &exists) {
+		return wrapError(err)
+	}
+
+	input.Tags = nil
+	input.Overwrite = aws.Bool(true)
+	if _, err := s.client.PutParameter(ctx, input); err != nil {
+		return wrapError(err)
+	}
+
+	// The value has changed regardless, so failing to tag (e.g. without
+	// ssm:AddTagsToResource) isn't an error.
+	if err := s.Tag(ctx, service, name); err != nil {
+		s.logger.Warnf("set '%s', but %v", name, err)
+	}
+
+	return nil
+}
+
+func (s SSM) awsTags(service Service) []types.Tag {
+	tags := []types.Tag{}
+	for k, v := range service.Tags(s.tags) {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	return tags
+}
+
+func (s SSM) Tag(ctx context.Context, service Service, name string) error {
+	s.logger.Debugf("tagging parameter '%s'", name)
+	_, err := s.client.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+		ResourceType: types.ResourceTypeForTaggingParameter,
+		ResourceId:   aws.String(service.Prefix() + "/" + name),
+		Tags:         s.awsTags(service),
 	})
 
-	return err
+	if err != nil {
+		return fmt.Errorf("unable to tag parameter: %w", wrapError(err))
+	}
+
+	return nil
 }
 
-func (s SSM) Delete(service Service, name string) error {
-	_, err := s.client.DeleteParameter(context.TODO(), &ssm.DeleteParameterInput{
+func (s SSM) Delete(ctx context.Context, service Service, name string) error {
+	s.logger.Debugf("deleting parameter '%s'", name)
+	_, err := s.client.DeleteParameter(ctx, &ssm.DeleteParameterInput{
 		Name: aws.String(service.Prefix() + "/" + name),
 	})
 
-	return err
+	return wrapError(err)
+}
+
+// SSM has no batch API for putting parameters, so they are set concurrently.
+func (s SSM) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	return SetConcurrently(ctx, s, service, items, opts)
+}
+
+// The most parameters DeleteParameters accepts per call.
+const maxDeleteParameters = 10
+
+func (s SSM) DeleteMany(ctx context.Context, service Service, names []string) error {
+	errs := make([]error, len(names))
+	for start := 0; start < len(names); start += maxDeleteParameters {
+		end := min(start+maxDeleteParameters, len(names))
+
+		batch := []string{}
+		for _, name := range names[start:end] {
+			batch = append(batch, service.Prefix()+"/"+name)
+		}
+
+		s.logger.Debugf("deleting %d parameters", len(batch))
+		output, err := s.client.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: batch})
+		if err != nil {
+			for i := start; i < end; i++ {
+				errs[i] = wrapError(err)
+			}
+			continue
+		}
+
+		for _, invalid := range output.InvalidParameters {
+			for i := start; i < end; i++ {
+				if batch[i-start] == invalid {
+					errs[i] = wrapError(&types.ParameterNotFound{Message: aws.String("parameter not found: " + names[i])})
+				}
+			}
+		}
+	}
+
+	return batchError(names, errs)
 }
 
 func asConfigItems(service Service, params []types.Parameter) []Parameter {
@@ -118,10 +480,19 @@ func asConfigItems(service Service, params []types.Parameter) []Parameter {
 }
 
 func asConfigItem(service Service, param types.Parameter) Parameter {
-	return Parameter{
+	item := Parameter{
 		Name:     *param.Name,
 		Value:    *param.Value,
 		IsSecret: param.Type == types.ParameterTypeSecureString,
 		Service:  service,
+		Store:    "ssm",
+		Type:     string(param.Type),
+		ARN:      aws.StringValue(param.ARN),
 	}
+
+	if param.LastModifiedDate != nil {
+		item.LastModified = *param.LastModifiedDate
+	}
+
+	return item
 }
@@ -0,0 +1,659 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+
+	"github.com/guardian/devx-config/log"
+)
+
+func TestParameterString(t *testing.T) {
+	service := Service{Stack: "deploy", Stage: "PROD", App: "example"}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"bar", "db_password=bar"},
+		{"line 1\nline \"2\"", `db_password="line 1\nline \"2\""`},
+		{"it's $HOME", `db_password="it's \$HOME"`},
+	}
+
+	for _, tc := range tests {
+		p := Parameter{Service: service, Name: "/PROD/deploy/example/db.password", Value: tc.value}
+		if got := p.String(); got != tc.want {
+			t.Errorf("got: %s; want %s", got, tc.want)
+		}
+	}
+}
+
+func TestPrefixTemplate(t *testing.T) {
+	tmpl, err := ParsePrefixTemplate("/{{.Account}}/{{.Stage}}/{{.App}}")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	service := Service{Stack: "deploy", Stage: "PROD", App: "example", Account: "123456789012", PrefixTemplate: tmpl}
+	if got := service.Prefix(); got != "/123456789012/PROD/example" {
+		t.Errorf("got: %s; want /123456789012/PROD/example", got)
+	}
+
+	if _, err := ParsePrefixTemplate("/{{.Region}}/{{.App}}"); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func TestPoliciesJSON(t *testing.T) {
+	expireAfter, err := ParseAge("90d")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	p := Policies{ExpireAfter: expireAfter, NotifyBeforeExpiry: 14 * 24 * time.Hour, NotifyNoChange: 36 * time.Hour}
+	got, err := p.JSON(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `[{"Attributes":{"Timestamp":"2023-04-01T00:00:00Z"},"Type":"Expiration","Version":"1.0"},` +
+		`{"Attributes":{"Before":"14","Unit":"Days"},"Type":"ExpirationNotification","Version":"1.0"},` +
+		`{"Attributes":{"After":"36","Unit":"Hours"},"Type":"NoChangeNotification","Version":"1.0"}]`
+	if got != want {
+		t.Fatalf("got: %s; want %s", got, want)
+	}
+}
+
+func TestServiceTags(t *testing.T) {
+	service := Service{Stack: "deploy", Stage: "PROD", App: "example"}
+
+	got := service.Tags(map[string]string{"Owner": "devx"})
+	want := map[string]string{"App": "example", "Stack": "deploy", "Stage": "PROD", "Owner": "devx"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v; want %v", got, want)
+	}
+}
+
+func TestSortByNames(t *testing.T) {
+	service := Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	params := []Parameter{
+		{Service: service, Name: "/PROD/deploy/example/b"},
+		{Service: service, Name: "/PROD/deploy/example/c"},
+		{Service: service, Name: "/PROD/deploy/example/a"},
+	}
+
+	SortByNames(params, []string{"c", "a", "b"})
+
+	got := []string{params[0].RelativeName(), params[1].RelativeName(), params[2].RelativeName()}
+	if !reflect.DeepEqual(got, []string{"c", "a", "b"}) {
+		t.Fatalf("got: %v; want [c a b]", got)
+	}
+}
+
+func TestSetJSONField(t *testing.T) {
+	got, err := SetJSONField(`{"username":"admin","port":5432}`, "password", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"password":"secret","port":5432,"username":"admin"}`
+	if got != want {
+		t.Fatalf("got: %s; want %s", got, want)
+	}
+
+	p, err := Parameter{Name: "db", Value: got}.JSONField("port")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if p.Value != "5432" {
+		t.Fatalf("got: %s; want 5432", p.Value)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	service := Service{Stack: "deploy", Stage: "PROD", App: "example"}
+	p := Parameter{Service: service, Name: "/PROD/deploy/example/db/token"}
+
+	tests := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{Prefix: "db/"}, true},
+		{Filter{Prefix: "api/"}, false},
+		{Filter{Contains: "tok"}, true},
+		{Filter{Regex: regexp.MustCompile(`^feature_`)}, false},
+	}
+
+	for _, tc := range tests {
+		if got := tc.filter.Match(p); got != tc.want {
+			t.Errorf("%+v: got %v; want %v", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	now := time.Now()
+	params := []Parameter{
+		{Name: "b", Value: "1", LastModified: now.Add(-time.Hour)},
+		{Name: "a", Value: "123", LastModified: now.Add(-2 * time.Hour)},
+		{Name: "c", Value: "12", LastModified: now},
+	}
+
+	tests := []struct {
+		key     SortKey
+		reverse bool
+		want    string
+	}{
+		{SortByName, false, "abc"},
+		{SortByName, true, "cba"},
+		{SortByModified, false, "cba"},
+		{SortBySize, false, "acb"},
+	}
+
+	for _, tc := range tests {
+		if err := Sort(params, tc.key, tc.reverse); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := ""
+		for _, p := range params {
+			got += p.Name
+		}
+
+		if got != tc.want {
+			t.Errorf("%s (reverse=%v): got %s; want %s", tc.key, tc.reverse, got, tc.want)
+		}
+	}
+}
+
+func TestSecretsManagerBatchGet(t *testing.T) {
+	prefix := "/PROD/deploy/example/"
+	tags := `"Tags": [{"Key": "App", "Value": "example"}, {"Key": "Stack", "Value": "deploy"}, {"Key": "Stage", "Value": "PROD"}]`
+
+	var list []string
+	for i := 0; i < 23; i++ {
+		list = append(list, fmt.Sprintf(`{"Name": "%skey%02d", "LastChangedDate": 1700000000, %s}`, prefix, i, tags))
+	}
+
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if r.Header.Get("X-Amz-Target") == "secretsmanager.ListSecrets" {
+			_, _ = w.Write([]byte(`{"SecretList": [` + strings.Join(list, ",") + `]}`))
+			return
+		}
+
+		var input struct{ SecretIdList []string }
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		batches = append(batches, len(input.SecretIdList))
+
+		var values, errs []string
+		for _, id := range input.SecretIdList {
+			if strings.HasSuffix(id, "missing") {
+				errs = append(errs, fmt.Sprintf(`{"SecretId": %q, "ErrorCode": "ResourceNotFoundException", "Message": "not found"}`, id))
+				continue
+			}
+			values = append(values, fmt.Sprintf(`{"ARN": "arn:%s", "Name": %q, "SecretString": "value of %s", "CreatedDate": 1600000000}`, id, id, id))
+		}
+		_, _ = w.Write([]byte(`{"SecretValues": [` + strings.Join(values, ",") + `], "Errors": [` + strings.Join(errs, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client := secretsmanager.New(secretsmanager.Options{
+		Region:           "eu-west-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolver: secretsmanager.EndpointResolverFromURL(server.URL),
+	})
+
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	st := NewSecretsManager(logger, client)
+	service := Service{Stage: "PROD", Stack: "deploy", App: "example"}
+
+	items, err := st.List(context.Background(), service, Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(batches, []int{20, 3}) {
+		t.Errorf("got batches of %v; want 20 then 3", batches)
+	}
+	if len(items) != 23 || items[0].Value != "value of "+prefix+"key00" || items[22].Name != prefix+"key22" {
+		t.Fatalf("got %+v; want 23 secrets in order", items)
+	}
+	if items[0].ARN != "arn:"+prefix+"key00" {
+		t.Errorf("got ARN %q; want the secret's ARN", items[0].ARN)
+	}
+	if !items[0].LastModified.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("got %v; want the time last changed, from the list", items[0].LastModified)
+	}
+
+	batches = nil
+	got, err := st.GetMany(context.Background(), service, []string{"b", "missing", "a"})
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("got %v; want the missing secret reported", err)
+	}
+	if len(got) != 2 || got[0].Value != "value of "+prefix+"b" || got[1].Value != "value of "+prefix+"a" || len(batches) != 1 {
+		t.Errorf("got %+v in %d batches; want b then a, in one batch", got, len(batches))
+	}
+}
+
+func TestHasTags(t *testing.T) {
+	want := map[string]string{"App": "example", "Stack": "deploy", "Stage": "PROD"}
+	tag := func(k, v string) smtypes.Tag { return smtypes.Tag{Key: aws.String(k), Value: aws.String(v)} }
+
+	tagged := []smtypes.Tag{tag("App", "example"), tag("Stack", "deploy"), tag("Stage", "PROD"), tag("Owner", "team")}
+	if !hasTags(tagged, want) {
+		t.Error("expected a match with all tags present")
+	}
+
+	wrongStage := []smtypes.Tag{tag("App", "example"), tag("Stack", "deploy"), tag("Stage", "CODE")}
+	if hasTags(wrongStage, want) {
+		t.Error("expected no match with a different stage")
+	}
+}
+
+func TestActions(t *testing.T) {
+	ops := []Operation{OpSet, OpDelete, OpLabel, OpUnlabel, OpTag}
+	for _, op := range ops {
+		if len(Actions("ssm", op)) == 0 {
+			t.Errorf("ssm: no actions for %s", op)
+		}
+	}
+
+	ops = []Operation{OpSet, OpDelete, OpTag, OpPromoteReplica}
+	for _, op := range ops {
+		if len(Actions("secretsmanager", op)) == 0 {
+			t.Errorf("secretsmanager: no actions for %s", op)
+		}
+	}
+
+	if got := Actions("vault", OpSet); got != nil {
+		t.Errorf("unknown store: got %v; want no actions", got)
+	}
+}
+
+func TestResourceARN(t *testing.T) {
+	tests := []struct {
+		store, want string
+	}{
+		{"ssm", "arn:aws:ssm:eu-west-1:123456789012:parameter/PROD/deploy/example/key"},
+		{"secretsmanager", "arn:aws:secretsmanager:eu-west-1:123456789012:secret:/PROD/deploy/example/key-XXXXXX"},
+	}
+
+	for _, tc := range tests {
+		if got := ResourceARN(tc.store, "aws", "eu-west-1", "123456789012", "/PROD/deploy/example/key"); got != tc.want {
+			t.Errorf("%s: got %s; want %s", tc.store, got, tc.want)
+		}
+	}
+}
+
+func TestReconcileTags(t *testing.T) {
+	current := []smtypes.Tag{
+		{Key: aws.String("App"), Value: aws.String("example")},
+		{Key: aws.String("Stage"), Value: aws.String("CODE")},
+		{Key: aws.String("Owner"), Value: aws.String("old-team")},
+		{Key: aws.String("Other"), Value: aws.String("set by another tool")},
+		{Key: aws.String(managedTagsKey), Value: aws.String("App Owner Stage")},
+	}
+	desired := map[string]string{"App": "example", "Stage": "PROD", managedTagsKey: "App Stage"}
+
+	toTag, toRemove := reconcileTags(current, desired)
+
+	want := []smtypes.Tag{
+		{Key: aws.String("Stage"), Value: aws.String("PROD")},
+		{Key: aws.String(managedTagsKey), Value: aws.String("App Stage")},
+	}
+	if !reflect.DeepEqual(toTag, want) {
+		t.Errorf("to tag: got %+v; want %+v", toTag, want)
+	}
+	if !reflect.DeepEqual(toRemove, []string{"Owner"}) {
+		t.Errorf("to remove: got %v; want [Owner]", toRemove)
+	}
+
+	// Tags set before keys were recorded are left alone.
+	_, toRemove = reconcileTags(current[:4], desired)
+	if len(toRemove) != 0 {
+		t.Errorf("to remove: got %v; want nothing without a record of managed keys", toRemove)
+	}
+}
+
+func TestParseParameterARN(t *testing.T) {
+	got, err := ParseParameterARN("arn:aws:ssm:eu-west-1:111122223333:parameter/platform/PROD/vpc-id")
+	want := ParameterARN{ARN: "arn:aws:ssm:eu-west-1:111122223333:parameter/platform/PROD/vpc-id", Region: "eu-west-1", Account: "111122223333", Name: "/platform/PROD/vpc-id"}
+	if err != nil || got != want {
+		t.Errorf("got %+v, %v; want %+v", got, err, want)
+	}
+
+	if _, err := ParseParameterARN("arn:aws:secretsmanager:eu-west-1:111122223333:secret:db-AbCdEf"); err == nil {
+		t.Errorf("expected an error for a secret ARN")
+	}
+}
+
+func TestSharedServiceKey(t *testing.T) {
+	tests := map[string]string{
+		"/platform/PROD/vpc-id": "vpc-id",
+		"/vpc-id":               "vpc-id",
+		"vpc-id":                "vpc-id",
+	}
+
+	for name, want := range tests {
+		p := Parameter{Service: sharedService(name), Name: name}
+		if got := p.Key(); got != want {
+			t.Errorf("%s: got key %s; want %s", name, got, want)
+		}
+	}
+}
+
+// Returns each of values in turn from Get, then the last one.
+type sequenceStore struct {
+	Store
+	values []string
+	errs   []error
+	reads  int
+}
+
+func (s *sequenceStore) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	i := min(s.reads, len(s.values)-1)
+	s.reads++
+	return Parameter{Name: name, Value: s.values[i]}, s.errs[i]
+}
+
+func TestVerify(t *testing.T) {
+	notFound := &ssmtypes.ParameterNotFound{Message: aws.String("not found")}
+
+	st := &sequenceStore{values: []string{"", "old", "new"}, errs: []error{notFound, nil, nil}}
+	if err := Verify(context.Background(), st, Service{}, "key", "new", 5, 0); err != nil || st.reads != 3 {
+		t.Errorf("got %v after %d reads; want success after 3", err, st.reads)
+	}
+
+	st = &sequenceStore{values: []string{"old"}, errs: []error{nil}}
+	if err := Verify(context.Background(), st, Service{}, "key", "new", 3, 0); err == nil || st.reads != 3 {
+		t.Errorf("got %v after %d reads; want failure after 3", err, st.reads)
+	}
+
+	denied := errors.New("AccessDeniedException: not allowed to decrypt")
+	st = &sequenceStore{values: []string{""}, errs: []error{denied}}
+	if err := Verify(context.Background(), st, Service{}, "key", "new", 3, 0); !errors.Is(err, denied) || st.reads != 1 {
+		t.Errorf("got %v after %d reads; want the read error, without retrying", err, st.reads)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	st = &sequenceStore{values: []string{"old"}, errs: []error{nil}}
+	if err := Verify(ctx, st, Service{}, "key", "new", 3, time.Hour); !errors.Is(err, context.Canceled) || st.reads != 1 {
+		t.Errorf("got %v after %d reads; want cancellation before retrying", err, st.reads)
+	}
+}
+
+func TestSecretsManagerListVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Versions": [
+			{"VersionId": "b", "VersionStages": ["AWSCURRENT"], "CreatedDate": 1700000200},
+			{"VersionId": "a", "CreatedDate": 1700000100}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := secretsmanager.New(secretsmanager.Options{
+		Region:           "eu-west-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolver: secretsmanager.EndpointResolverFromURL(server.URL),
+	})
+
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	versions, err := NewSecretsManager(logger, client).ListVersions(context.Background(), Service{Stage: "PROD", Stack: "deploy", App: "example"}, "KEY")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(versions) != 2 || versions[0].ID != "a" || versions[1].ID != "b" || versions[1].Labels[0] != "AWSCURRENT" {
+		t.Errorf("got %+v; want versions a then b (AWSCURRENT), oldest first", versions)
+	}
+}
+
+// Returns each of pages in turn from ListPages.
+type pagesStore struct {
+	Store
+	pages [][]Parameter
+	read  int
+}
+
+func (s *pagesStore) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	for _, page := range s.pages {
+		s.read++
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestEach(t *testing.T) {
+	st := &pagesStore{pages: [][]Parameter{{{Name: "a"}, {Name: "b"}}, {{Name: "c"}}, {{Name: "d"}}}}
+
+	var names []string
+	err := Each(context.Background(), st, Service{}, Filter{}, func(p Parameter) error {
+		names = append(names, p.Name)
+		if p.Name == "c" {
+			return Stop
+		}
+		return nil
+	})
+
+	if err != nil || !reflect.DeepEqual(names, []string{"a", "b", "c"}) || st.read != 2 {
+		t.Errorf("got %v, %v after %d pages; want a, b, c without error after 2", names, err, st.read)
+	}
+
+	failed := errors.New("failed")
+	err = Each(context.Background(), st, Service{}, Filter{}, func(p Parameter) error { return failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("got %v; want the error from fn", err)
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{&ssmtypes.ParameterNotFound{Message: aws.String("gone")}, ErrNotFound},
+		{&smtypes.ResourceNotFoundException{Message: aws.String("gone")}, ErrNotFound},
+		{&smithy.GenericAPIError{Code: "AccessDeniedException"}, ErrAccessDenied},
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, ErrThrottled},
+		{&ssmtypes.ParameterAlreadyExists{Message: aws.String("exists")}, ErrAlreadyExists},
+		{&smtypes.ResourceExistsException{Message: aws.String("exists")}, ErrAlreadyExists},
+		{&smtypes.InvalidRequestException{Message: aws.String("You can't perform this operation on the secret because it was marked for deletion.")}, ErrNotFound},
+	}
+
+	for _, tc := range tests {
+		got := wrapError(tc.err)
+		if !errors.Is(got, tc.want) {
+			t.Errorf("%v: got %v; want it to wrap %v", tc.err, got, tc.want)
+		}
+
+		var apiErr smithy.APIError
+		if !errors.As(got, &apiErr) || got.Error() != tc.err.Error() {
+			t.Errorf("%v: got %v; want the AWS error and message kept", tc.err, got)
+		}
+	}
+
+	other := &smithy.GenericAPIError{Code: "ValidationException"}
+	if got := wrapError(other); got != other {
+		t.Errorf("got %v; want unrecognised errors unchanged", got)
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	inner := &sequenceStore{values: []string{"value"}, errs: []error{nil}}
+
+	var calls []Call
+	var errs []error
+	readOnly := func(ctx context.Context, call Call) error {
+		if call.Op == OpSet {
+			return errors.New("read-only")
+		}
+		return nil
+	}
+	after := func(ctx context.Context, call Call, err error, took time.Duration) {
+		calls = append(calls, call)
+		errs = append(errs, err)
+	}
+	st := WithHooks(inner, readOnly, after)
+
+	p, err := st.Get(context.Background(), Service{}, "key")
+	if err != nil || p.Value != "value" || inner.reads != 1 {
+		t.Errorf("got %v, %v after %d reads; want the inner store's value", p, err, inner.reads)
+	}
+
+	if err := st.Set(context.Background(), Service{}, "key", "new", false, SetOptions{}); err == nil || err.Error() != "read-only" {
+		t.Errorf("got %v; want the before hook's error", err)
+	}
+
+	want := []Call{{Op: OpGet, Names: []string{"key"}}, {Op: OpSet, Names: []string{"key"}}}
+	if !reflect.DeepEqual(calls, want) || errs[0] != nil || errs[1] == nil {
+		t.Errorf("got calls %+v with errors %v; want %+v, the second failing", calls, errs, want)
+	}
+}
+
+// Writes a plugin that records its request and writes response.
+func writePlugin(t *testing.T, response string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, PluginPrefix+"fake")
+	script := "#!/bin/sh\ncat > " + filepath.Join(dir, "request.json") + "\necho '" + response + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, filepath.Join(dir, "request.json")
+}
+
+func TestPlugin(t *testing.T) {
+	dir, requestPath := writePlugin(t, `{"parameters": [{"name": "db/password", "value": "hunter2", "isSecret": true}]}`)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	factory, err := Lookup("fake")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	st, err := factory(Config{Logger: logger})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service := Service{Stage: "PROD", Stack: "deploy", App: "example"}
+	p, err := st.Get(context.Background(), service, "db/password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "/PROD/deploy/example/db/password" || p.Value != "hunter2" || !p.IsSecret || p.Store != "fake" {
+		t.Errorf("got %+v; want db/password=hunter2 from the fake store", p)
+	}
+
+	data, err := os.ReadFile(requestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"protocol":1,"op":"get","service":{"app":"example","stack":"deploy","stage":"PROD","prefix":"/PROD/deploy/example"},"names":["db/password"]}`
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("got request %s; want %s", got, want)
+	}
+
+	if _, err := st.GetMany(context.Background(), service, []string{"db/password", "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v; want missing parameters reported as not found", err)
+	}
+
+	if _, err := Lookup("nonexistent"); err == nil {
+		t.Errorf("unknown store: expected an error")
+	}
+}
+
+func TestPluginError(t *testing.T) {
+	dir, _ := writePlugin(t, `{"error": {"code": "access_denied", "message": "no token"}}`)
+	logger, _ := log.New(io.Discard, "info", log.FormatPlain)
+	st := NewPlugin(logger, "fake", filepath.Join(dir, PluginPrefix+"fake"))
+
+	err := st.Delete(context.Background(), Service{}, "key")
+	var pluginErr *PluginError
+	if !errors.Is(err, ErrAccessDenied) || !errors.As(err, &pluginErr) || pluginErr.Message != "no token" {
+		t.Errorf("got %v; want the plugin's access denied error", err)
+	}
+}
+
+func TestCached(t *testing.T) {
+	ctx := context.Background()
+	inner := &sequenceStore{values: []string{"old", "new"}, errs: []error{nil, nil}}
+	now := time.Now()
+
+	st := Cached(inner, time.Minute).(*cached)
+	st.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if p, err := st.Get(ctx, Service{}, "key"); err != nil || p.Value != "old" || inner.reads != 1 {
+			t.Errorf("got %v, %v after %d reads; want old from the cache", p, err, inner.reads)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if p, err := st.Get(ctx, Service{}, "key"); err != nil || p.Value != "new" || inner.reads != 2 {
+		t.Errorf("got %v, %v after %d reads; want new, once expired", p, err, inner.reads)
+	}
+
+	failed := errors.New("failed")
+	inner = &sequenceStore{values: []string{"", "value"}, errs: []error{failed, nil}}
+	st = Cached(inner, time.Minute).(*cached)
+	if _, err := st.Get(ctx, Service{}, "key"); !errors.Is(err, failed) {
+		t.Errorf("got %v; want the error", err)
+	}
+	if p, err := st.Get(ctx, Service{}, "key"); err != nil || p.Value != "value" || inner.reads != 2 {
+		t.Errorf("got %v, %v after %d reads; want the error not to be cached", p, err, inner.reads)
+	}
+}
+
+func TestCachedList(t *testing.T) {
+	ctx := context.Background()
+	inner := &pagesStore{pages: [][]Parameter{{{Name: "/TEST/s/a/x", Value: "1"}}, {{Name: "/TEST/s/a/y", Value: "2"}}}}
+	st := Cached(inner, time.Minute)
+	service := Service{Stage: "TEST", Stack: "s", App: "a"}
+
+	// Stopping early caches nothing.
+	_ = Each(ctx, st, service, Filter{}, func(p Parameter) error { return Stop })
+	for i := 0; i < 2; i++ {
+		params, err := st.List(ctx, service, Filter{})
+		if err != nil || len(params) != 2 {
+			t.Errorf("got %v, %v; want both parameters", params, err)
+		}
+	}
+	if inner.read != 3 {
+		t.Errorf("read %d pages; want 3, with the complete list cached", inner.read)
+	}
+
+	if p, err := st.Get(ctx, service, "y"); err != nil || p.Value != "2" {
+		t.Errorf("got %v, %v; want y from the cached list", p, err)
+	}
+}
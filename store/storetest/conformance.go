@@ -0,0 +1,213 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// Service is the service TestStore uses; Other is used to check that
+// services are kept apart.
+var (
+	Service = store.Service{App: "storetest", Stack: "conformance", Stage: "TEST"}
+	Other   = store.Service{App: "other", Stack: "conformance", Stage: "TEST"}
+)
+
+// TestStore checks that a store behaves as the Store interface requires,
+// e.g. that missing parameters are reported with store.ErrNotFound. newStore
+// is called for each subtest, and must return an empty store. For example:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.TestStore(t, func(t *testing.T) store.Store {
+//			return NewMyStore(newFakeClient(t))
+//		})
+//	}
+func TestStore(t *testing.T, newStore func(t *testing.T) store.Store) {
+	ctx := context.Background()
+
+	set := func(t *testing.T, st store.Store, service store.Service, name string, value string, isSecret bool) {
+		t.Helper()
+		if err := st.Set(ctx, service, name, value, isSecret, store.SetOptions{}); err != nil {
+			t.Fatalf("set %s: %v", name, err)
+		}
+	}
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "plain", "value", false)
+		set(t, st, Service, "db/password", "hunter2", true)
+
+		p, err := st.Get(ctx, Service, "plain")
+		if err != nil || p.Value != "value" || p.IsSecret || p.RelativeName() != "plain" {
+			t.Errorf("got %+v, %v; want plain=value, not secret", p, err)
+		}
+
+		p, err = st.Get(ctx, Service, "db/password")
+		if err != nil || p.Value != "hunter2" || !p.IsSecret || p.RelativeName() != "db/password" {
+			t.Errorf("got %+v, %v; want db/password=hunter2, secret", p, err)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Other, "missing", "elsewhere", false)
+
+		if _, err := st.Get(ctx, Service, "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("got %v; want store.ErrNotFound", err)
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "key", "old", false)
+		set(t, st, Service, "key", "new", false)
+
+		if p, err := st.Get(ctx, Service, "key"); err != nil || p.Value != "new" {
+			t.Errorf("got %+v, %v; want new", p, err)
+		}
+	})
+
+	t.Run("GetMany", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "a", "1", false)
+		set(t, st, Service, "b", "2", false)
+
+		params, err := st.GetMany(ctx, Service, []string{"b", "a"})
+		if err != nil || names(params) != "b a" {
+			t.Errorf("got %s, %v; want b a, in the order given", names(params), err)
+		}
+
+		params, err = st.GetMany(ctx, Service, []string{"a", "missing"})
+		if !errors.Is(err, store.ErrNotFound) || names(params) != "a" {
+			t.Errorf("got %s, %v; want a, with store.ErrNotFound", names(params), err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "a", "1", false)
+		set(t, st, Service, "db/password", "2", true)
+		set(t, st, Service, "db/user", "3", false)
+		set(t, st, Other, "b", "4", false)
+
+		params, err := st.List(ctx, Service, store.Filter{})
+		if err != nil || sortedNames(params) != "a db/password db/user" {
+			t.Errorf("got %s, %v; want a db/password db/user", sortedNames(params), err)
+		}
+
+		params, err = st.List(ctx, Service, store.Filter{Prefix: "db/"})
+		if err != nil || sortedNames(params) != "db/password db/user" {
+			t.Errorf("prefix: got %s, %v; want db/password db/user", sortedNames(params), err)
+		}
+
+		var paged []store.Parameter
+		err = st.ListPages(ctx, Service, store.Filter{Contains: "user"}, func(page []store.Parameter) error {
+			paged = append(paged, page...)
+			return nil
+		})
+		if err != nil || sortedNames(paged) != "db/user" {
+			t.Errorf("pages: got %s, %v; want db/user", sortedNames(paged), err)
+		}
+
+		stop := errors.New("stop")
+		err = st.ListPages(ctx, Service, store.Filter{}, func(page []store.Parameter) error { return stop })
+		if !errors.Is(err, stop) {
+			t.Errorf("pages: got %v; want the error from fn", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "key", "value", false)
+
+		if err := st.Delete(ctx, Service, "key"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := st.Get(ctx, Service, "key"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("after delete: got %v; want store.ErrNotFound", err)
+		}
+		if err := st.Delete(ctx, Service, "key"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("delete again: got %v; want store.ErrNotFound", err)
+		}
+	})
+
+	t.Run("SetManyAndDeleteMany", func(t *testing.T) {
+		st := newStore(t)
+		items := []store.Item{{Name: "a", Value: "1"}, {Name: "b", Value: "2", IsSecret: true}, {Name: "c", Value: "3"}}
+		if err := st.SetMany(ctx, Service, items, store.SetOptions{}); err != nil {
+			t.Fatalf("set many: %v", err)
+		}
+
+		params, err := st.GetMany(ctx, Service, []string{"a", "b", "c"})
+		if err != nil || names(params) != "a b c" || params[1].Value != "2" || !params[1].IsSecret {
+			t.Errorf("got %+v, %v; want a, b (secret) and c", params, err)
+		}
+
+		err = st.DeleteMany(ctx, Service, []string{"a", "missing", "c"})
+		var batchErr store.BatchError
+		if !errors.As(err, &batchErr) || len(batchErr) != 1 || !errors.Is(batchErr["missing"], store.ErrNotFound) {
+			t.Errorf("delete many: got %v; want a store.BatchError of just 'missing', with store.ErrNotFound", err)
+		}
+
+		params, _ = st.List(ctx, Service, store.Filter{})
+		if sortedNames(params) != "b" {
+			t.Errorf("after delete many: got %s; want b", sortedNames(params))
+		}
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "key", "value", false)
+
+		if err := st.Tag(ctx, Service, "key"); err != nil {
+			t.Errorf("got %v; want success", err)
+		}
+		if err := st.Tag(ctx, Service, "missing"); err == nil {
+			t.Errorf("missing: expected an error")
+		}
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		st := newStore(t)
+		set(t, st, Service, "key", "first", false)
+		set(t, st, Service, "key", "second", false)
+
+		versions, err := st.ListVersions(ctx, Service, "key")
+		if err != nil || len(versions) < 2 {
+			t.Fatalf("got %+v, %v; want two versions", versions, err)
+		}
+
+		first, last := versions[0], versions[len(versions)-1]
+		if p, err := st.GetVersion(ctx, Service, "key", first.ID); err != nil || p.Value != "first" {
+			t.Errorf("oldest: got %+v, %v; want first", p, err)
+		}
+		if p, err := st.GetVersion(ctx, Service, "key", last.ID); err != nil || p.Value != "second" {
+			t.Errorf("latest: got %+v, %v; want second", p, err)
+		}
+
+		if _, err := st.GetVersion(ctx, Service, "key", "999"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("missing version: got %v; want store.ErrNotFound", err)
+		}
+	})
+}
+
+// Relative names, space-separated.
+func names(params []store.Parameter) string {
+	out := make([]string, 0, len(params))
+	for _, p := range params {
+		out = append(out, p.RelativeName())
+	}
+
+	return strings.Join(out, " ")
+}
+
+func sortedNames(params []store.Parameter) string {
+	sorted := append([]store.Parameter{}, params...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	return names(sorted)
+}
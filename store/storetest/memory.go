@@ -0,0 +1,194 @@
+// Package storetest helps test stores, and code that uses them: Memory is an
+// in-memory Store, SSMServer fakes the parts of the SSM API that store.SSM
+// uses, and TestStore is a conformance suite that every store should pass.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type memoryVersion struct {
+	value    string
+	isSecret bool
+	modified time.Time
+}
+
+// Memory is an in-memory Store, safe for concurrent use. Versions are numbered
+// from 1, as in SSM. Use NewMemory to create one.
+type Memory struct {
+	mu     sync.Mutex
+	params map[string][]memoryVersion // by full name
+	tags   map[string]map[string]string
+	now    func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{params: map[string][]memoryVersion{}, tags: map[string]map[string]string{}, now: time.Now}
+}
+
+// Tags returns the tags of the named parameter (relative to the service).
+func (m *Memory) Tags(service store.Service, name string) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tags[service.Prefix()+"/"+name]
+}
+
+func notFound(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{store.ErrNotFound}, args...)...)
+}
+
+func (m *Memory) parameter(service store.Service, fullName string, v memoryVersion) store.Parameter {
+	return store.Parameter{Service: service, Name: fullName, Value: v.value, IsSecret: v.isSecret, Store: "memory", LastModified: v.modified}
+}
+
+func (m *Memory) Get(ctx context.Context, service store.Service, name string) (store.Parameter, error) {
+	return m.GetVersion(ctx, service, name, "")
+}
+
+// GetVersion gets the version by number, or the latest if version is empty.
+func (m *Memory) GetVersion(ctx context.Context, service store.Service, name string, version string) (store.Parameter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fullName := service.Prefix() + "/" + name
+	versions, ok := m.params[fullName]
+	if !ok {
+		return store.Parameter{}, notFound("parameter '%s' not found", name)
+	}
+
+	if version == "" {
+		return m.parameter(service, fullName, versions[len(versions)-1]), nil
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 || n > len(versions) {
+		return store.Parameter{}, notFound("version '%s' of '%s' not found", version, name)
+	}
+
+	return m.parameter(service, fullName, versions[n-1]), nil
+}
+
+func (m *Memory) GetMany(ctx context.Context, service store.Service, names []string) ([]store.Parameter, error) {
+	var params []store.Parameter
+	var missing []string
+	for _, name := range names {
+		p, err := m.Get(ctx, service, name)
+		if err != nil {
+			missing = append(missing, name)
+			continue
+		}
+		params = append(params, p)
+	}
+
+	if len(missing) > 0 {
+		return params, notFound("parameters not found: %s", strings.Join(missing, ", "))
+	}
+
+	return params, nil
+}
+
+func (m *Memory) List(ctx context.Context, service store.Service, f store.Filter) ([]store.Parameter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	params := []store.Parameter{}
+	for fullName, versions := range m.params {
+		if !strings.HasPrefix(fullName, service.Prefix()+"/") {
+			continue
+		}
+
+		p := m.parameter(service, fullName, versions[len(versions)-1])
+		if f.Match(p) {
+			params = append(params, p)
+		}
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+
+	return params, nil
+}
+
+// ListPages calls fn once, with every parameter.
+func (m *Memory) ListPages(ctx context.Context, service store.Service, f store.Filter, fn func(page []store.Parameter) error) error {
+	params, err := m.List(ctx, service, f)
+	if err != nil {
+		return err
+	}
+
+	return fn(params)
+}
+
+// Set ignores opts.
+func (m *Memory) Set(ctx context.Context, service store.Service, name string, value string, isSecret bool, opts store.SetOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fullName := service.Prefix() + "/" + name
+	m.params[fullName] = append(m.params[fullName], memoryVersion{value: value, isSecret: isSecret, modified: m.now()})
+	m.tags[fullName] = service.Tags(nil)
+
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, service store.Service, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fullName := service.Prefix() + "/" + name
+	if _, ok := m.params[fullName]; !ok {
+		return notFound("parameter '%s' not found", name)
+	}
+
+	delete(m.params, fullName)
+	delete(m.tags, fullName)
+	return nil
+}
+
+func (m *Memory) SetMany(ctx context.Context, service store.Service, items []store.Item, opts store.SetOptions) error {
+	return store.SetConcurrently(ctx, m, service, items, opts)
+}
+
+func (m *Memory) DeleteMany(ctx context.Context, service store.Service, names []string) error {
+	return store.DeleteConcurrently(ctx, m, service, names)
+}
+
+func (m *Memory) Tag(ctx context.Context, service store.Service, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fullName := service.Prefix() + "/" + name
+	if _, ok := m.params[fullName]; !ok {
+		return notFound("parameter '%s' not found", name)
+	}
+
+	m.tags[fullName] = service.Tags(nil)
+	return nil
+}
+
+// ListVersions returns the versions oldest first.
+func (m *Memory) ListVersions(ctx context.Context, service store.Service, name string) ([]store.Version, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	versions, ok := m.params[service.Prefix()+"/"+name]
+	if !ok {
+		return nil, notFound("parameter '%s' not found", name)
+	}
+
+	out := make([]store.Version, 0, len(versions))
+	for i, v := range versions {
+		out = append(out, store.Version{ID: strconv.Itoa(i + 1), Version: int64(i + 1), LastModified: v.modified})
+	}
+
+	return out, nil
+}
+
+var _ store.Store = (*Memory)(nil)
@@ -0,0 +1,248 @@
+package storetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMServer fakes the SSM API operations that store.SSM uses (other than
+// labelling and shared parameters), holding parameters in memory.
+type SSMServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	params map[string][]ssmParameter // by name, oldest version first
+	tags   map[string]map[string]string
+
+	denyTagging bool
+}
+
+type ssmParameter struct {
+	Name             string   `json:"Name"`
+	Value            string   `json:"Value"`
+	Type             string   `json:"Type"`
+	Version          int64    `json:"Version"`
+	Labels           []string `json:"Labels,omitempty"`
+	LastModifiedDate float64  `json:"LastModifiedDate"`
+	LastModifiedUser string   `json:"LastModifiedUser,omitempty"`
+}
+
+// NewSSMServer starts a fake SSM API, which is closed when the test ends.
+func NewSSMServer(t testing.TB) *SSMServer {
+	s := &SSMServer{params: map[string][]ssmParameter{}, tags: map[string]map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Client returns an SSM client for the server.
+func (s *SSMServer) Client() *ssm.Client {
+	return ssm.New(ssm.Options{
+		Region:           "eu-west-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolver: ssm.EndpointResolverFromURL(s.URL),
+	})
+}
+
+// Tags returns the tags of the parameter (by full name).
+func (s *SSMServer) Tags(name string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tags[name]
+}
+
+// DenyTagging makes AddTagsToResource fail, as for a caller without
+// ssm:AddTagsToResource.
+func (s *SSMServer) DenyTagging() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.denyTagging = true
+}
+
+type ssmError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+type ssmRequest struct {
+	Name, Path, ResourceId, Value, Type, NextToken string
+	Names                                          []string
+	Overwrite                                      bool
+	MaxResults                                     int
+	Tags                                           []struct{ Key, Value string }
+}
+
+func (s *SSMServer) serve(w http.ResponseWriter, r *http.Request) {
+	var in ssmRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeSSM(w, http.StatusBadRequest, ssmError{"ValidationException", err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSSM.")
+	switch op {
+	case "GetParameter":
+		p, err := s.get(in.Name)
+		if err != nil {
+			writeSSM(w, http.StatusBadRequest, err)
+			return
+		}
+		writeSSM(w, http.StatusOK, map[string]any{"Parameter": p})
+	case "GetParameters":
+		params, invalid := []ssmParameter{}, []string{}
+		for _, name := range in.Names {
+			if p, err := s.get(name); err == nil {
+				params = append(params, p)
+			} else {
+				invalid = append(invalid, name)
+			}
+		}
+		writeSSM(w, http.StatusOK, map[string]any{"Parameters": params, "InvalidParameters": invalid})
+	case "GetParametersByPath":
+		var names []string
+		for name := range s.params {
+			if strings.HasPrefix(name, strings.TrimSuffix(in.Path, "/")+"/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		var params []ssmParameter
+		for _, name := range names {
+			versions := s.params[name]
+			params = append(params, versions[len(versions)-1])
+		}
+		writeSSM(w, http.StatusOK, paginate(params, in.NextToken, in.MaxResults))
+	case "GetParameterHistory":
+		versions, ok := s.params[in.Name]
+		if !ok {
+			writeSSM(w, http.StatusBadRequest, ssmError{"ParameterNotFound", "parameter not found"})
+			return
+		}
+		writeSSM(w, http.StatusOK, paginate(versions, in.NextToken, in.MaxResults))
+	case "PutParameter":
+		versions, exists := s.params[in.Name]
+		if exists && !in.Overwrite {
+			writeSSM(w, http.StatusBadRequest, ssmError{"ParameterAlreadyExists", "parameter already exists"})
+			return
+		}
+		if in.Overwrite && len(in.Tags) > 0 {
+			writeSSM(w, http.StatusBadRequest, ssmError{"ValidationException", "tags and overwrite can't be used together"})
+			return
+		}
+
+		p := ssmParameter{Name: in.Name, Value: in.Value, Type: in.Type, Version: int64(len(versions) + 1), LastModifiedDate: float64(time.Now().Unix())}
+		s.params[in.Name] = append(versions, p)
+		if len(in.Tags) > 0 {
+			s.tags[in.Name] = map[string]string{}
+			for _, tag := range in.Tags {
+				s.tags[in.Name][tag.Key] = tag.Value
+			}
+		}
+		writeSSM(w, http.StatusOK, map[string]any{"Version": p.Version})
+	case "AddTagsToResource":
+		if s.denyTagging {
+			writeSSM(w, http.StatusBadRequest, ssmError{"AccessDeniedException", "not authorized to perform ssm:AddTagsToResource"})
+			return
+		}
+		if _, ok := s.params[in.ResourceId]; !ok {
+			writeSSM(w, http.StatusBadRequest, ssmError{"InvalidResourceId", "parameter not found"})
+			return
+		}
+		if s.tags[in.ResourceId] == nil {
+			s.tags[in.ResourceId] = map[string]string{}
+		}
+		for _, tag := range in.Tags {
+			s.tags[in.ResourceId][tag.Key] = tag.Value
+		}
+		writeSSM(w, http.StatusOK, map[string]any{})
+	case "DeleteParameters":
+		deleted, invalid := []string{}, []string{}
+		for _, name := range in.Names {
+			if _, ok := s.params[name]; ok {
+				delete(s.params, name)
+				delete(s.tags, name)
+				deleted = append(deleted, name)
+			} else {
+				invalid = append(invalid, name)
+			}
+		}
+		writeSSM(w, http.StatusOK, map[string]any{"DeletedParameters": deleted, "InvalidParameters": invalid})
+	case "DeleteParameter":
+		if _, ok := s.params[in.Name]; !ok {
+			writeSSM(w, http.StatusBadRequest, ssmError{"ParameterNotFound", "parameter not found"})
+			return
+		}
+		delete(s.params, in.Name)
+		delete(s.tags, in.Name)
+		writeSSM(w, http.StatusOK, map[string]any{})
+	default:
+		writeSSM(w, http.StatusBadRequest, ssmError{"UnsupportedOperation", fmt.Sprintf("%s isn't supported by storetest.SSMServer", op)})
+	}
+}
+
+// Gets a parameter by 'name' or 'name:version'.
+func (s *SSMServer) get(selector string) (ssmParameter, error) {
+	name, version, hasVersion := strings.Cut(selector, ":")
+	versions, ok := s.params[name]
+	if !ok {
+		return ssmParameter{}, ssmError{"ParameterNotFound", "parameter not found"}
+	}
+
+	if !hasVersion {
+		return versions[len(versions)-1], nil
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 || n > len(versions) {
+		return ssmParameter{}, ssmError{"ParameterVersionNotFound", "version not found"}
+	}
+
+	return versions[n-1], nil
+}
+
+func (e ssmError) Error() string {
+	return e.Message
+}
+
+// Returns a page of maxResults (10 by default, as SSM), with the offset of
+// the next as its token.
+func paginate(params []ssmParameter, token string, maxResults int) map[string]any {
+	if maxResults <= 0 {
+		maxResults = 10
+	}
+
+	start, _ := strconv.Atoi(token)
+	start = min(start, len(params))
+	end := min(start+maxResults, len(params))
+
+	out := map[string]any{"Parameters": append([]ssmParameter{}, params[start:end]...)}
+	if end < len(params) {
+		out["NextToken"] = strconv.Itoa(end)
+	}
+
+	return out
+}
+
+func writeSSM(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,13 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestMemory(t *testing.T) {
+	TestStore(t, func(t *testing.T) store.Store {
+		return NewMemory()
+	})
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Values returns a read-only store of the given values, keyed by name
+// relative to the service (e.g. 'db/host'), for any service. It is typically
+// put first in a Fallback, to shadow individual values locally.
+func Values(name string, values map[string]string) Store {
+	return valueStore{name, values}
+}
+
+type valueStore struct {
+	name   string
+	values map[string]string
+}
+
+func (v valueStore) param(service Service, name string) (Parameter, bool) {
+	value, ok := v.values[name]
+	return Parameter{Service: service, Name: service.Prefix() + "/" + name, Value: value, Store: v.name}, ok
+}
+
+func (v valueStore) Get(ctx context.Context, service Service, name string) (Parameter, error) {
+	p, ok := v.param(service, name)
+	if !ok {
+		return Parameter{}, fmt.Errorf("'%s' not found in %s: %w", name, v.name, ErrNotFound)
+	}
+
+	return p, nil
+}
+
+func (v valueStore) GetMany(ctx context.Context, service Service, names []string) ([]Parameter, error) {
+	params := []Parameter{}
+	var missing []string
+	for _, name := range names {
+		if p, ok := v.param(service, name); ok {
+			params = append(params, p)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return params, fmt.Errorf("%v not found in %s: %w", missing, v.name, ErrNotFound)
+	}
+
+	return params, nil
+}
+
+// Values have no history, so the only version is '1'.
+func (v valueStore) GetVersion(ctx context.Context, service Service, name string, version string) (Parameter, error) {
+	if version != "1" {
+		return Parameter{}, fmt.Errorf("version %s of '%s' not found in %s: %w", version, name, v.name, ErrNotFound)
+	}
+
+	return v.Get(ctx, service, name)
+}
+
+func (v valueStore) ListVersions(ctx context.Context, service Service, name string) ([]Version, error) {
+	if _, err := v.Get(ctx, service, name); err != nil {
+		return nil, err
+	}
+
+	return []Version{{ID: "1", Version: 1}}, nil
+}
+
+func (v valueStore) List(ctx context.Context, service Service, f Filter) ([]Parameter, error) {
+	names := make([]string, 0, len(v.values))
+	for name := range v.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := []Parameter{}
+	for _, name := range names {
+		p, _ := v.param(service, name)
+		params = append(params, p)
+	}
+
+	return filter(params, f), nil
+}
+
+func (v valueStore) ListPages(ctx context.Context, service Service, f Filter, fn func(page []Parameter) error) error {
+	params, _ := v.List(ctx, service, f)
+	if len(params) == 0 {
+		return nil
+	}
+
+	return fn(params)
+}
+
+func (v valueStore) Set(ctx context.Context, service Service, name string, value string, isSecret bool, opts SetOptions) error {
+	return fmt.Errorf("unable to set '%s' in %s: %w", name, v.name, ErrReadOnly)
+}
+
+func (v valueStore) Delete(ctx context.Context, service Service, name string) error {
+	return fmt.Errorf("unable to delete '%s' from %s: %w", name, v.name, ErrReadOnly)
+}
+
+func (v valueStore) SetMany(ctx context.Context, service Service, items []Item, opts SetOptions) error {
+	return fmt.Errorf("unable to set parameters in %s: %w", v.name, ErrReadOnly)
+}
+
+func (v valueStore) DeleteMany(ctx context.Context, service Service, names []string) error {
+	return fmt.Errorf("unable to delete parameters from %s: %w", v.name, ErrReadOnly)
+}
+
+func (v valueStore) Tag(ctx context.Context, service Service, name string) error {
+	return fmt.Errorf("unable to tag '%s' in %s: %w", name, v.name, ErrReadOnly)
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Verify reads the parameter back until it has the expected value, to check
+// that a write can be read (e.g. that the reader can decrypt it with the KMS
+// key). Reads that find nothing, or the old value, are retried up to attempts
+// times, delay apart, to allow for eventual consistency; any other error fails
+// immediately, as does cancelling ctx. Values are left out of errors, as they
+// may be secret.
+func Verify(ctx context.Context, st Store, service Service, name string, want string, attempts int, delay time.Duration) error {
+	var last string
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		got, err := st.Get(ctx, service, name)
+		if err != nil {
+			if isNotFound(err) {
+				last = "it was not found"
+				continue
+			}
+
+			return fmt.Errorf("unable to read '%s' back: %w", name, err)
+		}
+
+		if got.Value == want {
+			return nil
+		}
+		last = "it had a different value"
+	}
+
+	return fmt.Errorf("'%s' didn't read back as written after %d attempts (%s)", name, attempts, last)
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(wrapError(err), ErrNotFound)
+}
@@ -0,0 +1,174 @@
+// Package systemd writes a service's parameters as a systemd EnvironmentFile,
+// for apps run as systemd units (e.g. on EC2), and reloads units to pick up
+// changes.
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guardian/devx-config/store"
+)
+
+// EnvironmentFile returns the parameters as lines of an EnvironmentFile, keyed
+// by each parameter's Key (e.g. 'db_password'), sorted by key.
+func EnvironmentFile(service store.Service, params []store.Parameter) ([]byte, error) {
+	lines := map[string]string{}
+	seen := map[string]string{} // keys to names, to catch clashes
+	for _, p := range params {
+		if !p.DeletedDate.IsZero() {
+			continue
+		}
+
+		key := p.Key()
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("'%s' and '%s' both have the key '%s'", other, p.RelativeName(), key)
+		}
+		seen[key] = p.RelativeName()
+		lines[key] = key + "=" + quote(p.Value)
+	}
+
+	keys := make([]string, 0, len(lines))
+	for k := range lines {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Parameters of %s, written by devx-config.\n", service.Prefix())
+	for _, k := range keys {
+		b.WriteString(lines[k] + "\n")
+	}
+
+	return b.Bytes(), nil
+}
+
+var safe = regexp.MustCompile(`^[-a-zA-Z0-9_./:@%+,=]*$`)
+
+// Quotes s as systemd reads it. Within double quotes, systemd keeps newlines
+// and drops the backslash before '"', '\', '`' and '$' (so '$' is never
+// expanded).
+func quote(s string) string {
+	if safe.MatchString(s) {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", "$", `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// WriteFile replaces the file at path with data, atomically (so a unit
+// starting meanwhile never reads part of it), readable only by its owner.
+// The owner is 'user' or 'user:group' (by name or ID), or empty for the
+// current user; the group defaults to the user's primary group. The file is
+// left alone, and changed is false, if it already has the same content (but
+// its owner and mode are still set, in case they were changed by hand).
+func WriteFile(path string, data []byte, owner string) (changed bool, err error) {
+	uid, gid := -1, -1
+	if owner != "" {
+		if uid, gid, err = lookupOwner(owner); err != nil {
+			return false, err
+		}
+	}
+
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return false, fmt.Errorf("unable to change the owner of '%s': %w", path, err)
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			return false, fmt.Errorf("unable to change the mode of '%s': %w", path, err)
+		}
+		return false, nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("unable to create '%s': %w", dir, err)
+	}
+
+	// CreateTemp's files are only readable by their owner (0600).
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return false, fmt.Errorf("unable to create file in '%s': %w", dir, err)
+	}
+	defer os.Remove(f.Name()) // fails harmlessly once renamed
+
+	_, werr := f.Write(data)
+	cerr := f.Close()
+	if werr != nil || cerr != nil {
+		return false, fmt.Errorf("unable to write '%s': %w", f.Name(), firstErr(werr, cerr))
+	}
+
+	if err := os.Chown(f.Name(), uid, gid); err != nil {
+		return false, fmt.Errorf("unable to change the owner of '%s': %w", path, err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return false, fmt.Errorf("unable to replace '%s': %w", path, err)
+	}
+
+	return true, nil
+}
+
+func lookupOwner(owner string) (uid int, gid int, err error) {
+	userName, groupName, _ := strings.Cut(owner, ":")
+
+	u, err := user.Lookup(userName)
+	if err != nil {
+		if u, err = user.LookupId(userName); err != nil {
+			return -1, -1, fmt.Errorf("unknown user '%s'", userName)
+		}
+	}
+
+	gidStr := u.Gid
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			if g, err = user.LookupGroupId(groupName); err != nil {
+				return -1, -1, fmt.Errorf("unknown group '%s'", groupName)
+			}
+		}
+		gidStr = g.Gid
+	}
+
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return -1, -1, fmt.Errorf("user '%s' has a non-numeric ID '%s'", userName, u.Uid)
+	}
+	if gid, err = strconv.Atoi(gidStr); err != nil {
+		return -1, -1, fmt.Errorf("group of '%s' has a non-numeric ID '%s'", owner, gidStr)
+	}
+
+	return uid, gid, nil
+}
+
+// Reload reloads the unit if it supports it, otherwise restarts it, if it is
+// running (so stopped units stay stopped), with systemctl's output going to
+// stderr.
+func Reload(unit string) error {
+	c := exec.Command("systemctl", "try-reload-or-restart", unit)
+	c.Stdout, c.Stderr = os.Stderr, os.Stderr
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("unable to reload '%s' (is systemd running?): %w", unit, err)
+	}
+
+	return nil
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
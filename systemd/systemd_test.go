@@ -0,0 +1,88 @@
+package systemd
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestEnvironmentFile(t *testing.T) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "CODE"}
+	params := []store.Parameter{
+		{Service: service, Name: "/CODE/deploy/api/port", Value: "8080"},
+		{Service: service, Name: "/CODE/deploy/api/db/password", Value: `p$ss"word`, IsSecret: true},
+		{Service: service, Name: "/CODE/deploy/api/motd", Value: "hello\nworld"},
+		{Service: service, Name: "/CODE/deploy/api/old", Value: "gone", DeletedDate: time.Now()},
+	}
+
+	got, err := EnvironmentFile(service, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "# Parameters of /CODE/deploy/api, written by devx-config.\n" +
+		`db_password="p\$ss\"word"` + "\n" +
+		"motd=\"hello\nworld\"\n" +
+		"port=8080\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	clash := append(params, store.Parameter{Service: service, Name: "/CODE/deploy/api/db.password", Value: "other"})
+	if _, err := EnvironmentFile(service, clash); err == nil {
+		t.Errorf("clash: expected an error")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api", "env")
+
+	changed, err := WriteFile(path, []byte("port=8080\n"), "")
+	if err != nil || !changed {
+		t.Fatalf("new file: got %v, %v; want it written", changed, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("got %v, %v; want mode 0600", info, err)
+	}
+
+	// Even if its content is the same, a file made readable by others is
+	// made private again.
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = WriteFile(path, []byte("port=8080\n"), "")
+	if err != nil || changed {
+		t.Errorf("same content: got %v, %v; want it left alone", changed, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("same content: got %v, %v; want mode 0600", info, err)
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("unable to find the current user: %v", err)
+	}
+
+	changed, err = WriteFile(path, []byte("port=9090\n"), current.Uid)
+	if err != nil || !changed {
+		t.Errorf("new content: got %v, %v; want it written", changed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "port=9090\n" {
+		t.Errorf("got %q; want the new content", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("got %d files; want no temporary files left", len(entries))
+	}
+
+	if _, err := WriteFile(path, nil, "no-such-user-devx"); err == nil {
+		t.Errorf("unknown owner: expected an error")
+	}
+}
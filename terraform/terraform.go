@@ -0,0 +1,158 @@
+// Package terraform exports a service's parameters as Terraform resources,
+// with import blocks (Terraform 1.5+) so that existing parameters can be
+// brought under Terraform's management without being recreated.
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/guardian/devx-config/store"
+)
+
+type Options struct {
+	Tags   map[string]string // extra tags, as for the stores (see store.Service.Tags)
+	KMSKey string            // for SecureStrings and secrets, if not the AWS-managed key
+}
+
+// Export returns an aws_ssm_parameter resource for each SSM parameter, and an
+// aws_secretsmanager_secret for each secret, each with an import block,
+// sorted by name. Values of plain parameters are included; SecureStrings take
+// theirs from a sensitive variable (e.g. set with TF_VAR_db_password), so
+// secrets never end up in code. Secret values (aws_secretsmanager_secret_version)
+// are left out.
+func Export(service store.Service, params []store.Parameter, opts Options) ([]byte, error) {
+	sorted := append([]store.Parameter{}, params...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Parameters of %s, exported by devx-config.\n", service.Prefix())
+
+	tags := service.Tags(opts.Tags)
+	seen := map[string]string{} // labels to names, to catch clashes
+	for _, p := range sorted {
+		if !p.DeletedDate.IsZero() {
+			continue
+		}
+
+		label := Label(p)
+		if other, ok := seen[label]; ok {
+			return nil, fmt.Errorf("'%s' and '%s' would both be named '%s'", other, p.RelativeName(), label)
+		}
+		seen[label] = p.RelativeName()
+
+		switch p.Store {
+		case "ssm":
+			writeParameter(&b, p, label, tags, opts.KMSKey)
+		case "secretsmanager":
+			if p.ARN == "" {
+				return nil, fmt.Errorf("'%s' has no ARN, so can't be imported", p.RelativeName())
+			}
+			writeSecret(&b, p, label, tags, opts.KMSKey)
+		default:
+			return nil, fmt.Errorf("'%s' is in the %s store, which has no Terraform resource", p.RelativeName(), p.Store)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+func writeParameter(b *bytes.Buffer, p store.Parameter, label string, tags map[string]string, kmsKey string) {
+	fmt.Fprintf(b, "\nimport {\n  to = aws_ssm_parameter.%s\n  id = %s\n}\n", label, quote(p.Name))
+
+	attrs := [][2]string{{"name", quote(p.Name)}, {"type", quote(firstNonEmpty(p.Type, "String"))}}
+	if p.IsSecret {
+		fmt.Fprintf(b, "\nvariable %s {\n  type      = string\n  sensitive = true\n}\n", quote(label))
+
+		attrs = append(attrs, [2]string{"value", "var." + label})
+		if kmsKey != "" {
+			attrs = append(attrs, [2]string{"key_id", quote(kmsKey)})
+		}
+	} else {
+		attrs = append(attrs, [2]string{"value", quote(p.Value)})
+	}
+
+	writeResource(b, "aws_ssm_parameter", label, attrs, tags)
+}
+
+func writeSecret(b *bytes.Buffer, p store.Parameter, label string, tags map[string]string, kmsKey string) {
+	fmt.Fprintf(b, "\nimport {\n  to = aws_secretsmanager_secret.%s\n  id = %s\n}\n", label, quote(p.ARN))
+
+	attrs := [][2]string{{"name", quote(p.Name)}}
+	if kmsKey != "" {
+		attrs = append(attrs, [2]string{"kms_key_id", quote(kmsKey)})
+	}
+
+	writeResource(b, "aws_secretsmanager_secret", label, attrs, tags)
+}
+
+// Writes the resource as 'terraform fmt' would, with its attributes' equals
+// signs aligned.
+func writeResource(b *bytes.Buffer, resourceType string, label string, attrs [][2]string, tags map[string]string) {
+	fmt.Fprintf(b, "\nresource %s %s {\n", quote(resourceType), quote(label))
+	writeAttributes(b, "  ", attrs)
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tagAttrs [][2]string
+	for _, k := range keys {
+		tagAttrs = append(tagAttrs, [2]string{quote(k), quote(tags[k])})
+	}
+
+	b.WriteString("\n  tags = {\n")
+	writeAttributes(b, "    ", tagAttrs)
+	b.WriteString("  }\n}\n")
+}
+
+func writeAttributes(b *bytes.Buffer, indent string, attrs [][2]string) {
+	width := 0
+	for _, a := range attrs {
+		width = max(width, len(a[0]))
+	}
+
+	for _, a := range attrs {
+		fmt.Fprintf(b, "%s%-*s = %s\n", indent, width, a[0], a[1])
+	}
+}
+
+var invalidLabel = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Label returns the parameter's resource name: its Key (e.g. 'db_password'),
+// with any characters Terraform doesn't allow replaced by '_'.
+func Label(p store.Parameter) string {
+	label := invalidLabel.ReplaceAllString(p.Key(), "_")
+	if label == "" || (label[0] >= '0' && label[0] <= '9') || label[0] == '-' {
+		label = "_" + label
+	}
+
+	return label
+}
+
+// HCL strings are JSON strings, except that '${' and '%{' start templates.
+func quote(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+
+	r := strings.NewReplacer("${", "$${", "%{", "%%{")
+	return r.Replace(strings.TrimSuffix(b.String(), "\n"))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
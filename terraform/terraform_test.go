@@ -0,0 +1,112 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guardian/devx-config/store"
+)
+
+func TestExport(t *testing.T) {
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	params := []store.Parameter{
+		{Service: service, Name: "/PROD/deploy/api/url", Value: "https://${host}/", Store: "ssm", Type: "String"},
+		{Service: service, Name: "/PROD/deploy/api/db/password", Value: "hunter2", IsSecret: true, Store: "ssm", Type: "SecureString"},
+		{Service: service, Name: "/PROD/deploy/api/api-key", Value: "secret", IsSecret: true, Store: "secretsmanager", ARN: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:/PROD/deploy/api/api-key-AbCdEf"},
+	}
+
+	got, err := Export(service, params, Options{Tags: map[string]string{"Owner": "devx"}, KMSKey: "alias/api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `# Parameters of /PROD/deploy/api, exported by devx-config.
+
+import {
+  to = aws_secretsmanager_secret.api-key
+  id = "arn:aws:secretsmanager:eu-west-1:123456789012:secret:/PROD/deploy/api/api-key-AbCdEf"
+}
+
+resource "aws_secretsmanager_secret" "api-key" {
+  name       = "/PROD/deploy/api/api-key"
+  kms_key_id = "alias/api"
+
+  tags = {
+    "App"   = "api"
+    "Owner" = "devx"
+    "Stack" = "deploy"
+    "Stage" = "PROD"
+  }
+}
+
+import {
+  to = aws_ssm_parameter.db_password
+  id = "/PROD/deploy/api/db/password"
+}
+
+variable "db_password" {
+  type      = string
+  sensitive = true
+}
+
+resource "aws_ssm_parameter" "db_password" {
+  name   = "/PROD/deploy/api/db/password"
+  type   = "SecureString"
+  value  = var.db_password
+  key_id = "alias/api"
+
+  tags = {
+    "App"   = "api"
+    "Owner" = "devx"
+    "Stack" = "deploy"
+    "Stage" = "PROD"
+  }
+}
+
+import {
+  to = aws_ssm_parameter.url
+  id = "/PROD/deploy/api/url"
+}
+
+resource "aws_ssm_parameter" "url" {
+  name  = "/PROD/deploy/api/url"
+  type  = "String"
+  value = "https://$${host}/"
+
+  tags = {
+    "App"   = "api"
+    "Owner" = "devx"
+    "Stack" = "deploy"
+    "Stage" = "PROD"
+  }
+}
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if strings.Contains(string(got), "hunter2") || strings.Contains(string(got), `"secret"`) {
+		t.Errorf("secret values must not be exported")
+	}
+
+	plugin := []store.Parameter{{Service: service, Name: "/PROD/deploy/api/token", Store: "vault"}}
+	if _, err := Export(service, plugin, Options{}); err == nil {
+		t.Errorf("plugin store: expected an error")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := map[string]string{
+		"db/password":  "db_password",
+		"tls/cert.pem": "tls_cert_pem",
+		"2fa":          "_2fa",
+		"a b":          "a_b",
+	}
+
+	service := store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+	for name, want := range tests {
+		p := store.Parameter{Service: service, Name: service.Prefix() + "/" + name}
+		if got := Label(p); got != want {
+			t.Errorf("%s: got %s; want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/store"
+)
+
+// CloudTrail only keeps 90 days of management events.
+const trailRetention = 90 * 24 * time.Hour
+
+// Returns the window of PutParameter events to look up for the versions, or
+// false if none of them are recent enough to be in CloudTrail.
+func trailWindow(versions []store.Version, now time.Time) (time.Time, time.Time, bool) {
+	var start, end time.Time
+	for _, v := range versions {
+		if v.LastModified.IsZero() {
+			continue
+		}
+		if start.IsZero() || v.LastModified.Before(start) {
+			start = v.LastModified
+		}
+		if v.LastModified.After(end) {
+			end = v.LastModified
+		}
+	}
+
+	oldest := now.Add(-trailRetention)
+	if end.IsZero() || end.Before(oldest) {
+		return time.Time{}, time.Time{}, false
+	}
+	if start.Before(oldest) {
+		start = oldest
+	}
+
+	// Allow for clock skew between SSM and CloudTrail.
+	return start.Add(-time.Minute), end.Add(time.Minute), true
+}
+
+// Sets the principal and source IP of each version from the PutParameter
+// events for the parameter, matched on the version each call created.
+func annotateVersions(versions []store.Version, events []awsclient.TrailEvent, fullName string) {
+	byVersion := map[int64]awsclient.TrailEvent{}
+	for _, e := range events {
+		name, _ := e.RequestParameters["name"].(string)
+		if strings.TrimPrefix(name, "/") != strings.TrimPrefix(fullName, "/") {
+			continue
+		}
+
+		// JSON numbers are decoded as float64.
+		if version, ok := e.ResponseElements["version"].(float64); ok {
+			byVersion[int64(version)] = e
+		}
+	}
+
+	for i := range versions {
+		if e, ok := byVersion[versions[i].Version]; ok {
+			versions[i].Principal = e.Principal
+			versions[i].SourceIP = e.SourceIP
+		}
+	}
+}
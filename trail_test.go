@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/store"
+)
+
+func TestTrailWindow(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	versions := []store.Version{
+		{Version: 1, LastModified: now.Add(-200 * 24 * time.Hour)},
+		{Version: 2, LastModified: now.Add(-time.Hour)},
+	}
+	start, end, ok := trailWindow(versions, now)
+	if !ok || !start.Equal(now.Add(-trailRetention-time.Minute)) || !end.Equal(now.Add(-time.Hour+time.Minute)) {
+		t.Errorf("got %v to %v (%t); want the last 90 days, up to version 2", start, end, ok)
+	}
+
+	if _, _, ok := trailWindow(versions[:1], now); ok {
+		t.Errorf("expected no window for versions older than CloudTrail's retention")
+	}
+}
+
+func TestAnnotateVersions(t *testing.T) {
+	versions := []store.Version{{Version: 1}, {Version: 2}}
+	events := []awsclient.TrailEvent{
+		{
+			Principal:         "arn:aws:sts::123456789012:assumed-role/developer/alice",
+			SourceIP:          "203.0.113.7",
+			RequestParameters: map[string]any{"name": "/PROD/deploy/example/KEY"},
+			ResponseElements:  map[string]any{"version": float64(2)},
+		},
+		{
+			Principal:         "arn:aws:sts::123456789012:assumed-role/developer/bob",
+			RequestParameters: map[string]any{"name": "/PROD/deploy/example/OTHER"},
+			ResponseElements:  map[string]any{"version": float64(1)},
+		},
+	}
+
+	annotateVersions(versions, events, "/PROD/deploy/example/KEY")
+
+	if versions[0].Principal != "" {
+		t.Errorf("version 1: got principal %s; want none (event is for another parameter)", versions[0].Principal)
+	}
+	if versions[1].Principal != events[0].Principal || versions[1].SourceIP != "203.0.113.7" {
+		t.Errorf("version 2: got %+v", versions[1])
+	}
+}
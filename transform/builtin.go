@@ -0,0 +1,131 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/store"
+)
+
+// Returns p with a new value.
+func withValue(p store.Parameter, value string) []store.Parameter {
+	p.Value = value
+	return []store.Parameter{p}
+}
+
+// 'base64' decodes (standard, padded) base64 on read, and encodes on write.
+func newBase64(arg string, env Env) (Transform, error) {
+	if arg != "" {
+		return Transform{}, errors.New("takes no argument")
+	}
+
+	return Transform{
+		Read: func(ctx context.Context, p store.Parameter) ([]store.Parameter, error) {
+			data, err := base64.StdEncoding.DecodeString(p.Value)
+			if err != nil {
+				return nil, err
+			}
+			return withValue(p, string(data)), nil
+		},
+		Write: func(ctx context.Context, p store.Parameter) (string, error) {
+			return base64.StdEncoding.EncodeToString([]byte(p.Value)), nil
+		},
+	}, nil
+}
+
+// 'kms:<key>' decrypts (base64) ciphertext with KMS on read, and encrypts with
+// the key on write. Without a key, values can only be read.
+func newKMS(keyID string, env Env) (Transform, error) {
+	if env.AWS == nil {
+		return Transform{}, errors.New("AWS isn't available")
+	}
+
+	t := Transform{
+		Read: func(ctx context.Context, p store.Parameter) ([]store.Parameter, error) {
+			cfg, err := env.AWS(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			plaintext, err := awsclient.Decrypt(ctx, cfg, keyID, p.Value, nil)
+			if err != nil {
+				return nil, err
+			}
+			return withValue(p, string(plaintext)), nil
+		},
+	}
+
+	if keyID != "" {
+		t.Write = func(ctx context.Context, p store.Parameter) (string, error) {
+			cfg, err := env.AWS(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			return awsclient.Encrypt(ctx, cfg, keyID, []byte(p.Value))
+		}
+	}
+
+	return t, nil
+}
+
+// 'json-flatten' replaces a JSON object with a parameter per field (nested
+// objects' fields joined by '/'), e.g. 'db' of '{"host": "x", "port": 5432}'
+// becomes 'db/host' of 'x' and 'db/port' of '5432'. It can't be reversed.
+func newJSONFlatten(arg string, env Env) (Transform, error) {
+	if arg != "" {
+		return Transform{}, errors.New("takes no argument")
+	}
+
+	return Transform{
+		Read: func(ctx context.Context, p store.Parameter) ([]store.Parameter, error) {
+			var fields map[string]any
+			dec := json.NewDecoder(bytes.NewReader([]byte(p.Value)))
+			dec.UseNumber()
+			if err := dec.Decode(&fields); err != nil {
+				return nil, fmt.Errorf("not a JSON object: %w", err)
+			}
+
+			var out []store.Parameter
+			if err := flatten(p, p.Name, fields, &out); err != nil {
+				return nil, err
+			}
+			return out, nil
+		},
+	}, nil
+}
+
+func flatten(p store.Parameter, name string, fields map[string]any, out *[]store.Parameter) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case map[string]any:
+			if err := flatten(p, name+"/"+k, v, out); err != nil {
+				return err
+			}
+		case string:
+			p.Name, p.Value = name+"/"+k, v
+			*out = append(*out, p)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			p.Name, p.Value = name+"/"+k, string(data)
+			*out = append(*out, p)
+		}
+	}
+
+	return nil
+}
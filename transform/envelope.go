@@ -0,0 +1,143 @@
+package transform
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/guardian/devx-config/awsclient"
+	"github.com/guardian/devx-config/store"
+)
+
+// EnvelopePrefix marks values encrypted by the 'envelope' transform, which
+// are stored as '<prefix><encrypted data key>:<nonce and ciphertext>', both
+// base64-encoded.
+const EnvelopePrefix = "devx-envelope:v1:"
+
+// Functions for the KMS calls, replaced in tests.
+var (
+	generateDataKey = awsclient.GenerateDataKey
+	decryptDataKey  = func(ctx context.Context, cfg aws.Config, encrypted []byte, encryptionContext map[string]string) ([]byte, error) {
+		return awsclient.Decrypt(ctx, cfg, "", base64.StdEncoding.EncodeToString(encrypted), encryptionContext)
+	}
+)
+
+// Binds the data key (as KMS encryption context) and the ciphertext (as GCM
+// additional data) to the parameter's full name, so an encrypted value copied
+// to another parameter can't be decrypted.
+func encryptionContext(name string) map[string]string {
+	return map[string]string{"parameter": name}
+}
+
+// 'envelope:<key>' encrypts values client-side before they are written, with
+// AES-256-GCM under a new data key from the KMS key, so AWS only ever stores
+// ciphertext. Reading decrypts them (which needs kms:Decrypt on the key), and
+// leaves values that weren't encrypted as they are, so existing parameters
+// can be moved over gradually.
+func newEnvelope(keyID string, env Env) (Transform, error) {
+	if keyID == "" {
+		return Transform{}, errors.New("a KMS key is required, e.g. 'envelope:alias/my-key'")
+	}
+	if env.AWS == nil {
+		return Transform{}, errors.New("AWS isn't available")
+	}
+
+	return Transform{
+		Read: func(ctx context.Context, p store.Parameter) ([]store.Parameter, error) {
+			if !strings.HasPrefix(p.Value, EnvelopePrefix) {
+				return []store.Parameter{p}, nil
+			}
+
+			cfg, err := env.AWS(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			plaintext, err := openEnvelope(ctx, cfg, p.Name, p.Value)
+			if err != nil {
+				return nil, err
+			}
+			return withValue(p, plaintext), nil
+		},
+		Write: func(ctx context.Context, p store.Parameter) (string, error) {
+			cfg, err := env.AWS(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			return sealEnvelope(ctx, cfg, keyID, p.Name, p.Value)
+		},
+	}, nil
+}
+
+func sealEnvelope(ctx context.Context, cfg aws.Config, keyID string, name string, value string) (string, error) {
+	key, encryptedKey, err := generateDataKey(ctx, cfg, keyID, encryptionContext(name))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	return EnvelopePrefix + base64.StdEncoding.EncodeToString(encryptedKey) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openEnvelope(ctx context.Context, cfg aws.Config, name string, value string) (string, error) {
+	encodedKey, encodedSealed, ok := strings.Cut(strings.TrimPrefix(value, EnvelopePrefix), ":")
+	if !ok {
+		return "", errors.New("invalid envelope")
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid envelope: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encodedSealed)
+	if err != nil {
+		return "", fmt.Errorf("invalid envelope: %w", err)
+	}
+
+	key, err := decryptDataKey(ctx, cfg, encryptedKey, encryptionContext(name))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid envelope: too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt envelope: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,187 @@
+// Package transform transforms parameter values as they are read and written,
+// e.g. to base64-decode a binary file stored as text, decrypt a value that is
+// encrypted with a second KMS key, or flatten a JSON object into a parameter
+// per field. Transformations are registered by name (see Register) and applied
+// to parameters by the config file's 'transforms' rules (see
+// config.Transform), or to every parameter with --transform.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/guardian/devx-config/config"
+	"github.com/guardian/devx-config/internal/registry"
+	"github.com/guardian/devx-config/store"
+)
+
+// Transform is a named transformation.
+type Transform struct {
+	// Transforms a parameter as it is read. It may return several
+	// parameters, e.g. to flatten a JSON object.
+	Read func(ctx context.Context, p store.Parameter) ([]store.Parameter, error)
+
+	// Reverses Read for a parameter about to be written, returning the value
+	// to write in place of p.Value, or nil if it can't be reversed (so the
+	// parameter can't be written while the transformation applies).
+	Write func(ctx context.Context, p store.Parameter) (string, error)
+}
+
+// Env is what a Factory is given to create a transformation.
+type Env struct {
+	// Loads AWS config, for transformations that call AWS (e.g. 'kms').
+	AWS func(ctx context.Context) (aws.Config, error)
+}
+
+// Factory creates a transformation, given the argument after its name (e.g.
+// 'alias/other-key' in 'kms:alias/other-key'), if any.
+type Factory func(arg string, env Env) (Transform, error)
+
+var factories registry.Registry[Factory]
+
+func init() {
+	Register("base64", newBase64)
+	Register("kms", newKMS)
+	Register("json-flatten", newJSONFlatten)
+	Register("envelope", newEnvelope)
+}
+
+// Register makes a transformation available by name, replacing any already
+// registered with that name. It is typically called from an init function.
+func Register(name string, factory Factory) {
+	factories.Register(name, factory)
+}
+
+// Names returns the names of the registered transformations, sorted.
+func Names() []string {
+	return factories.Names()
+}
+
+type rule struct {
+	pattern    string
+	names      []string
+	transforms []Transform
+}
+
+// An empty pattern matches every parameter (e.g. for --transform).
+func match(pattern string, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+
+	return path.Match(pattern, name)
+}
+
+// Pipeline applies the transformations of every rule whose pattern matches a
+// parameter's name (relative to its service), rule by rule.
+type Pipeline struct {
+	rules []rule
+}
+
+// New creates the transformations of each rule, failing if any is unknown or
+// has an invalid pattern or argument.
+func New(rules []config.Transform, env Env) (Pipeline, error) {
+	var p Pipeline
+	for _, r := range rules {
+		if _, err := match(r.Pattern, ""); err != nil {
+			return Pipeline{}, fmt.Errorf("invalid transform pattern '%s': %w", r.Pattern, err)
+		}
+
+		compiled := rule{pattern: r.Pattern, names: r.Apply}
+		for _, spec := range r.Apply {
+			name, arg, _ := strings.Cut(spec, ":")
+
+			factory, ok := factories.Lookup(name)
+			if !ok {
+				return Pipeline{}, fmt.Errorf("unknown transform '%s' (must be one of '%s')", name, strings.Join(Names(), "', '"))
+			}
+
+			t, err := factory(arg, env)
+			if err != nil {
+				return Pipeline{}, fmt.Errorf("invalid transform '%s': %w", spec, err)
+			}
+			compiled.transforms = append(compiled.transforms, t)
+		}
+
+		p.rules = append(p.rules, compiled)
+	}
+
+	return p, nil
+}
+
+// Empty is true if there are no rules, so nothing would be transformed.
+func (p Pipeline) Empty() bool {
+	return len(p.rules) == 0
+}
+
+// Read transforms params as they are read.
+func (p Pipeline) Read(ctx context.Context, params []store.Parameter) ([]store.Parameter, error) {
+	if p.Empty() {
+		return params, nil
+	}
+
+	out := make([]store.Parameter, 0, len(params))
+	for _, param := range params {
+		transformed, err := p.readOne(ctx, param)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, transformed...)
+	}
+
+	return out, nil
+}
+
+func (p Pipeline) readOne(ctx context.Context, param store.Parameter) ([]store.Parameter, error) {
+	params := []store.Parameter{param}
+	for _, r := range p.rules {
+		if ok, _ := match(r.pattern, param.RelativeName()); !ok {
+			continue
+		}
+
+		for i, t := range r.transforms {
+			var next []store.Parameter
+			for _, current := range params {
+				out, err := t.Read(ctx, current)
+				if err != nil {
+					return nil, fmt.Errorf("unable to transform '%s' (%s): %w", current.RelativeName(), r.names[i], err)
+				}
+				next = append(next, out...)
+			}
+			params = next
+		}
+	}
+
+	return params, nil
+}
+
+// Write transforms a value about to be written to the named parameter
+// (relative to the service), reversing the transformations that apply to it.
+func (p Pipeline) Write(ctx context.Context, service store.Service, name string, value string) (string, error) {
+	param := store.Parameter{Service: service, Name: service.Prefix() + "/" + name}
+
+	for i := len(p.rules) - 1; i >= 0; i-- {
+		r := p.rules[i]
+		if ok, _ := match(r.pattern, name); !ok {
+			continue
+		}
+
+		for j := len(r.transforms) - 1; j >= 0; j-- {
+			if r.transforms[j].Write == nil {
+				return "", fmt.Errorf("'%s' can't be written, as the '%s' transform can't be reversed", name, r.names[j])
+			}
+
+			var err error
+			param.Value = value
+			if value, err = r.transforms[j].Write(ctx, param); err != nil {
+				return "", fmt.Errorf("unable to transform '%s' (%s): %w", name, r.names[j], err)
+			}
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,144 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/guardian/devx-config/config"
+	"github.com/guardian/devx-config/store"
+)
+
+var service = store.Service{App: "api", Stack: "deploy", Stage: "PROD"}
+
+func param(name, value string) store.Parameter {
+	return store.Parameter{Service: service, Name: service.Prefix() + "/" + name, Value: value}
+}
+
+func TestPipeline(t *testing.T) {
+	// 'db' is a base64-encoded JSON object.
+	Register("upper", func(arg string, env Env) (Transform, error) {
+		return Transform{Read: func(ctx context.Context, p store.Parameter) ([]store.Parameter, error) {
+			return withValue(p, strings.ToUpper(p.Value)), nil
+		}}, nil
+	})
+
+	p, err := New([]config.Transform{
+		{Pattern: "tls/*", Apply: []string{"base64"}},
+		{Pattern: "db", Apply: []string{"base64", "json-flatten"}},
+		{Pattern: "*", Apply: []string{"upper"}},
+	}, Env{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	got, err := p.Read(ctx, []store.Parameter{
+		param("tls/key.pem", "S0VZ"),
+		param("db", "eyJob3N0IjogIngiLCAicG9ydCI6IDU0MzIsICJvcHRzIjogeyJzc2wiOiB0cnVlfX0="),
+		param("port", "8080"),
+		param("name", "api"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var pairs []string
+	for _, p := range got {
+		pairs = append(pairs, p.RelativeName()+"="+p.Value)
+	}
+	if got, want := strings.Join(pairs, " "), "tls/key.pem=KEY db/host=X db/opts/ssl=TRUE db/port=5432 port=8080 name=API"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+
+	// '*' doesn't match across '/', so 'tls/key.pem' isn't upper-cased.
+	if value, err := p.Write(ctx, service, "name", "API"); err == nil || value != "" {
+		t.Errorf("'upper' can't be reversed: got %s, %v; want an error", value, err)
+	}
+
+	p, _ = New([]config.Transform{{Pattern: "tls/*", Apply: []string{"base64", "base64"}}}, Env{})
+	if value, err := p.Write(ctx, service, "tls/key.pem", "KEY"); err != nil || value != "UzBWWg==" {
+		t.Errorf("got %s, %v; want UzBWWg==", value, err)
+	}
+	if value, _ := p.Write(ctx, service, "port", "8080"); value != "8080" {
+		t.Errorf("unmatched: got %s; want 8080", value)
+	}
+
+	for _, rules := range [][]config.Transform{
+		{{Pattern: "*", Apply: []string{"rot13"}}},
+		{{Pattern: "[", Apply: []string{"base64"}}},
+		{{Pattern: "*", Apply: []string{"base64:arg"}}},
+		{{Pattern: "*", Apply: []string{"kms"}}}, // no AWS
+	} {
+		if _, err := New(rules, Env{}); err == nil {
+			t.Errorf("%v: expected an error", rules)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	// The fake KMS "encrypts" data keys by reversing them.
+	reverse := func(b []byte) []byte {
+		out := make([]byte, len(b))
+		for i := range b {
+			out[len(b)-1-i] = b[i]
+		}
+		return out
+	}
+	// The fake KMS also checks the encryption context, as KMS would.
+	generateDataKey = func(ctx context.Context, cfg aws.Config, keyID string, encryptionContext map[string]string) ([]byte, []byte, error) {
+		key := []byte("0123456789abcdef0123456789abcdef")
+		return key, append(reverse(key), encryptionContext["parameter"]...), nil
+	}
+	decryptDataKey = func(ctx context.Context, cfg aws.Config, encrypted []byte, encryptionContext map[string]string) ([]byte, error) {
+		key, ok := bytes.CutSuffix(encrypted, []byte(encryptionContext["parameter"]))
+		if !ok {
+			return nil, errors.New("invalid encryption context")
+		}
+		return reverse(key), nil
+	}
+
+	env := Env{AWS: func(ctx context.Context) (aws.Config, error) { return aws.Config{}, nil }}
+	p, err := New([]config.Transform{{Pattern: "secret/*", Apply: []string{"envelope:alias/app"}}}, env)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	sealed, err := p.Write(ctx, service, "secret/key", "hunter2")
+	if err != nil || !strings.HasPrefix(sealed, EnvelopePrefix) || strings.Contains(sealed, "hunter2") {
+		t.Fatalf("got %s, %v; want an envelope", sealed, err)
+	}
+
+	got, err := p.Read(ctx, []store.Parameter{param("secret/key", sealed), param("secret/legacy", "plaintext")})
+	if err != nil || got[0].Value != "hunter2" || got[1].Value != "plaintext" {
+		t.Errorf("got %+v, %v; want hunter2 and the unencrypted value as is", got, err)
+	}
+
+	if _, err := p.Read(ctx, []store.Parameter{param("secret/other", sealed)}); err == nil {
+		t.Errorf("copied to another parameter: expected an error")
+	}
+
+	// The ciphertext is bound to the name too, not just the data key.
+	checked := decryptDataKey
+	decryptDataKey = func(ctx context.Context, cfg aws.Config, encrypted []byte, _ map[string]string) ([]byte, error) {
+		return checked(ctx, cfg, encrypted, encryptionContext(service.Prefix()+"/secret/key"))
+	}
+	if _, err := p.Read(ctx, []store.Parameter{param("secret/other", sealed)}); err == nil {
+		t.Errorf("copied to another parameter, with the data key's context ignored: expected an error")
+	}
+	decryptDataKey = checked
+
+	tampered := sealed[:len(sealed)-4] + "AAA="
+	if _, err := p.Read(ctx, []store.Parameter{param("secret/key", tampered)}); err == nil {
+		t.Errorf("tampered: expected an error")
+	}
+
+	if _, err := New([]config.Transform{{Pattern: "*", Apply: []string{"envelope"}}}, env); err == nil {
+		t.Errorf("no key: expected an error")
+	}
+}